@@ -8,11 +8,18 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"runtime"
 	"runtime/pprof"
+	"strconv"
+	"strings"
 
 	"github.com/fivemoreminix/qedit/internal/clipboard"
+	"github.com/fivemoreminix/qedit/internal/editorconfig"
+	"github.com/fivemoreminix/qedit/internal/task"
 	internal_ui "github.com/fivemoreminix/qedit/internal/ui"
+	"github.com/fivemoreminix/qedit/internal/vcs"
+	"github.com/fivemoreminix/qedit/pkg/buffer"
 	"github.com/fivemoreminix/qedit/pkg/ui"
 	"github.com/gdamore/tcell/v2"
 )
@@ -34,6 +41,23 @@ var (
 	dialog         ui.Component // nil if not present (has exclusive focus)
 
 	focusedComponent ui.Component = nil
+
+	changeTrackers = make(map[*ui.TextEdit]*vcs.Tracker) // Git change markers of each TextEdit
+
+	editorConfigs = make(map[*ui.TextEdit]editorconfig.Properties) // EditorConfig properties of each TextEdit
+
+	outputView      *ui.OutputView   // Output of the current Run
+	outputContainer *ui.TabContainer // Where the outputView was last shown
+	currentRun      *task.Run        // nil if no task has been run
+	errorIdx        = -1             // Index of the current Location of currentRun
+
+	formatOnSave = true // Whether files are formatted by their Language when saved
+
+	largeFileSize = int64(64 << 20) // Files at least this large are opened in large file mode
+
+	blockClipboard string // Text last copied from a block selection, to paste as a block
+
+	lastFind string // Text last searched for with Find
 )
 
 func changeFocus(to ui.Component) {
@@ -68,12 +92,436 @@ func getActiveTextEdit() *ui.TextEdit {
 	tabContainer := getActiveTabContainer()
 	if tabContainer != nil && tabContainer.GetTabCount() > 0 {
 		tab := tabContainer.GetTab(tabContainer.GetSelectedTabIdx())
-		te := tab.Child.(*ui.TextEdit)
+		te, _ := tab.Child.(*ui.TextEdit)
 		return te
 	}
 	return nil
 }
 
+// setBlockClipboard remembers `text` copied from the TextEdit, if it was copied
+// from a block selection, so pasting it inserts it as a block.
+func setBlockClipboard(te *ui.TextEdit, text []byte) {
+	if te.IsBlockSelecting() {
+		blockClipboard = string(text)
+	} else {
+		blockClipboard = ""
+	}
+}
+
+// returns nil if no DiffView is visible
+func getActiveDiffView() *ui.DiffView {
+	tabContainer := getActiveTabContainer()
+	if tabContainer != nil && tabContainer.GetTabCount() > 0 {
+		tab := tabContainer.GetTab(tabContainer.GetSelectedTabIdx())
+		dv, _ := tab.Child.(*ui.DiffView)
+		return dv
+	}
+	return nil
+}
+
+// showDiffView opens the DiffView in a new tab of the active TabContainer.
+func showDiffView(name string, dv *ui.DiffView) {
+	tabContainer := getActiveTabContainer()
+	if tabContainer == nil {
+		tabContainer = ui.NewTabContainer(&theme)
+		panelContainer.SetSelected(tabContainer)
+	}
+	tabContainer.AddTab(name, dv)
+	tabContainer.FocusTab(tabContainer.GetTabCount() - 1)
+}
+
+// bookmarkSigns is the group of Signs used for bookmarks in a TextEdit.
+const bookmarkSigns = "bookmarks"
+
+// toggleBookmark adds a bookmark to the line of the cursor, or removes the
+// bookmark that is already on that line.
+func toggleBookmark(te *ui.TextEdit) {
+	line, _ := te.GetCursor().GetLineCol()
+	for _, sign := range te.GetSignGroup(bookmarkSigns) {
+		if sign.GetLine() == line {
+			te.RemoveSign(bookmarkSigns, sign)
+			return
+		}
+	}
+	te.AddSign(bookmarkSigns, line, &ui.Sign{
+		Glyph:   '■',
+		Style:   tcell.Style{}.Foreground(tcell.ColorTeal).Background(tcell.ColorBlack),
+		Tooltip: "Bookmark",
+	})
+}
+
+// gotoBookmark moves the cursor to the nearest bookmark after the cursor line,
+// or before it if `forwards` is false. The search wraps around the buffer.
+func gotoBookmark(te *ui.TextEdit, forwards bool) {
+	bookmarks := te.GetSignGroup(bookmarkSigns)
+	if len(bookmarks) == 0 {
+		return
+	}
+
+	cursLine, _ := te.GetCursor().GetLineCol()
+	lines := te.Buffer.Lines()
+	best, bestDist := -1, lines+1
+	for _, sign := range bookmarks {
+		dist := sign.GetLine() - cursLine
+		if !forwards {
+			dist = -dist
+		}
+		if dist <= 0 {
+			dist += lines // Wrap around
+		}
+		if dist < bestDist {
+			best, bestDist = sign.GetLine(), dist
+		}
+	}
+
+	te.SetCursor(te.GetCursor().SetLineCol(best, 0))
+	te.ScrollToCursor()
+}
+
+// findNext selects the next occurrence of the text last searched for in the
+// active TextEdit, or beeps if there is none.
+func findNext() {
+	changeFocus(panelContainer)
+	te := getActiveTextEdit()
+	if te == nil || lastFind == "" {
+		return
+	}
+	found, err := te.Find([]byte(lastFind))
+	if err != nil {
+		showErrorDialog("Could not search file", fmt.Sprintf("File at %#v could not be searched. %v", te.FilePath, err), nil)
+	} else if !found {
+		(*screen).Beep()
+	}
+}
+
+// trackChanges starts showing the lines of the TextEdit that differ from the
+// version of its file committed in git. Files outside of a git repository, or
+// not committed, are silently not tracked.
+func trackChanges(te *ui.TextEdit) {
+	untrackChanges(te)
+	if te.FilePath == "" || te.LargeFile { // Tracking compares the whole file
+		return
+	}
+	if tracker, err := vcs.NewGitTracker(te.FilePath, *screen); err == nil {
+		changeTrackers[te] = tracker
+		te.SetSignProvider(vcs.ChangeSigns, tracker)
+	}
+}
+
+// untrackChanges stops showing the changes made to the file of the TextEdit.
+func untrackChanges(te *ui.TextEdit) {
+	if tracker, ok := changeTrackers[te]; ok {
+		tracker.Close()
+		delete(changeTrackers, te)
+	}
+	te.SetSignProvider(vcs.ChangeSigns, nil)
+}
+
+// gotoChange moves the cursor to the next or previous changed hunk.
+func gotoChange(forwards bool) {
+	if dv := getActiveDiffView(); dv != nil {
+		dv.NextHunk(forwards)
+		return
+	}
+
+	te := getActiveTextEdit()
+	if te == nil {
+		return
+	}
+	tracker, ok := changeTrackers[te]
+	if !ok {
+		return
+	}
+	tracker.UpdateNow(te)
+	cursLine, _ := te.GetCursor().GetLineCol()
+	if line, ok := tracker.NextHunk(cursLine, forwards); ok {
+		te.SetCursor(te.GetCursor().SetLineCol(line, 0))
+		te.ScrollToCursor()
+	}
+}
+
+// resolveConflict resolves the merge conflict under the cursor of the active
+// TextEdit, keeping the lines chosen by `r`.
+func resolveConflict(r buffer.Resolution) {
+	te := getActiveTextEdit()
+	if te != nil {
+		line, _ := te.GetCursor().GetLineCol()
+		te.ResolveConflict(line, r)
+		changeFocus(panelContainer)
+	}
+}
+
+// loadTasks returns the Tasks configured in the working directory, or the
+// DefaultTasks if there is no configuration.
+func loadTasks() []task.Task {
+	tasks, err := task.LoadTasks(task.ConfigFile)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			showErrorDialog("Could not load tasks", fmt.Sprintf("The tasks in %#v could not be loaded. The default tasks will be used, instead. %v", task.ConfigFile, err), nil)
+		}
+		return task.DefaultTasks
+	}
+	return tasks
+}
+
+// runTask stops the current Run, if any, and starts the Task, showing its output
+// in the outputView.
+func runTask(t task.Task) {
+	if currentRun != nil {
+		currentRun.Kill()
+	}
+
+	run, err := task.Start(t, *screen)
+	if err != nil {
+		showErrorDialog("Could not run task", fmt.Sprintf("Task %#v could not be started. %v", t.Name, err), nil)
+		return
+	}
+	currentRun = run
+	errorIdx = -1
+
+	outputView.Clear()
+	outputView.AppendLine("$ "+strings.Join(t.Command, " "), false)
+	showOutputView("Output: " + t.Name)
+	changeFocus(panelContainer)
+}
+
+// showOutputView shows the outputView in a tab named `name`. The tab is reused if
+// it is still open, otherwise it is added to the active TabContainer.
+func showOutputView(name string) {
+	if outputContainer != nil {
+		for i := 0; i < outputContainer.GetTabCount(); i++ {
+			if tab := outputContainer.GetTab(i); tab.Child == outputView {
+				tab.Name = name
+				return
+			}
+		}
+	}
+
+	outputContainer = getActiveTabContainer()
+	if outputContainer == nil {
+		outputContainer = ui.NewTabContainer(&theme)
+		panelContainer.SetSelected(outputContainer)
+	}
+	outputContainer.AddTab(name, outputView)
+	outputContainer.FocusTab(outputContainer.GetTabCount() - 1)
+}
+
+// gotoLocation shows the file of the Location in the active TabContainer, opening
+// it if it is not already open, and moves the cursor to the Location.
+func gotoLocation(loc task.Location) {
+	tabContainer := getActiveTabContainer()
+	if tabContainer == nil {
+		tabContainer = ui.NewTabContainer(&theme)
+		panelContainer.SetSelected(tabContainer)
+	}
+
+	path, _ := filepath.Abs(loc.File)
+	var te *ui.TextEdit
+	for i := 0; i < tabContainer.GetTabCount(); i++ {
+		if other, ok := tabContainer.GetTab(i).Child.(*ui.TextEdit); ok && other.FilePath != "" {
+			if otherPath, _ := filepath.Abs(other.FilePath); otherPath == path {
+				te = other
+				tabContainer.FocusTab(i)
+				break
+			}
+		}
+	}
+
+	if te == nil {
+		var err error
+		te, err = openTextEdit(loc.File)
+		if err != nil {
+			showErrorDialog("File could not be opened", fmt.Sprintf("File at %#v could not be opened. %v", loc.File, err), nil)
+			return
+		}
+		trackChanges(te)
+		tabContainer.AddTab(loc.File, te)
+		tabContainer.FocusTab(tabContainer.GetTabCount() - 1)
+	}
+
+	te.SetCursor(te.GetCursor().SetLineCol(loc.Line-1, ui.Max(0, loc.Col-1)))
+	te.ScrollToCursor()
+	changeFocus(panelContainer)
+}
+
+// gotoError goes to the next or previous Location found in the output of the
+// current Run, and selects its line in the outputView.
+func gotoError(forwards bool) {
+	if currentRun == nil || len(currentRun.Locations) == 0 {
+		return
+	}
+	count := len(currentRun.Locations)
+	if forwards {
+		errorIdx = (errorIdx + 1) % count
+	} else {
+		errorIdx = (errorIdx - 1 + count) % count
+	}
+	loc := currentRun.Locations[errorIdx]
+	outputView.SetSelected(loc.OutputLine + 1) // The first line shows the command
+	gotoLocation(loc)
+}
+
+// openTextEdit reads the file at `path` into a new TextEdit. A file of at least
+// largeFileSize bytes is mapped into memory, instead, and opened in large file mode.
+func openTextEdit(path string) (*ui.TextEdit, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	props := lookupEditorConfig(path)
+	var te *ui.TextEdit
+	if info.Size() >= largeFileSize {
+		file, err := buffer.MapFile(path)
+		if err != nil {
+			return nil, err
+		}
+		te = ui.NewLargeTextEdit(screen, path, file, &theme) // Not decoded from its charset
+	} else {
+		bytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if bytes, err = props.Decode(bytes); err != nil {
+			return nil, fmt.Errorf("could not decode from %s: %v", props["charset"], err)
+		}
+		te = ui.NewTextEdit(screen, path, bytes, &theme)
+	}
+	applyEditorConfig(te, props)
+	return te, nil
+}
+
+// lookupEditorConfig returns the EditorConfig properties of the file at `path`.
+// There are none if an EditorConfig file could not be read.
+func lookupEditorConfig(path string) editorconfig.Properties {
+	props, err := editorconfig.Lookup(path)
+	if err != nil {
+		return nil
+	}
+	return props
+}
+
+// applyEditorConfig sets the indentation and line delimiters of the TextEdit from
+// the EditorConfig properties of its file. The rest are applied when it is saved.
+func applyEditorConfig(te *ui.TextEdit, props editorconfig.Properties) {
+	editorConfigs[te] = props
+	switch props["indent_style"] {
+	case "tab":
+		te.UseHardTabs = true
+	case "space":
+		te.UseHardTabs = false
+	}
+	if te.UseHardTabs {
+		if width, ok := props.TabWidth(); ok {
+			te.TabSize = width
+		}
+	} else if size, ok := props.IndentSize(); ok {
+		te.TabSize = size
+	}
+	if length, ok := props.MaxLineLength(); ok {
+		te.WrapColumn = length // Soft wrap at the length lines are kept within
+	}
+	switch props["end_of_line"] {
+	case "lf":
+		te.IsCRLF = false
+	case "crlf":
+		te.IsCRLF = true
+	}
+}
+
+// getSaveContents makes the edits the EditorConfig properties of the TextEdit
+// ask for on saving, like trimming trailing whitespace, and returns the contents
+// to write to its file, in the charset and line delimiters of the properties.
+func getSaveContents(te *ui.TextEdit) ([]byte, error) {
+	props := editorConfigs[te]
+	if props["trim_trailing_whitespace"] == "true" {
+		te.TrimTrailingWhitespace()
+	}
+	switch props["insert_final_newline"] {
+	case "true":
+		te.SetFinalNewline(true)
+	case "false":
+		te.SetFinalNewline(false)
+	}
+	return props.Encode(te.Buffer.Bytes())
+}
+
+// showEncodeErrorDialog reports that the TextEdit could not be saved in the
+// charset of its file.
+func showEncodeErrorDialog(te *ui.TextEdit, path string, err error) {
+	showErrorDialog("Could not encode file", fmt.Sprintf("File at %#v could not be saved in the %s charset, so it was not written. %v", path, editorConfigs[te]["charset"], err), nil)
+}
+
+// replaceFile writes the buffer to a new file, which then replaces the file at
+// `path`. A large file is saved this way, because writing over the file would
+// change the memory it is mapped to while it is being written.
+func replaceFile(path string, buf buffer.Buffer) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // Fails once the file has been renamed
+
+	if info, err := os.Stat(path); err == nil {
+		f.Chmod(info.Mode()) // Keep the permissions of the file being replaced
+	}
+	if _, err = buf.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// showFormatErrorDialog reports that the TextEdit could not be formatted. The
+// callback is called when the dialog is closed, if it is not nil.
+func showFormatErrorDialog(te *ui.TextEdit, err error, callback func()) {
+	showErrorDialog("Could not format file", fmt.Sprintf("The %s formatter failed, so the file was left unformatted. %v", te.Language.Name, err), callback)
+}
+
+// getIgnoredProperties returns the EditorConfig properties of the TextEdit that
+// would change its contents on saving, if it is a large file. A large file is
+// written from its buffer as it is, so they are not applied.
+func getIgnoredProperties(te *ui.TextEdit) []string {
+	if !te.LargeFile {
+		return nil
+	}
+	props := editorConfigs[te]
+	var ignored []string
+	if charset, ok := props["charset"]; ok && charset != "utf-8" {
+		ignored = append(ignored, "charset")
+	}
+	if eol := props["end_of_line"]; eol == "lf" || eol == "crlf" {
+		ignored = append(ignored, "end_of_line")
+	}
+	if props["trim_trailing_whitespace"] == "true" {
+		ignored = append(ignored, "trim_trailing_whitespace")
+	}
+	if newline := props["insert_final_newline"]; newline == "true" || newline == "false" {
+		ignored = append(ignored, "insert_final_newline")
+	}
+	return ignored
+}
+
+// showIgnoredPropertiesDialog warns that the TextEdit was saved without the
+// EditorConfig properties returned by getIgnoredProperties, if it has any.
+func showIgnoredPropertiesDialog(te *ui.TextEdit) {
+	ignored := getIgnoredProperties(te)
+	if len(ignored) == 0 {
+		dialog = nil
+		changeFocus(panelContainer)
+		return
+	}
+	message := fmt.Sprintf("File at %#v is a large file, so it was saved as it is, without the %s of its EditorConfig.", te.FilePath, strings.Join(ignored, ", "))
+	dialog = ui.NewMessageDialog("Saved without EditorConfig", message, ui.MessageKindWarning, nil, &theme, func(string) {
+		dialog = nil
+		changeFocus(panelContainer)
+	})
+	changeFocus(dialog)
+}
+
 // Shows the Save As... dialog for saving unnamed files
 func saveAs() {
 	callback := func(filePaths []string) {
@@ -81,27 +529,55 @@ func saveAs() {
 		tabContainer := getActiveTabContainer()
 		tab := tabContainer.GetTab(tabContainer.GetSelectedTabIdx())
 
-		// If we got the callback, it is safe to assume there are one or more files
-		f, err := os.OpenFile(filePaths[0], os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fs.ModePerm)
-		if err != nil {
-			showErrorDialog("Could not open file for writing", fmt.Sprintf("File at %#v could not be opened with write permissions. Maybe another program has it open? %v", filePaths[0], err), nil)
-			return
+		if te.FilePath == "" { // The filetype is known for the first time
+			te.SetLanguage(buffer.LanguageByFilename(filePaths[0]))
+		}
+		applyEditorConfig(te, lookupEditorConfig(filePaths[0]))
+		var formatErr error
+		if formatOnSave {
+			formatErr = te.Format()
 		}
-		defer f.Close()
 
-		_, err = te.Buffer.WriteTo(f)
-		if err != nil {
-			showErrorDialog("Failed to write to file", fmt.Sprintf("File at %#v was opened for writing, but an error occurred while writing the buffer. %v", filePaths[0], err), nil)
-			return
+		// If we got the callback, it is safe to assume there are one or more files
+		if te.LargeFile {
+			if err := replaceFile(filePaths[0], te.Buffer); err != nil {
+				showErrorDialog("Failed to write to file", fmt.Sprintf("File at %#v could not be replaced with the buffer. %v", filePaths[0], err), nil)
+				return
+			}
+		} else {
+			contents, err := getSaveContents(te)
+			if err != nil {
+				showEncodeErrorDialog(te, filePaths[0], err)
+				return
+			}
+			f, err := os.OpenFile(filePaths[0], os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fs.ModePerm)
+			if err != nil {
+				showErrorDialog("Could not open file for writing", fmt.Sprintf("File at %#v could not be opened with write permissions. Maybe another program has it open? %v", filePaths[0], err), nil)
+				return
+			}
+			defer f.Close()
+
+			_, err = f.Write(contents)
+			if err != nil {
+				showErrorDialog("Failed to write to file", fmt.Sprintf("File at %#v was opened for writing, but an error occurred while writing the buffer. %v", filePaths[0], err), nil)
+				return
+			}
 		}
 		te.Dirty = false
 
 		te.FilePath = filePaths[0]
 		tab.Name = filePaths[0]
+		trackChanges(te)
 
 		dialog = nil // Hide the file selector
 		changeFocus(panelContainer)
 		tab.Name = filePaths[0]
+
+		if formatErr != nil {
+			showFormatErrorDialog(te, formatErr, func() { showIgnoredPropertiesDialog(te) })
+		} else {
+			showIgnoredPropertiesDialog(te)
+		}
 	}
 
 	dialog = ui.NewFileSelectorDialog(
@@ -144,6 +620,8 @@ func main() {
 	}
 	defer s.Fini() // Useful for handling panics
 
+	s.EnableMouse() // For clicking, selecting and scrolling in a TextEdit
+
 	var closing bool
 	sizex, sizey := s.Size()
 
@@ -162,27 +640,22 @@ func main() {
 			_, err := os.Stat(arg)
 
 			var dirty bool
-			var bytes []byte
+			var textEdit *ui.TextEdit
 
 			if errors.Is(err, os.ErrNotExist) { // If the file does not exist...
 				dirty = true
+				textEdit = ui.NewTextEdit(screen, arg, []byte{}, &theme)
+				applyEditorConfig(textEdit, lookupEditorConfig(arg))
 			} else { // If the file exists...
-				file, err := os.Open(arg)
+				textEdit, err = openTextEdit(arg)
 				if err != nil {
 					showErrorDialog("File could not be opened", fmt.Sprintf("File at %#v could not be opened. %v", arg, err), nil)
 					continue
 				}
-				defer file.Close()
-
-				bytes, err = ioutil.ReadAll(file)
-				if err != nil {
-					showErrorDialog("Could not read file", fmt.Sprintf("File at %#v was opened, but could not be read. %v", arg, err), nil)
-					continue
-				}
 			}
 
-			textEdit := ui.NewTextEdit(screen, arg, bytes, &theme)
 			textEdit.Dirty = dirty
+			trackChanges(textEdit)
 			getActiveTabContainer().AddTab(arg, textEdit)
 		}
 		panelContainer.SetFocused(true) // Lets any opened TextEdit component know to be focused
@@ -213,22 +686,13 @@ func main() {
 
 			var errOccurred bool
 			for _, path := range filePaths {
-				file, err := os.Open(path)
+				textEdit, err := openTextEdit(path)
 				if err != nil {
 					showErrorDialog("File could not be opened", fmt.Sprintf("File at %#v could not be opened. %v", path, err), nil)
 					errOccurred = true
 					continue
 				}
-				defer file.Close()
-
-				bytes, err := ioutil.ReadAll(file)
-				if err != nil {
-					showErrorDialog("Could not read file", fmt.Sprintf("File at %#v was opened, but could not be read. %v", path, err), nil)
-					errOccurred = true
-					continue
-				}
-
-				textEdit := ui.NewTextEdit(screen, path, bytes, &theme)
+				trackChanges(textEdit)
 				if tabContainer == nil {
 					tabContainer = ui.NewTabContainer(&theme)
 					panelContainer.SetSelected(tabContainer)
@@ -260,29 +724,112 @@ func main() {
 		te := getActiveTextEdit()
 		if te != nil {
 			if te.FilePath != "" {
-				f, err := os.OpenFile(te.FilePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fs.ModePerm)
-				if err != nil {
-					showErrorDialog("Could not open file for writing", fmt.Sprintf("File at %#v could not be opened with write permissions. Maybe another program has it open? %v", te.FilePath, err), nil)
-					return
+				var formatErr error
+				if formatOnSave {
+					formatErr = te.Format()
 				}
-				defer f.Close()
 
-				_, err = te.Buffer.WriteTo(f) // TODO: check count
-				if err != nil {
-					showErrorDialog("Failed to write to file", fmt.Sprintf("File at %#v was opened for writing, but an error occurred while writing the buffer. %v", te.FilePath, err), nil)
-					return
+				if te.LargeFile {
+					if err := replaceFile(te.FilePath, te.Buffer); err != nil {
+						showErrorDialog("Failed to write to file", fmt.Sprintf("File at %#v could not be replaced with the buffer. %v", te.FilePath, err), nil)
+						return
+					}
+				} else {
+					contents, err := getSaveContents(te)
+					if err != nil {
+						showEncodeErrorDialog(te, te.FilePath, err)
+						return
+					}
+					f, err := os.OpenFile(te.FilePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fs.ModePerm)
+					if err != nil {
+						showErrorDialog("Could not open file for writing", fmt.Sprintf("File at %#v could not be opened with write permissions. Maybe another program has it open? %v", te.FilePath, err), nil)
+						return
+					}
+					defer f.Close()
+
+					_, err = f.Write(contents) // TODO: check count
+					if err != nil {
+						showErrorDialog("Failed to write to file", fmt.Sprintf("File at %#v was opened for writing, but an error occurred while writing the buffer. %v", te.FilePath, err), nil)
+						return
+					}
 				}
 				te.Dirty = false
 
 				changeFocus(panelContainer)
+				if formatErr != nil {
+					showFormatErrorDialog(te, formatErr, func() { showIgnoredPropertiesDialog(te) })
+				} else {
+					showIgnoredPropertiesDialog(te)
+				}
 			} else {
 				saveAs()
 			}
 		}
 	}}, &ui.ItemEntry{Name: "Save As...", QuickChar: 5, Callback: saveAs}, &ui.ItemSeparator{},
+		&ui.ItemEntry{Name: "Compare with Saved", QuickChar: 2, Callback: func() {
+			te := getActiveTextEdit()
+			if te == nil || te.FilePath == "" {
+				return
+			}
+			if te.LargeFile {
+				showErrorDialog("Cannot compare file", fmt.Sprintf("File at %#v is too large to be compared.", te.FilePath), nil)
+				return
+			}
+			saved, err := ioutil.ReadFile(te.FilePath)
+			if err == nil {
+				saved, err = editorConfigs[te].Decode(saved) // Compare text, like the buffer's
+			}
+			if err != nil {
+				showErrorDialog("Could not read file", fmt.Sprintf("File at %#v could not be read for comparison. %v", te.FilePath, err), nil)
+				return
+			}
+			dv := ui.NewDiffView(screen, "Saved: "+te.FilePath, saved, "Buffer: "+te.FilePath, te.Buffer.Bytes(), &theme)
+			dv.TabSize = te.TabSize
+			showDiffView("Compare "+te.FilePath, dv)
+			changeFocus(panelContainer)
+		}}, &ui.ItemEntry{Name: "Compare Files...", QuickChar: 8, Callback: func() {
+			callback := func(filePaths []string) {
+				if len(filePaths) != 2 {
+					showErrorDialog("Cannot compare files", fmt.Sprintf("Two files are needed for comparison, but %d were given.", len(filePaths)), nil)
+					return
+				}
+
+				var contents [2][]byte
+				for i, path := range filePaths {
+					var err error
+					contents[i], err = ioutil.ReadFile(path)
+					if err != nil {
+						showErrorDialog("Could not read file", fmt.Sprintf("File at %#v could not be read for comparison. %v", path, err), nil)
+						return
+					}
+				}
+
+				dialog = nil // Hide the file selector
+				dv := ui.NewDiffView(screen, filePaths[0], contents[0], filePaths[1], contents[1], &theme)
+				showDiffView(fmt.Sprintf("%s ↔ %s", filePaths[0], filePaths[1]), dv)
+				changeFocus(panelContainer)
+			}
+			dialog = ui.NewFileSelectorDialog(
+				screen,
+				"Two comma-separated files to compare",
+				true,
+				&theme,
+				callback,
+				func() { // Dialog is canceled
+					dialog = nil
+					changeFocus(panelContainer)
+				},
+			)
+			changeFocus(dialog)
+		}}, &ui.ItemSeparator{},
 		&ui.ItemEntry{Name: "Close", Shortcut: "Ctrl+Q", Callback: func() {
 			tabContainer := getActiveTabContainer()
 			if tabContainer != nil && tabContainer.GetTabCount() > 0 {
+				if te := getActiveTextEdit(); te != nil {
+					untrackChanges(te)
+					delete(editorConfigs, te)
+					te.Close()
+				}
 				tabContainer.RemoveTab(tabContainer.GetSelectedTabIdx())
 			} else {
 				// if the selected is root: close editor. otherwise close panel
@@ -352,6 +899,7 @@ func main() {
 				if err != nil {
 					showErrorDialog("Clipboard Failure", fmt.Sprintf("%v", err), nil)
 				}
+				setBlockClipboard(te, bytes)
 				te.Delete(false) // Delete selection
 			}
 			if err == nil { // Prevent hiding error dialog
@@ -368,6 +916,7 @@ func main() {
 				if err != nil {
 					showErrorDialog("Clipboard Failure", fmt.Sprintf("%v", err), nil)
 				}
+				setBlockClipboard(te, bytes)
 			}
 			if err == nil {
 				changeFocus(panelContainer)
@@ -379,21 +928,190 @@ func main() {
 			contents, err := clipboard.ClipRead()
 			if err != nil {
 				showErrorDialog("Clipboard Failure", fmt.Sprintf("%v", err), nil)
+			} else if contents != "" && contents == blockClipboard {
+				te.InsertBlock(contents) // What was copied as a block is pasted as one
+				changeFocus(panelContainer)
 			} else {
 				te.Insert(contents)
 				changeFocus(panelContainer)
 			}
 		}
+	}}, &ui.ItemSeparator{}, &ui.ItemEntry{Name: "Format Document", QuickChar: 0, Callback: func() {
+		te := getActiveTextEdit()
+		if te != nil {
+			changeFocus(panelContainer)
+			if err := te.Format(); err != nil {
+				showFormatErrorDialog(te, err, nil)
+			}
+		}
+	}}, &ui.ItemEntry{Name: "Format on Save", QuickChar: 10, Callback: func() {
+		formatOnSave = !formatOnSave
+		changeFocus(panelContainer)
+	}}, &ui.ItemSeparator{}, &ui.ItemEntry{Name: "Indent Lines", QuickChar: 0, Callback: func() {
+		te := getActiveTextEdit()
+		if te != nil {
+			te.IndentLines()
+			changeFocus(panelContainer)
+		}
+	}}, &ui.ItemEntry{Name: "Outdent Lines", QuickChar: 0, Callback: func() {
+		te := getActiveTextEdit()
+		if te != nil {
+			te.OutdentLines()
+			changeFocus(panelContainer)
+		}
+	}}, &ui.ItemEntry{Name: "Toggle Comment", QuickChar: 7, Shortcut: "Ctrl+_", Callback: func() {
+		te := getActiveTextEdit()
+		if te != nil {
+			te.ToggleComment()
+			changeFocus(panelContainer)
+		}
+	}}, &ui.ItemEntry{Name: "Convert Indentation to Tabs", QuickChar: 23, Callback: func() {
+		te := getActiveTextEdit()
+		if te != nil {
+			te.ConvertIndentation(true)
+			changeFocus(panelContainer)
+		}
+	}}, &ui.ItemEntry{Name: "Convert Indentation to Spaces", QuickChar: 23, Callback: func() {
+		te := getActiveTextEdit()
+		if te != nil {
+			te.ConvertIndentation(false)
+			changeFocus(panelContainer)
+		}
+	}}, &ui.ItemSeparator{}, &ui.ItemEntry{Name: "Auto-Close Brackets", QuickChar: 5, Callback: func() {
+		te := getActiveTextEdit()
+		if te != nil {
+			te.AutoPair = !te.AutoPair
+			changeFocus(panelContainer)
+		}
+	}}, &ui.ItemEntry{Name: "Auto-Indent", QuickChar: 5, Callback: func() {
+		te := getActiveTextEdit()
+		if te != nil {
+			te.AutoIndent = !te.AutoIndent
+			changeFocus(panelContainer)
+		}
+	}}, &ui.ItemEntry{Name: "Word Wrap", QuickChar: 5, Callback: func() {
+		te := getActiveTextEdit()
+		if te != nil {
+			te.SoftWrap = !te.SoftWrap
+			te.ScrollToCursor()
+			changeFocus(panelContainer)
+		}
+	}}, &ui.ItemEntry{Name: "Wrap Column...", QuickChar: 5, Callback: func() {
+		te := getActiveTextEdit()
+		if te != nil {
+			callback := func(text string) {
+				dialog = nil // Hide dialog
+				column, err := strconv.Atoi(strings.TrimSpace(text))
+				if err == nil && column >= 0 {
+					te.WrapColumn = column // 0 wraps at the width of the view
+					te.SoftWrap = true
+					te.ScrollToCursor()
+				}
+				changeFocus(panelContainer)
+			}
+			dialog = internal_ui.NewInputDialog(screen, "Wrap Column", &theme, callback, func() {
+				// Dialog canceled
+				dialog = nil
+				changeFocus(panelContainer)
+			})
+			changeFocus(dialog)
+		}
+	}}, &ui.ItemEntry{Name: "Revert Change", QuickChar: 0, Callback: func() {
+		te := getActiveTextEdit()
+		if te != nil {
+			if tracker, ok := changeTrackers[te]; ok {
+				line, _ := te.GetCursor().GetLineCol()
+				tracker.RevertHunk(te, line)
+			}
+			changeFocus(panelContainer)
+		}
+	}}, &ui.ItemSeparator{}, &ui.ItemEntry{Name: "Accept Ours", QuickChar: 7, Callback: func() {
+		resolveConflict(buffer.ResolveOurs)
+	}}, &ui.ItemEntry{Name: "Accept Theirs", QuickChar: 7, Callback: func() {
+		resolveConflict(buffer.ResolveTheirs)
+	}}, &ui.ItemEntry{Name: "Accept Both", QuickChar: 8, Callback: func() {
+		resolveConflict(buffer.ResolveBoth)
+	}}, &ui.ItemEntry{Name: "Accept Base", QuickChar: 9, Callback: func() {
+		resolveConflict(buffer.ResolveBase)
 	}}, &ui.ItemSeparator{}, &ui.ItemEntry{Name: "Select All", QuickChar: 7, Shortcut: "Ctrl+A", Callback: func() {
 
 	}}, &ui.ItemEntry{Name: "Select Line", QuickChar: 7, Callback: func() {
 
+	}}, &ui.ItemSeparator{}, &ui.ItemEntry{Name: "Add Cursor Above", QuickChar: 11, Shortcut: "Alt+Ctrl+Up", Callback: func() {
+		te := getActiveTextEdit()
+		if te != nil {
+			te.AddCursorAbove()
+			changeFocus(panelContainer)
+		}
+	}}, &ui.ItemEntry{Name: "Add Cursor Below", QuickChar: 11, Shortcut: "Alt+Ctrl+Down", Callback: func() {
+		te := getActiveTextEdit()
+		if te != nil {
+			te.AddCursorBelow()
+			changeFocus(panelContainer)
+		}
+	}}, &ui.ItemEntry{Name: "Add Next Occurrence", QuickChar: 4, Shortcut: "Ctrl+D", Callback: func() {
+		te := getActiveTextEdit()
+		if te != nil {
+			te.AddNextOccurrence()
+			changeFocus(panelContainer)
+		}
+	}}, &ui.ItemEntry{Name: "Split Selection into Lines", QuickChar: 6, Shortcut: "Ctrl+L", Callback: func() {
+		te := getActiveTextEdit()
+		if te != nil {
+			te.SplitSelectionIntoLines()
+			changeFocus(panelContainer)
+		}
+	}}, &ui.ItemEntry{Name: "Remove Extra Cursors", QuickChar: 7, Callback: func() {
+		te := getActiveTextEdit()
+		if te != nil {
+			te.RemoveExtraCursors()
+			changeFocus(panelContainer)
+		}
+	}}, &ui.ItemSeparator{}, &ui.ItemEntry{Name: "Fold", QuickChar: 0, Callback: func() {
+		te := getActiveTextEdit()
+		if te != nil {
+			te.Fold()
+			changeFocus(panelContainer)
+		}
+	}}, &ui.ItemEntry{Name: "Unfold", QuickChar: 0, Callback: func() {
+		te := getActiveTextEdit()
+		if te != nil {
+			te.Unfold()
+			changeFocus(panelContainer)
+		}
+	}}, &ui.ItemEntry{Name: "Fold All", QuickChar: 5, Callback: func() {
+		te := getActiveTextEdit()
+		if te != nil {
+			te.FoldAll()
+			changeFocus(panelContainer)
+		}
+	}}, &ui.ItemEntry{Name: "Unfold All", QuickChar: 7, Callback: func() {
+		te := getActiveTextEdit()
+		if te != nil {
+			te.UnfoldAll()
+			changeFocus(panelContainer)
+		}
 	}}})
 
 	searchMenu := ui.NewMenu("Search", 0, &theme)
 
-	searchMenu.AddItems([]ui.Item{&ui.ItemEntry{Name: "Find and Replace...", Shortcut: "Ctrl+F", Callback: func() {
-		s.Beep()
+	searchMenu.AddItems([]ui.Item{&ui.ItemEntry{Name: "Find...", Shortcut: "Ctrl+F", Callback: func() {
+		if getActiveTextEdit() == nil {
+			return
+		}
+		callback := func(text string) {
+			dialog = nil // Hide dialog
+			lastFind = text
+			findNext()
+		}
+		dialog = internal_ui.NewInputDialog(screen, "Find", &theme, callback, func() {
+			// Dialog canceled
+			dialog = nil
+			changeFocus(panelContainer)
+		})
+		changeFocus(dialog)
+	}}, &ui.ItemEntry{Name: "Find Next", QuickChar: 5, Shortcut: "F3", Callback: func() {
+		findNext()
 	}}, &ui.ItemEntry{Name: "Find in Directory...", QuickChar: 8, Callback: func() {
 
 	}}, &ui.ItemSeparator{}, &ui.ItemEntry{Name: "Go to line...", Shortcut: "Ctrl+G", Callback: func() {
@@ -413,16 +1131,115 @@ func main() {
 			})
 			changeFocus(dialog)
 		}
+	}}, &ui.ItemEntry{Name: "Go to Matching Bracket", QuickChar: 15, Shortcut: "Ctrl+]", Callback: func() {
+		te := getActiveTextEdit()
+		if te != nil {
+			te.JumpToMatchingBracket()
+			changeFocus(panelContainer)
+		}
+	}}, &ui.ItemSeparator{}, &ui.ItemEntry{Name: "Toggle Bookmark", Shortcut: "Ctrl+B", Callback: func() {
+		te := getActiveTextEdit()
+		if te != nil {
+			toggleBookmark(te)
+			changeFocus(panelContainer)
+		}
+	}}, &ui.ItemEntry{Name: "Next Bookmark", QuickChar: 0, Callback: func() {
+		te := getActiveTextEdit()
+		if te != nil {
+			gotoBookmark(te, true)
+			changeFocus(panelContainer)
+		}
+	}}, &ui.ItemEntry{Name: "Previous Bookmark", QuickChar: 0, Callback: func() {
+		te := getActiveTextEdit()
+		if te != nil {
+			gotoBookmark(te, false)
+			changeFocus(panelContainer)
+		}
+	}}, &ui.ItemSeparator{}, &ui.ItemEntry{Name: "Next Change", QuickChar: 5, Callback: func() {
+		gotoChange(true)
+		changeFocus(panelContainer)
+	}}, &ui.ItemEntry{Name: "Previous Change", QuickChar: 9, Callback: func() {
+		gotoChange(false)
+		changeFocus(panelContainer)
+	}}, &ui.ItemEntry{Name: "Next Conflict", QuickChar: 6, Callback: func() {
+		te := getActiveTextEdit()
+		if te != nil {
+			te.NextConflict(true)
+			changeFocus(panelContainer)
+		}
+	}}, &ui.ItemEntry{Name: "Previous Conflict", QuickChar: 10, Callback: func() {
+		te := getActiveTextEdit()
+		if te != nil {
+			te.NextConflict(false)
+			changeFocus(panelContainer)
+		}
+	}}})
+
+	outputView = ui.NewOutputView(screen, &theme)
+	outputView.LineChosenCallback = func(idx int) {
+		if currentRun == nil {
+			return
+		}
+		for i, loc := range currentRun.Locations {
+			if loc.OutputLine+1 == idx { // The first line shows the command
+				errorIdx = i
+				gotoLocation(loc)
+				return
+			}
+		}
+	}
+
+	runMenu := ui.NewMenu("Run", 0, &theme)
+
+	var taskItems []ui.Item
+	for _, t := range loadTasks() {
+		t := t
+		taskItems = append(taskItems, &ui.ItemEntry{Name: t.Name, Callback: func() {
+			runTask(t)
+		}})
+	}
+	runMenu.AddItems(taskItems)
+	runMenu.AddItems([]ui.Item{&ui.ItemSeparator{}, &ui.ItemEntry{Name: "Run Command...", QuickChar: 4, Callback: func() {
+		callback := func(command string) {
+			dialog = nil // Hide dialog
+			if command = strings.TrimSpace(command); command == "" {
+				changeFocus(panelContainer)
+				return
+			}
+			runTask(task.Task{Name: command, Command: task.ShellCommand(command)})
+		}
+		dialog = internal_ui.NewInputDialog(screen, "Run Command", &theme, callback, func() {
+			// Dialog canceled
+			dialog = nil
+			changeFocus(panelContainer)
+		})
+		changeFocus(dialog)
+	}}, &ui.ItemEntry{Name: "Stop Task", QuickChar: 0, Callback: func() {
+		if currentRun != nil {
+			currentRun.Kill()
+		}
+		changeFocus(panelContainer)
+	}}, &ui.ItemSeparator{}, &ui.ItemEntry{Name: "Next Error", QuickChar: 0, Callback: func() {
+		gotoError(true)
+		changeFocus(panelContainer)
+	}}, &ui.ItemEntry{Name: "Previous Error", QuickChar: 0, Callback: func() {
+		gotoError(false)
+		changeFocus(panelContainer)
 	}}})
 
 	menuBar.AddMenu(fileMenu)
 	menuBar.AddMenu(panelMenu)
 	menuBar.AddMenu(editMenu)
 	menuBar.AddMenu(searchMenu)
+	menuBar.AddMenu(runMenu)
 
 	for !closing {
 		s.Clear()
 
+		for te, tracker := range changeTrackers {
+			tracker.Update(te) // Only compares the lines edited since the last Update
+		}
+
 		// Draw background (grey and black checkerboard)
 		// TODO: draw checkered background on panics with error dialog
 		//ui.DrawRect(screen, 0, 0, sizex, sizey, '▚', tcell.Style{}.Foreground(tcell.ColorGrey).Background(tcell.ColorBlack))
@@ -454,12 +1271,36 @@ func main() {
 
 			var tabs string
 			if te.UseHardTabs {
-				tabs = "Tabs: Hard"
+				tabs = fmt.Sprintf("Tabs: Hard (%d)", te.TabSize)
 			} else {
-				tabs = "Tabs: Spaces"
+				tabs = fmt.Sprintf("Tabs: Spaces (%d)", te.TabSize)
 			}
 
-			str := fmt.Sprintf(" Filetype: %s  %d, %d  %s  %s", "None", line+1, col+1, delim, tabs)
+			str := fmt.Sprintf(" Filetype: %s  %d, %d  %s  %s", te.Language.Name, line+1, col+1, delim, tabs)
+			if te.AutoPair {
+				str += "  Auto-Close"
+			}
+			if te.LargeFile {
+				str += "  Large file"
+				if done, total := te.GetLoadProgress(); done < total {
+					str += fmt.Sprintf(" (counting lines: %d%%)", done*100/total)
+				}
+				if te.Err() != nil {
+					str += " (truncated by another program)"
+				}
+			}
+			if cursors := te.GetCursorCount(); cursors > 1 {
+				str += fmt.Sprintf("  Cursors: %d", cursors)
+			}
+			if conflicts := len(te.GetConflicts()); conflicts > 0 {
+				str += fmt.Sprintf("  Conflicts: %d", conflicts)
+			}
+			if signs := te.GetSigns(line); len(signs) > 0 && signs[0].Tooltip != "" {
+				str += "  " + signs[0].Tooltip // Show what the sign on this line means
+			}
+			ui.DrawStr(s, 0, sizey-1, str, theme["StatusBar"])
+		} else if dv := getActiveDiffView(); dv != nil {
+			str := fmt.Sprintf(" Diff: %d hunks  (n) next  (p) previous", dv.GetHunkCount())
 			ui.DrawStr(s, 0, sizey-1, str, theme["StatusBar"])
 		}
 
@@ -477,6 +1318,10 @@ func main() {
 			// On Escape, we change focus between editor and the MenuBar.
 			if dialog == nil {
 				if ev.Key() == tcell.KeyEscape {
+					if te := getActiveTextEdit(); focusedComponent == panelContainer && te != nil && te.GetCursorCount() > 1 {
+						te.RemoveExtraCursors() // Escape leaves multi-cursor editing first
+						continue
+					}
 					if focusedComponent == panelContainer {
 						changeFocus(menuBar)
 					} else {
@@ -493,6 +1338,29 @@ func main() {
 			}
 
 			focusedComponent.HandleEvent(ev)
+		case *tcell.EventMouse:
+			if dialog == nil && focusedComponent == panelContainer {
+				focusedComponent.HandleEvent(ev)
+			}
+		case *ui.EventHighlight:
+			ev.Apply()
+		case *vcs.EventDiff:
+			ev.Apply()
+		case *ui.EventLoadProgress:
+			// Nothing to do: the progress is drawn in the status bar
+		case *task.EventOutput:
+			if ev.Run == currentRun { // Ignore the remaining output of stopped tasks
+				_, ok := ev.Run.AddLine(ev.Line)
+				outputView.AppendLine(ev.Line, ok)
+			}
+		case *task.EventDone:
+			if ev.Run == currentRun {
+				if ev.Err != nil {
+					outputView.AppendLine(fmt.Sprintf("[%s: %v]", ev.Run.Task.Name, ev.Err), false)
+				} else {
+					outputView.AppendLine(fmt.Sprintf("[%s: done]", ev.Run.Task.Name), false)
+				}
+			}
 		}
 	}
 
@@ -5,15 +5,15 @@ go 1.21
 require (
 	github.com/gdamore/tcell/v2 v2.6.0
 	github.com/mattn/go-runewidth v0.0.15
+	github.com/rivo/uniseg v0.4.4
 	github.com/zyedidia/clipboard v1.0.4
 	github.com/zyedidia/rope v0.0.0-20210616205215-37fbf22eab3a
+	golang.org/x/text v0.12.0
 )
 
 require (
 	github.com/gdamore/encoding v1.0.0 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
-	github.com/rivo/uniseg v0.4.4 // indirect
 	golang.org/x/sys v0.11.0 // indirect
 	golang.org/x/term v0.11.0 // indirect
-	golang.org/x/text v0.12.0 // indirect
 )
@@ -0,0 +1,133 @@
+// Package editorconfig finds the properties that EditorConfig files give a file.
+// The .editorconfig files in the directory of the file, and every directory above
+// it up to one marked as the root, are read. See https://editorconfig.org.
+package editorconfig
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Filename is the name of EditorConfig files.
+const Filename = ".editorconfig"
+
+// Properties are the properties given to a file, by lowercase name. Their values
+// are lowercase, too. Properties which are unset are absent.
+type Properties map[string]string
+
+// A section is a glob and the properties it gives the files it matches.
+type section struct {
+	glob       string
+	properties [][2]string // In the order they are given
+}
+
+// A file is a parsed EditorConfig file.
+type file struct {
+	root     bool // Whether the files of directories above are not read
+	sections []section
+}
+
+// parse reads an EditorConfig file from `r`. Lines which can't be parsed are
+// ignored, like other editors do.
+func parse(r io.Reader) (*file, error) {
+	f := &file{}
+	var current *section
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' && line[len(line)-1] == ']' {
+			f.sections = append(f.sections, section{glob: line[1 : len(line)-1]})
+			current = &f.sections[len(f.sections)-1]
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.ToLower(strings.TrimSpace(value))
+		if current == nil { // The preamble, before any section
+			if key == "root" {
+				f.root = value == "true"
+			}
+			continue
+		}
+		current.properties = append(current.properties, [2]string{key, value})
+	}
+	return f, scanner.Err()
+}
+
+// parseFile reads the EditorConfig file at `path`.
+func parseFile(path string) (*file, error) {
+	r, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return parse(r)
+}
+
+// apply sets the properties of the sections matching `path` in `props`. The
+// globs are relative to `dir`, the directory of the EditorConfig file. Later
+// sections take precedence over earlier ones.
+func (f *file) apply(dir, path string, props Properties) {
+	for _, s := range f.sections {
+		m, err := compileGlob(dir, s.glob)
+		if err != nil || !m.match(path) {
+			continue
+		}
+		for _, p := range s.properties {
+			if p[1] == "unset" {
+				delete(props, p[0])
+			} else {
+				props[p[0]] = p[1]
+			}
+		}
+	}
+}
+
+// Lookup returns the properties of the file at `path`, which need not exist,
+// from the EditorConfig files above it. Nearer files take precedence. An error is
+// returned if an EditorConfig file could not be read.
+func Lookup(path string) (Properties, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	var files []*file // Nearest first
+	for dir := filepath.Dir(path); ; {
+		f, err := parseFile(filepath.Join(dir, Filename))
+		if err == nil {
+			dirs = append(dirs, dir)
+			files = append(files, f)
+			if f.root {
+				break
+			}
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	props := Properties{}
+	for i := len(files) - 1; i >= 0; i-- {
+		files[i].apply(filepath.ToSlash(dirs[i]), filepath.ToSlash(path), props)
+	}
+	return props, nil
+}
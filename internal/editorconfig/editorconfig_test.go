@@ -0,0 +1,104 @@
+package editorconfig
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGlob(t *testing.T) {
+	tests := []struct {
+		glob, path string
+		match      bool
+	}{
+		{"*", "/p/a.go", true},
+		{"*.go", "/p/sub/a.go", true},
+		{"*.go", "/p/a.go.txt", false},
+		{"sub/*.go", "/p/sub/a.go", true},
+		{"/sub/*.go", "/p/sub/a.go", true},
+		{"sub/*.go", "/p/sub/deep/a.go", false},
+		{"sub/**.go", "/p/sub/deep/a.go", true},
+		{"a?.go", "/p/ab.go", true},
+		{"[abc].go", "/p/b.go", true},
+		{"[!abc].go", "/p/b.go", false},
+		{"*.{yml,yaml}", "/p/a.yaml", true},
+		{"*.{yml,yaml}", "/p/a.json", false},
+		{"{a,{b,c}}.go", "/p/c.go", true},
+		{"{single}.go", "/p/{single}.go", true},
+		{"file{1..10}.txt", "/p/file7.txt", true},
+		{"file{1..10}.txt", "/p/file11.txt", false},
+		{"Makefile", "/p/sub/Makefile", true},
+		{`\*.go`, "/p/a.go", false},
+	}
+	for _, test := range tests {
+		m, err := compileGlob("/p", test.glob)
+		if err != nil {
+			t.Errorf("Glob %q: %v", test.glob, err)
+			continue
+		}
+		if match := m.match(test.path); match != test.match {
+			t.Errorf("Glob %q matching %q: got %v, expected %v", test.glob, test.path, match, test.match)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	dir := t.TempDir()
+	write := func(path, contents string) {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(".editorconfig", "root = true\n\n[*]\nindent_style = Tab\ncharset = utf-8\n\n[*.yaml]\nindent_style = space\nindent_size = 2\n")
+	write("sub/.editorconfig", "; Nearer files take precedence\n[*.yaml]\nindent_size = 4\ncharset = unset\nmax_line_length = 100\n")
+
+	props, err := Lookup(filepath.Join(dir, "sub", "a.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (Properties{"indent_style": "space", "indent_size": "4", "max_line_length": "100"}); !reflect.DeepEqual(props, expected) {
+		t.Errorf("Got %v, expected %v", props, expected)
+	}
+	if size, ok := props.IndentSize(); !ok || size != 4 {
+		t.Errorf("Expected an indent size of 4, got %v", size)
+	}
+	if length, ok := props.MaxLineLength(); !ok || length != 100 {
+		t.Errorf("Expected a max line length of 100, got %v", length)
+	}
+
+	props, err = Lookup(filepath.Join(dir, "a.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (Properties{"indent_style": "tab", "charset": "utf-8"}); !reflect.DeepEqual(props, expected) {
+		t.Errorf("Got %v, expected %v", props, expected)
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	props := Properties{"charset": "latin1", "end_of_line": "crlf"}
+	encoded, err := props.Encode([]byte("café\nx\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "caf\xe9\r\nx\r\n"; string(encoded) != expected {
+		t.Errorf("Encoded %q, expected %q", encoded, expected)
+	}
+	if decoded, err := props.Decode(encoded); err != nil || string(decoded) != "café\r\nx\r\n" {
+		t.Errorf("Decoded %q, %v", decoded, err)
+	}
+	if _, err = props.Encode([]byte("世")); err == nil {
+		t.Errorf("Expected an error encoding a rune not in the charset")
+	}
+
+	props = Properties{"charset": "utf-8-bom"}
+	encoded, _ = props.Encode([]byte("a"))
+	if decoded, _ := props.Decode(encoded); string(encoded) != "\xef\xbb\xbfa" || string(decoded) != "a" {
+		t.Errorf("Encoded %q and decoded %q with a byte order mark", encoded, decoded)
+	}
+}
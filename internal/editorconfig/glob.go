@@ -0,0 +1,163 @@
+package editorconfig
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// numRange matches the contents of a glob's {num1..num2}.
+var numRange = regexp.MustCompile(`^([+-]?\d+)\.\.([+-]?\d+)$`)
+
+// A matcher matches paths against a glob, which has been translated to a regexp.
+type matcher struct {
+	re     *regexp.Regexp
+	ranges [][2]int // Bounds of each {num1..num2}, in the order of their groups
+}
+
+// compileGlob translates the glob of a section in an EditorConfig file in the
+// directory `dir`. A glob without a slash matches files by name in any directory
+// under `dir`; otherwise it matches paths relative to `dir`.
+func compileGlob(dir, glob string) (*matcher, error) {
+	m := &matcher{}
+	prefix := regexp.QuoteMeta(strings.TrimSuffix(dir, "/")) + "/"
+	if !strings.Contains(glob, "/") {
+		prefix += "(?:.*/)?"
+	}
+	re, err := regexp.Compile("^" + prefix + m.translate(strings.TrimPrefix(glob, "/")) + "$")
+	if err != nil {
+		return nil, err
+	}
+	m.re = re
+	return m, nil
+}
+
+// translate returns the regexp matching what `glob` does. The bounds of every
+// numeric range are added to the matcher, as its group is written.
+func (m *matcher) translate(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+			} else {
+				b.WriteString(`\\`)
+			}
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				b.WriteString(".*")
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 || strings.Contains(glob[i+1:i+1+end], "/") { // Not a class of characters
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			i += end + 1
+			b.WriteByte('[')
+			if strings.HasPrefix(class, "!") {
+				b.WriteByte('^')
+				class = class[1:]
+			}
+			for j := 0; j < len(class); j++ {
+				if strings.IndexByte(`\[]^`, class[j]) >= 0 {
+					b.WriteByte('\\')
+				}
+				b.WriteByte(class[j])
+			}
+			b.WriteByte(']')
+		case '{':
+			end := matchingBrace(glob, i)
+			if end < 0 {
+				b.WriteString(`\{`)
+				continue
+			}
+			inner := glob[i+1 : end]
+			i = end
+			if n := numRange.FindStringSubmatch(inner); n != nil {
+				lo, _ := strconv.Atoi(n[1])
+				hi, _ := strconv.Atoi(n[2])
+				m.ranges = append(m.ranges, [2]int{min(lo, hi), max(lo, hi)})
+				b.WriteString(`([+-]?\d+)`)
+			} else if alts := splitAlternatives(inner); len(alts) > 1 {
+				for j, alt := range alts {
+					alts[j] = m.translate(alt)
+				}
+				b.WriteString("(?:" + strings.Join(alts, "|") + ")")
+			} else { // Braces around a single word are taken literally
+				b.WriteString(`\{` + m.translate(inner) + `\}`)
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return b.String()
+}
+
+// matchingBrace returns the index of the brace closing the one at `open` in
+// `glob`, or -1 if it isn't closed.
+func matchingBrace(glob string, open int) int {
+	depth := 0
+	for i := open; i < len(glob); i++ {
+		switch glob[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitAlternatives splits the contents of braces at the commas outside of any
+// nested braces.
+func splitAlternatives(inner string) []string {
+	var alts []string
+	depth, start := 0, 0
+	for i := 0; i < len(inner); i++ {
+		switch inner[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				alts = append(alts, inner[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(alts, inner[start:])
+}
+
+// match returns whether `path`, an absolute path with forward slashes, matches
+// the glob.
+func (m *matcher) match(path string) bool {
+	groups := m.re.FindStringSubmatch(path)
+	if groups == nil {
+		return false
+	}
+	for i, r := range m.ranges {
+		if groups[i+1] == "" { // In an alternative that wasn't matched
+			continue
+		}
+		if n, err := strconv.Atoi(groups[i+1]); err != nil || n < r[0] || n > r[1] {
+			return false
+		}
+	}
+	return true
+}
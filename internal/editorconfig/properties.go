@@ -0,0 +1,92 @@
+package editorconfig
+
+import (
+	"bytes"
+	"strconv"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// utf8BOM is the byte order mark of UTF-8 text.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// getInt returns the value of the property `name` as a positive number.
+func (p Properties) getInt(name string) (int, bool) {
+	n, err := strconv.Atoi(p[name])
+	return n, err == nil && n > 0
+}
+
+// TabWidth returns the width of a tab: the tab_width, or else the indent_size.
+func (p Properties) TabWidth() (int, bool) {
+	if n, ok := p.getInt("tab_width"); ok {
+		return n, true
+	}
+	return p.getInt("indent_size")
+}
+
+// IndentSize returns the width of one level of indentation: the indent_size, or
+// the TabWidth if it is "tab" or unset in a file indented with tabs.
+func (p Properties) IndentSize() (int, bool) {
+	if size, ok := p["indent_size"]; size == "tab" || !ok && p["indent_style"] == "tab" {
+		if n, ok := p.getInt("tab_width"); ok {
+			return n, true
+		}
+	}
+	return p.getInt("indent_size")
+}
+
+// MaxLineLength returns the display column lines should be kept within: the
+// max_line_length, unless it is "off".
+func (p Properties) MaxLineLength() (int, bool) {
+	return p.getInt("max_line_length")
+}
+
+// getEncoding returns the encoding of the charset, or nil if it is UTF-8 or not
+// known.
+func (p Properties) getEncoding() encoding.Encoding {
+	switch p["charset"] {
+	case "latin1":
+		return charmap.ISO8859_1
+	case "utf-16be":
+		return unicode.UTF16(unicode.BigEndian, unicode.UseBOM)
+	case "utf-16le":
+		return unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)
+	}
+	return nil
+}
+
+// Decode converts `contents`, read from a file, from the charset to UTF-8. The
+// byte order mark of a UTF-8 file is removed, and added back by Encode if the
+// charset is utf-8-bom.
+func (p Properties) Decode(contents []byte) ([]byte, error) {
+	if enc := p.getEncoding(); enc != nil {
+		return enc.NewDecoder().Bytes(contents)
+	}
+	if charset := p["charset"]; charset == "utf-8" || charset == "utf-8-bom" {
+		return bytes.TrimPrefix(contents, utf8BOM), nil
+	}
+	return contents, nil
+}
+
+// Encode converts UTF-8 `contents`, to be written to a file, to the charset. If
+// the end_of_line is lf or crlf, every line delimiter is changed to it. An error
+// is returned if the contents can't be represented in the charset.
+func (p Properties) Encode(contents []byte) ([]byte, error) {
+	switch p["end_of_line"] {
+	case "lf":
+		contents = bytes.ReplaceAll(contents, []byte("\r\n"), []byte("\n"))
+	case "crlf":
+		contents = bytes.ReplaceAll(contents, []byte("\r\n"), []byte("\n"))
+		contents = bytes.ReplaceAll(contents, []byte("\n"), []byte("\r\n"))
+	}
+
+	if enc := p.getEncoding(); enc != nil {
+		return enc.NewEncoder().Bytes(contents)
+	}
+	if p["charset"] == "utf-8-bom" && !bytes.HasPrefix(contents, utf8BOM) {
+		contents = append(append([]byte{}, utf8BOM...), contents...)
+	}
+	return contents, nil
+}
@@ -0,0 +1,72 @@
+package task
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultErrorFormats match the `file:line:col: message` locations printed by
+// Go, GCC, Clang, and most other compilers and linters. The column is optional.
+var DefaultErrorFormats = []string{
+	`^\s*(?P<file>[^:\s][^:]*):(?P<line>\d+):(?:(?P<col>\d+):)?\s*(?P<message>.*)$`,
+}
+
+// A Location is a position in a file that was printed in the output of a Task.
+// Line and Col start from one, as printed by compilers; Col is zero if unknown.
+type Location struct {
+	File    string
+	Line    int
+	Col     int
+	Message string
+
+	OutputLine int // Index of the line of output the Location was found on
+}
+
+// An ErrorFormat recognizes locations in lines of output with a regular expression.
+// The expression uses the named groups "file" and "line", and optionally "col"
+// and "message".
+type ErrorFormat struct {
+	re *regexp.Regexp
+}
+
+// NewErrorFormat compiles the regular expression of an ErrorFormat.
+func NewErrorFormat(expr string) (*ErrorFormat, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &ErrorFormat{re}, nil
+}
+
+// Parse returns the Location in the line of output if it matches the ErrorFormat.
+// A relative file path is made relative to `dir`.
+func (f *ErrorFormat) Parse(line, dir string) (Location, bool) {
+	match := f.re.FindStringSubmatch(line)
+	if match == nil {
+		return Location{}, false
+	}
+
+	var loc Location
+	for i, name := range f.re.SubexpNames() {
+		switch name {
+		case "file":
+			loc.File = strings.TrimSpace(match[i])
+		case "line":
+			loc.Line, _ = strconv.Atoi(match[i])
+		case "col":
+			loc.Col, _ = strconv.Atoi(match[i])
+		case "message":
+			loc.Message = match[i]
+		}
+	}
+
+	if loc.File == "" || loc.Line <= 0 {
+		return Location{}, false
+	}
+	if !filepath.IsAbs(loc.File) && dir != "" {
+		loc.File = filepath.Join(dir, loc.File)
+	}
+	return loc, true
+}
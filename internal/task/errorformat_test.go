@@ -0,0 +1,33 @@
+package task
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDefaultErrorFormat(t *testing.T) {
+	f, err := NewErrorFormat(DefaultErrorFormats[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		line string
+		loc  Location
+		ok   bool
+	}{
+		{"pkg/ui/textedit.go:12:5: undefined: foo", Location{File: filepath.Join("dir", "pkg/ui/textedit.go"), Line: 12, Col: 5, Message: "undefined: foo"}, true},
+		{"main.c:3: error: expected ';'", Location{File: filepath.Join("dir", "main.c"), Line: 3, Message: "error: expected ';'"}, true},
+		{"    rope_test.go:40: Expected 3", Location{File: filepath.Join("dir", "rope_test.go"), Line: 40, Message: "Expected 3"}, true},
+		{"/abs/file.go:7:1: oops", Location{File: "/abs/file.go", Line: 7, Col: 1, Message: "oops"}, true},
+		{"ok  	github.com/fivemoreminix/qedit/pkg/buffer	0.046s", Location{}, false},
+		{"# github.com/fivemoreminix/qedit/pkg/ui", Location{}, false},
+	}
+
+	for _, c := range cases {
+		loc, ok := f.Parse(c.line, "dir")
+		if ok != c.ok || loc != c.loc {
+			t.Errorf("Parse(%q) = %+v, %v ; expected %+v, %v", c.line, loc, ok, c.loc, c.ok)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Package task runs external commands, like builds and tests, and finds the
+// locations of errors in their output.
+package task
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// ConfigFile is the name of the file, in the working directory, that tasks are
+// loaded from. It contains a JSON array of Tasks.
+const ConfigFile = ".qedit-tasks.json"
+
+// A Task is a configured command that can be run.
+type Task struct {
+	Name         string
+	Command      []string // Program and arguments
+	Dir          string   // Working directory; the current one if empty
+	ErrorFormats []string // Regular expressions; DefaultErrorFormats if empty
+}
+
+// DefaultTasks are used when there is no ConfigFile.
+var DefaultTasks = []Task{
+	{Name: "Go Build", Command: []string{"go", "build", "./..."}},
+	{Name: "Go Test", Command: []string{"go", "test", "./..."}},
+	{Name: "Go Vet", Command: []string{"go", "vet", "./..."}},
+	{Name: "Make", Command: []string{"make"}},
+}
+
+// LoadTasks reads the Tasks from the JSON file at `path`.
+func LoadTasks(path string) ([]Task, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []Task
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// EventOutput is posted to the screen for each line a Run outputs.
+type EventOutput struct {
+	tcell.EventTime
+	Run  *Run
+	Line string
+}
+
+// EventDone is posted to the screen after a Run exits. Err is the error returned
+// by the command, such as an *exec.ExitError for a non-zero exit status.
+type EventDone struct {
+	tcell.EventTime
+	Run *Run
+	Err error
+}
+
+// A Run is a single execution of a Task. Its output is read on another goroutine,
+// and delivered to the main goroutine as events posted to the screen.
+type Run struct {
+	Task      Task
+	Lines     []string   // Output received so far, from AddLine
+	Locations []Location // Locations found in Lines, in order
+
+	formats []*ErrorFormat
+	cmd     *exec.Cmd
+	mutex   sync.Mutex // Protects killed
+	killed  bool
+}
+
+// Start runs the Task asynchronously, posting an EventOutput for every line of
+// standard output or error, and an EventDone when the command exits.
+func Start(task Task, screen tcell.Screen) (*Run, error) {
+	exprs := task.ErrorFormats
+	if len(exprs) == 0 {
+		exprs = DefaultErrorFormats
+	}
+	formats := make([]*ErrorFormat, len(exprs))
+	for i, expr := range exprs {
+		var err error
+		if formats[i], err = NewErrorFormat(expr); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(task.Command) == 0 {
+		return nil, exec.ErrNotFound
+	}
+	cmd := exec.Command(task.Command[0], task.Command[1:]...)
+	cmd.Dir = task.Dir
+	startProcessGroup(cmd) // Kill stops any commands it runs, too
+
+	reader, writer := io.Pipe()
+	cmd.Stdout = writer
+	cmd.Stderr = writer // Interleave both, as they would be in a terminal
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	r := &Run{Task: task, formats: formats, cmd: cmd}
+
+	waitErr := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		writer.Close() // Lets the scanner finish
+		waitErr <- err
+	}()
+
+	go func() {
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(nil, 1024*1024)
+		for scanner.Scan() {
+			ev := &EventOutput{Run: r, Line: scanner.Text()}
+			ev.SetEventNow()
+			screen.PostEventWait(ev) // Wait, so no output is dropped
+		}
+		io.Copy(io.Discard, reader) // In case of a line too long: never block the command
+
+		// Posted after all output, so EventDone is always the last event of a Run
+		ev := &EventDone{Run: r, Err: <-waitErr}
+		ev.SetEventNow()
+		screen.PostEventWait(ev)
+	}()
+
+	return r, nil
+}
+
+// AddLine records a line of output, which should be called in the main goroutine
+// upon receiving an EventOutput. If the line has a Location, it is returned.
+func (r *Run) AddLine(line string) (Location, bool) {
+	r.Lines = append(r.Lines, line)
+	for _, f := range r.formats {
+		if loc, ok := f.Parse(line, r.Task.Dir); ok {
+			loc.OutputLine = len(r.Lines) - 1
+			r.Locations = append(r.Locations, loc)
+			return loc, true
+		}
+	}
+	return Location{}, false
+}
+
+// Kill stops the command and any commands it runs, if it is still running.
+func (r *Run) Kill() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.killed && r.cmd.Process != nil {
+		r.killed = true
+		killProcessGroup(r.cmd)
+	}
+}
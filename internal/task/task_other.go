@@ -0,0 +1,19 @@
+//go:build !unix
+
+package task
+
+import "os/exec"
+
+// ShellCommand returns the Command of a Task which runs `command` with the shell,
+// so it can quote arguments and use pipes, like in a terminal.
+func ShellCommand(command string) []string {
+	return []string{"cmd", "/C", command}
+}
+
+// startProcessGroup does nothing where process groups are not supported.
+func startProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills only the command where process groups are not supported.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,25 @@
+//go:build unix
+
+package task
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// ShellCommand returns the Command of a Task which runs `command` with the shell,
+// so it can quote arguments and use pipes, like in a terminal.
+func ShellCommand(command string) []string {
+	return []string{"sh", "-c", command}
+}
+
+// startProcessGroup makes the command begin a process group, so it can be killed
+// along with any commands it runs, like those of a shell.
+func startProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup kills the process group begun by the command.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
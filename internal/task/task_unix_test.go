@@ -0,0 +1,56 @@
+//go:build unix
+
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// waitDone returns the output of the Run once it is done, or fails after a timeout.
+func waitDone(t *testing.T, screen tcell.Screen) []string {
+	t.Helper()
+	var lines []string
+	timeout := time.AfterFunc(5*time.Second, func() { screen.PostEvent(tcell.NewEventInterrupt(nil)) })
+	defer timeout.Stop()
+	for {
+		switch ev := screen.PollEvent().(type) {
+		case *EventOutput:
+			lines = append(lines, ev.Line)
+		case *EventDone:
+			return lines
+		case *tcell.EventInterrupt:
+			t.Fatalf("Expected the command to be done, got output %q", lines)
+		}
+	}
+}
+
+func TestShellCommand(t *testing.T) {
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+
+	if _, err := Start(Task{Command: ShellCommand(`printf '%s\n' "A|B" 'c d'`)}, screen); err != nil {
+		t.Fatal(err)
+	}
+	if lines := waitDone(t, screen); len(lines) != 2 || lines[0] != "A|B" || lines[1] != "c d" {
+		t.Errorf("Expected quoted arguments to be kept whole, got %q", lines)
+	}
+
+	// The shell runs sleep as a child, which holds the output open
+	r, err := Start(Task{Command: ShellCommand("echo started; sleep 30; echo done")}, screen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := screen.PollEvent().(*EventOutput); !ok { // The shell is running sleep
+		t.Fatalf("Expected output")
+	}
+	r.Kill()
+	if lines := waitDone(t, screen); len(lines) != 0 {
+		t.Errorf("Expected no more output, got %q", lines)
+	}
+}
@@ -0,0 +1,129 @@
+package ui
+
+import (
+	"github.com/fivemoreminix/qedit/pkg/ui"
+	"github.com/gdamore/tcell/v2"
+)
+
+// An InputDialog asks the user for a single line of text.
+type InputDialog struct {
+	Title               string
+	InputChosenCallback func(string)
+
+	x, y          int
+	width, height int
+	focused       bool
+	screen        *tcell.Screen
+	theme         *ui.Theme
+
+	tabOrder    []ui.Component
+	tabOrderIdx int
+
+	inputField   *ui.InputField
+	acceptButton *ui.Button
+	cancelButton *ui.Button
+}
+
+func NewInputDialog(s *tcell.Screen, title string, theme *ui.Theme, inputChosenCallback func(string), cancelCallback func()) *InputDialog {
+	dialog := &InputDialog{
+		Title:               title,
+		InputChosenCallback: inputChosenCallback,
+		screen:              s,
+		theme:               theme,
+	}
+
+	dialog.inputField = ui.NewInputField(s, nil, theme.GetOrDefault("Window"))
+	dialog.acceptButton = ui.NewButton("OK", theme, dialog.onConfirm)
+	dialog.cancelButton = ui.NewButton("Cancel", theme, cancelCallback)
+	dialog.tabOrder = []ui.Component{dialog.inputField, dialog.cancelButton, dialog.acceptButton}
+
+	return dialog
+}
+
+func (d *InputDialog) onConfirm() {
+	if d.InputChosenCallback != nil {
+		if len(d.inputField.Buffer) > 0 { // Passed as it is, so spaces can be searched for
+			d.InputChosenCallback(string(d.inputField.Buffer))
+		}
+	}
+}
+
+func (d *InputDialog) Draw(s tcell.Screen) {
+	ui.DrawWindow(s, d.x, d.y, d.width, d.height, d.Title, d.theme)
+
+	btnWidth, _ := d.acceptButton.GetSize()
+	d.acceptButton.SetPos(d.x+d.width-btnWidth-1, d.y+4) // Place "OK" button on right, bottom
+
+	d.inputField.Draw(s)
+	d.acceptButton.Draw(s)
+	d.cancelButton.Draw(s)
+}
+
+func (d *InputDialog) SetFocused(v bool) {
+	d.focused = v
+	d.tabOrder[d.tabOrderIdx].SetFocused(v)
+}
+
+func (d *InputDialog) SetTheme(theme *ui.Theme) {
+	d.theme = theme
+	d.inputField.SetStyle(theme.GetOrDefault("Window"))
+	d.acceptButton.SetTheme(theme)
+	d.cancelButton.SetTheme(theme)
+}
+
+func (d *InputDialog) GetPos() (int, int) {
+	return d.x, d.y
+}
+
+func (d *InputDialog) SetPos(x, y int) {
+	d.x, d.y = x, y
+	d.inputField.SetPos(d.x+1, d.y+2)   // Center input field
+	d.cancelButton.SetPos(d.x+1, d.y+4) // Place "Cancel" button on left, bottom
+}
+
+func (d *InputDialog) GetMinSize() (int, int) {
+	return ui.Max(len(d.Title)+2, 40), 6
+}
+
+func (d *InputDialog) GetSize() (int, int) {
+	return d.width, d.height
+}
+
+func (d *InputDialog) SetSize(width, height int) {
+	minX, minY := d.GetMinSize()
+	d.width, d.height = ui.Max(width, minX), ui.Max(height, minY)
+
+	d.inputField.SetSize(d.width-2, 1)
+	d.cancelButton.SetSize(d.cancelButton.GetMinSize())
+	d.acceptButton.SetSize(d.acceptButton.GetMinSize())
+}
+
+func (d *InputDialog) HandleEvent(event tcell.Event) bool {
+	switch ev := event.(type) {
+	case *tcell.EventKey:
+		switch ev.Key() {
+		case tcell.KeyTab:
+			d.tabOrder[d.tabOrderIdx].SetFocused(false)
+
+			d.tabOrderIdx++
+			if d.tabOrderIdx >= len(d.tabOrder) {
+				d.tabOrderIdx = 0
+			}
+
+			d.tabOrder[d.tabOrderIdx].SetFocused(true)
+
+			return true
+		case tcell.KeyEsc:
+			if d.cancelButton.Callback != nil {
+				d.cancelButton.Callback()
+			}
+			return true
+		case tcell.KeyEnter:
+			if d.tabOrder[d.tabOrderIdx] == d.inputField {
+				d.onConfirm()
+				return true
+			}
+		}
+	}
+	return d.tabOrder[d.tabOrderIdx].HandleEvent(event)
+}
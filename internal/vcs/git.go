@@ -0,0 +1,35 @@
+// Package vcs compares files being edited with their versions in version control.
+package vcs
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// GitHeadContents returns the contents of the file at `path` as it was committed
+// at HEAD, by asking the local `git` binary. An error is returned if git is not
+// installed, the file is not in a repository, or the file is not committed.
+func GitHeadContents(path string) ([]byte, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	dir, name := filepath.Split(abs)
+
+	// "./" makes the object path relative to the working directory, not the repo root
+	cmd := exec.Command("git", "-C", dir, "show", "HEAD:./"+name)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	contents, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git show: %s", msg)
+		}
+		return nil, err
+	}
+	return contents, nil
+}
@@ -0,0 +1,380 @@
+package vcs
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/fivemoreminix/qedit/pkg/buffer"
+	"github.com/fivemoreminix/qedit/pkg/diff"
+	"github.com/fivemoreminix/qedit/pkg/ui"
+	"github.com/gdamore/tcell/v2"
+)
+
+// ChangeSigns is the group of the SignProvider a Tracker sets in a TextEdit.
+const ChangeSigns = "vcs"
+
+// debounce is how long a Tracker waits after an edit before computing the
+// differences, so they are not computed for every key typed.
+const debounce = 100 * time.Millisecond
+
+// Styles of the Signs beside lines that were added, modified, or had lines
+// deleted after them.
+var (
+	AddedStyle    = tcell.Style{}.Foreground(tcell.ColorGreen).Background(tcell.ColorBlack)
+	ModifiedStyle = tcell.Style{}.Foreground(tcell.ColorOlive).Background(tcell.ColorBlack)
+	DeletedStyle  = tcell.Style{}.Foreground(tcell.ColorMaroon).Background(tcell.ColorBlack)
+)
+
+var (
+	addedSign    = &ui.Sign{Glyph: '┃', Style: AddedStyle, Tooltip: "Added line"}
+	modifiedSign = &ui.Sign{Glyph: '┃', Style: ModifiedStyle, Tooltip: "Modified line"}
+)
+
+// A Tracker keeps the differences between the lines of a TextEdit and a base
+// version of its file, like the version committed at HEAD, and shows them as
+// Signs beside the line numbers. The Tracker observes the Buffer of the TextEdit,
+// and only compares the lines around the edits made since the last comparison.
+// With a screen, lines are compared on a worker goroutine, and the differences
+// are posted as an EventDiff, to be applied on the main goroutine.
+type Tracker struct {
+	base   []string    // Lines of the base version, with delimiters
+	hunks  []diff.Hunk // Old is the base, New is the Buffer
+	screen tcell.Screen
+	buffer buffer.Buffer // Buffer being observed, or nil before the first Update
+
+	dirty               bool // Whether lines from dirtyStart to dirtyEnd must be compared
+	dirtyStart          int
+	dirtyEnd            int // Exclusive
+	generation          int // Incremented on every Change to the Buffer
+	requestedGeneration int // Generation of the latest job
+	latestJob           atomic.Int64
+}
+
+// A diffJob is the work given to a worker goroutine of a Tracker: comparing the
+// lines of the window with the lines of the base version they replaced.
+type diffJob struct {
+	id               int64
+	generation       int
+	snapshot         buffer.Buffer
+	newStart, newEnd int // Window of lines in the snapshot, exclusive end
+	oldStart, oldEnd int // Window of lines in the base version, exclusive end
+	hunks            []diff.Hunk
+}
+
+// EventDiff is posted to the screen when a Tracker has compared lines in the
+// background. Call Apply on the main goroutine to use them.
+type EventDiff struct {
+	tcell.EventTime
+	tracker *Tracker
+	job     *diffJob
+}
+
+// Apply replaces the differences of the Tracker with the ones in the event,
+// unless the Buffer has changed since they were computed.
+func (ev *EventDiff) Apply() {
+	ev.tracker.apply(ev.job)
+}
+
+// NewTracker creates a Tracker comparing against `base`. Without a screen,
+// Update compares lines synchronously.
+func NewTracker(base []byte, screen tcell.Screen) *Tracker {
+	return &Tracker{base: diff.SplitLines(base), screen: screen, requestedGeneration: -1}
+}
+
+// NewGitTracker creates a Tracker comparing the file at `path` with its version
+// committed at HEAD. See GitHeadContents for the errors returned.
+func NewGitTracker(path string, screen tcell.Screen) (*Tracker, error) {
+	base, err := GitHeadContents(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewTracker(base, screen), nil
+}
+
+// Close stops observing the Buffer, and abandons any work in the background.
+func (t *Tracker) Close() {
+	if t.buffer != nil {
+		t.buffer.UnregisterObserver(t)
+		t.buffer = nil
+	}
+	t.latestJob.Add(1)
+}
+
+// OnChange moves the hunks after the Change, and marks the lines it touched to
+// be compared again.
+func (t *Tracker) OnChange(change buffer.Change) {
+	moved := change.EndLine - change.StartLine
+	shift := func(b int) int { // Moves the boundary before line b
+		switch {
+		case b <= change.StartLine:
+			return b
+		case !change.Removed:
+			return b + moved
+		case b <= change.EndLine: // Within the removed lines, now joined to StartLine
+			return change.StartLine
+		default:
+			return b - moved
+		}
+	}
+	for i := range t.hunks {
+		t.hunks[i].NewStart = shift(t.hunks[i].NewStart)
+		t.hunks[i].NewEnd = shift(t.hunks[i].NewEnd)
+	}
+
+	start, end := change.StartLine, change.StartLine+1
+	if !change.Removed {
+		end = change.EndLine + 1
+	}
+	if t.dirty {
+		start, end = min(start, shift(t.dirtyStart)), max(end, shift(t.dirtyEnd))
+	}
+	t.dirty, t.dirtyStart, t.dirtyEnd = true, start, end
+	t.generation++
+}
+
+// observe starts observing the Buffer of the TextEdit, if it is not already, and
+// marks every line to be compared.
+func (t *Tracker) observe(te *ui.TextEdit) {
+	if t.buffer == te.Buffer {
+		return
+	}
+	if t.buffer != nil {
+		t.buffer.UnregisterObserver(t)
+	}
+	t.buffer = te.Buffer
+	t.buffer.RegisterObserver(t)
+	t.hunks = nil
+	t.dirty, t.dirtyStart, t.dirtyEnd = true, 0, t.buffer.Lines()
+	t.generation++
+}
+
+// Update starts comparing the lines that were edited since the last comparison,
+// in the background if the Tracker has a screen. The comparison waits until there
+// have been no edits for a moment.
+func (t *Tracker) Update(te *ui.TextEdit) {
+	t.observe(te)
+	if !t.dirty || t.requestedGeneration == t.generation {
+		return
+	}
+	t.requestedGeneration = t.generation
+	job := t.newJob(t.buffer.Snapshot())
+	if t.screen == nil {
+		t.run(job)
+		t.apply(job)
+		return
+	}
+	go func() {
+		time.Sleep(debounce)
+		if t.latestJob.Load() != job.id {
+			return // Abandoned for a newer edit
+		}
+		t.run(job)
+		ev := &EventDiff{tracker: t, job: job}
+		ev.SetEventNow()
+		t.screen.PostEvent(ev)
+	}()
+}
+
+// UpdateNow compares the lines that were edited since the last comparison, and
+// waits for the result. Work in the background is abandoned.
+func (t *Tracker) UpdateNow(te *ui.TextEdit) {
+	t.observe(te)
+	if !t.dirty {
+		return
+	}
+	t.requestedGeneration = t.generation
+	job := t.newJob(t.buffer)
+	t.run(job)
+	t.apply(job)
+}
+
+// newJob returns a job to compare the dirty lines, widened to include the hunks
+// they touch, in `snapshot` of the Buffer.
+func (t *Tracker) newJob(snapshot buffer.Buffer) *diffJob {
+	lines := t.buffer.Lines()
+	if t.buffer.RunesInLineWithDelim(lines-1) == 0 {
+		lines-- // SplitLines has no empty line after the last delimiter
+	}
+
+	start, end := t.dirtyStart, t.dirtyEnd
+	for changed := true; changed; {
+		changed = false
+		for _, h := range t.hunks {
+			if h.NewStart <= end && h.NewEnd >= start && (h.NewStart < start || h.NewEnd > end) {
+				start, end = min(start, h.NewStart), max(end, h.NewEnd)
+				changed = true
+			}
+		}
+	}
+	end = min(end, lines)
+	start = min(start, end)
+
+	// Lines outside of the hunks are the same in both versions, so the window
+	// is offset in the base version like the nearest hunks outside of it are.
+	oldStart, oldEnd := start, end+len(t.base)-lines
+	for _, h := range t.hunks {
+		if h.NewEnd < start {
+			oldStart = start + h.OldEnd - h.NewEnd
+		} else if h.NewStart > end {
+			oldEnd = end + h.OldStart - h.NewStart
+			break
+		}
+	}
+	oldStart = max(0, min(oldStart, len(t.base)))
+	oldEnd = max(oldStart, min(oldEnd, len(t.base)))
+
+	return &diffJob{
+		id:         t.latestJob.Add(1),
+		generation: t.generation,
+		snapshot:   snapshot,
+		newStart:   start,
+		newEnd:     end,
+		oldStart:   oldStart,
+		oldEnd:     oldEnd,
+	}
+}
+
+// run compares the lines of the job. It must not access any state of the Tracker
+// that is modified on the main goroutine.
+func (t *Tracker) run(job *diffJob) {
+	lines := make([]string, job.newEnd-job.newStart)
+	for i := range lines {
+		lines[i] = string(job.snapshot.Line(job.newStart + i))
+	}
+	job.hunks = diff.Diff(t.base[job.oldStart:job.oldEnd], lines)
+	for i := range job.hunks {
+		job.hunks[i].OldStart += job.oldStart
+		job.hunks[i].OldEnd += job.oldStart
+		job.hunks[i].NewStart += job.newStart
+		job.hunks[i].NewEnd += job.newStart
+	}
+}
+
+// apply replaces the hunks in the window of the job with its hunks, unless the
+// Buffer has changed since the job was created.
+func (t *Tracker) apply(job *diffJob) {
+	if job.id != t.latestJob.Load() || job.generation != t.generation {
+		return
+	}
+	hunks := make([]diff.Hunk, 0, len(t.hunks)+len(job.hunks))
+	for _, h := range t.hunks {
+		if h.NewEnd < job.newStart {
+			hunks = append(hunks, h)
+		}
+	}
+	hunks = append(hunks, job.hunks...)
+	for _, h := range t.hunks {
+		if h.NewStart > job.newEnd {
+			hunks = append(hunks, h)
+		}
+	}
+	t.hunks = hunks
+	t.dirty = false
+}
+
+func deletedTooltip(h diff.Hunk) string {
+	if h.OldEnd-h.OldStart == 1 {
+		return "Deleted 1 line"
+	}
+	return fmt.Sprintf("Deleted %d lines", h.OldEnd-h.OldStart)
+}
+
+// GetSigns returns the Signs of the hunks on the lines from startLine to endLine,
+// inclusively. A deletion is marked on the line above the deleted lines.
+func (t *Tracker) GetSigns(startLine, endLine int) map[int]*ui.Sign {
+	signs := make(map[int]*ui.Sign)
+	for _, h := range t.hunks {
+		if h.NewEnd < startLine || h.NewStart > endLine+1 {
+			continue
+		}
+		switch {
+		case h.IsDeletion():
+			sign := &ui.Sign{Glyph: '▁', Style: DeletedStyle, Tooltip: deletedTooltip(h)}
+			line := h.NewStart - 1
+			if h.NewStart == 0 {
+				sign.Glyph, line = '▔', 0
+			}
+			if line >= startLine && line <= endLine {
+				signs[line] = sign
+			}
+		default:
+			sign := modifiedSign
+			if h.IsInsertion() {
+				sign = addedSign
+			}
+			for line := max(h.NewStart, startLine); line < h.NewEnd && line <= endLine; line++ {
+				signs[line] = sign
+			}
+		}
+	}
+	return signs
+}
+
+// GetHunks returns the differences from the last comparison. Old lines are in the
+// base version, and new lines are in the TextEdit. Do not modify the slice.
+func (t *Tracker) GetHunks() []diff.Hunk {
+	return t.hunks
+}
+
+// hunkAtLine returns the index of the Hunk that includes `line` of the TextEdit,
+// or -1. A deletion includes the line its Sign is placed on.
+func (t *Tracker) hunkAtLine(line int) int {
+	for i, h := range t.hunks {
+		if line >= h.NewStart && line < h.NewEnd {
+			return i
+		}
+		if h.IsDeletion() && (line == h.NewStart-1 || (h.NewStart == 0 && line == 0)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// NextHunk returns the first line of the nearest Hunk starting after `line`, or
+// before it if `forwards` is false. The search wraps around. False is returned
+// if there are no Hunks.
+func (t *Tracker) NextHunk(line int, forwards bool) (int, bool) {
+	if len(t.hunks) == 0 {
+		return 0, false
+	}
+
+	// A deleted hunk is located at the line its Sign is on
+	startOf := func(h diff.Hunk) int {
+		if h.IsDeletion() && h.NewStart > 0 {
+			return h.NewStart - 1
+		}
+		return h.NewStart
+	}
+
+	if forwards {
+		for _, h := range t.hunks {
+			if startOf(h) > line {
+				return startOf(h), true
+			}
+		}
+		return startOf(t.hunks[0]), true
+	}
+	for i := len(t.hunks) - 1; i >= 0; i-- {
+		if startOf(t.hunks[i]) < line {
+			return startOf(t.hunks[i]), true
+		}
+	}
+	return startOf(t.hunks[len(t.hunks)-1]), true
+}
+
+// RevertHunk replaces the lines of the Hunk at `line` with the lines from the
+// base version. Returns false if there is no Hunk at `line`.
+func (t *Tracker) RevertHunk(te *ui.TextEdit, line int) bool {
+	t.UpdateNow(te)
+	i := t.hunkAtLine(line)
+	if i < 0 {
+		return false
+	}
+
+	h := t.hunks[i]
+	te.ReplaceLines(h.NewStart, h.NewEnd, []byte(strings.Join(t.base[h.OldStart:h.OldEnd], "")))
+	t.UpdateNow(te)
+	return true
+}
@@ -0,0 +1,163 @@
+package vcs
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/fivemoreminix/qedit/pkg/diff"
+	"github.com/fivemoreminix/qedit/pkg/ui"
+)
+
+const trackerBase = "a\nb\nc\nd\ne\nf\n"
+
+// newTrackedEdit returns a TextEdit with `contents`, and a Tracker comparing it
+// with trackerBase.
+func newTrackedEdit(contents string) (*ui.TextEdit, *Tracker) {
+	te := ui.NewTextEdit(nil, "", []byte(contents), &ui.DefaultTheme)
+	tracker := NewTracker([]byte(trackerBase), nil)
+	tracker.Update(te)
+	return te, tracker
+}
+
+// applyHunks reconstructs the lines of the TextEdit from the base lines and the
+// hunks of the Tracker.
+func applyHunks(base, lines []string, hunks []diff.Hunk) []string {
+	var result []string
+	i := 0
+	for _, h := range hunks {
+		result = append(result, base[i:h.OldStart]...)
+		result = append(result, lines[h.NewStart:h.NewEnd]...)
+		i = h.OldEnd
+	}
+	return append(result, base[i:]...)
+}
+
+func TestTrackerHunks(t *testing.T) {
+	_, tracker := newTrackedEdit("a\nx\nc\ne\nf\ng\n")
+	expected := []diff.Hunk{
+		{OldStart: 1, OldEnd: 2, NewStart: 1, NewEnd: 2},
+		{OldStart: 3, OldEnd: 4, NewStart: 3, NewEnd: 3},
+		{OldStart: 6, OldEnd: 6, NewStart: 5, NewEnd: 6},
+	}
+	if hunks := tracker.GetHunks(); !reflect.DeepEqual(hunks, expected) {
+		t.Errorf("Expected hunks %v, got %v", expected, hunks)
+	}
+}
+
+func TestTrackerIncremental(t *testing.T) {
+	te, tracker := newTrackedEdit(trackerBase)
+	base := diff.SplitLines([]byte(trackerBase))
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		line := rng.Intn(te.Buffer.Lines())
+		if rng.Intn(2) == 0 || te.Buffer.Len() == 0 {
+			text := []string{"x", "\n", "y\nz", "a\n"}[rng.Intn(4)]
+			te.Buffer.Insert(line, rng.Intn(te.Buffer.RunesInLine(line)+1), []byte(text))
+		} else if n := te.Buffer.RunesInLineWithDelim(line); n > 0 {
+			te.Buffer.Remove(line, 0, line, rng.Intn(n))
+		}
+		if rng.Intn(3) == 0 {
+			continue // Let several edits accumulate between updates
+		}
+
+		tracker.Update(te)
+		lines := diff.SplitLines(te.Buffer.Bytes())
+		hunks := tracker.GetHunks()
+		if result := applyHunks(base, lines, hunks); !reflect.DeepEqual(result, lines) {
+			t.Fatalf("After edit %d, hunks %v reconstruct %q, expected %q", i, hunks, result, lines)
+		}
+		for j := 1; j < len(hunks); j++ {
+			if hunks[j].OldStart < hunks[j-1].OldEnd || hunks[j].NewStart < hunks[j-1].NewEnd {
+				t.Fatalf("After edit %d, hunks %v overlap", i, hunks)
+			}
+		}
+	}
+}
+
+func TestHunkAtLine(t *testing.T) {
+	// Modified line 1, deleted "d" after line 2, added line 5
+	_, tracker := newTrackedEdit("a\nx\nc\ne\nf\ng\n")
+	tests := []struct{ line, hunk int }{{0, -1}, {1, 0}, {2, 1}, {3, -1}, {5, 2}}
+	for _, test := range tests {
+		if hunk := tracker.hunkAtLine(test.line); hunk != test.hunk {
+			t.Errorf("Line %d: expected hunk %d, got %d", test.line, test.hunk, hunk)
+		}
+	}
+
+	// Deleted "a" at the start of the file
+	_, tracker = newTrackedEdit("b\nc\nd\ne\nf\n")
+	if hunk := tracker.hunkAtLine(0); hunk != 0 {
+		t.Errorf("Expected the deletion at the start to be at line 0, got hunk %d", hunk)
+	}
+}
+
+func TestNextHunk(t *testing.T) {
+	_, tracker := newTrackedEdit("a\nx\nc\ne\nf\ng\n")
+	tests := []struct {
+		line     int
+		forwards bool
+		next     int
+	}{
+		{0, true, 1},
+		{1, true, 2}, // The deletion is at the line above it
+		{2, true, 5},
+		{5, true, 1}, // Wraps around
+		{5, false, 2},
+		{2, false, 1},
+		{1, false, 5}, // Wraps around
+	}
+	for _, test := range tests {
+		if next, ok := tracker.NextHunk(test.line, test.forwards); !ok || next != test.next {
+			t.Errorf("From line %d, forwards %v: expected line %d, got %d", test.line, test.forwards, test.next, next)
+		}
+	}
+
+	_, tracker = newTrackedEdit(trackerBase)
+	if _, ok := tracker.NextHunk(0, true); ok {
+		t.Errorf("Expected no hunks in an unchanged file")
+	}
+}
+
+func TestRevertHunk(t *testing.T) {
+	te, tracker := newTrackedEdit("a\nx\nc\ne\nf\ng\n")
+	tests := []struct {
+		line     int
+		expected string
+	}{
+		{5, "a\nx\nc\ne\nf\n"},
+		{1, "a\nb\nc\ne\nf\n"},
+		{2, trackerBase},
+	}
+	for _, test := range tests {
+		if !tracker.RevertHunk(te, test.line) {
+			t.Fatalf("No hunk to revert at line %d", test.line)
+		}
+		if contents := string(te.Buffer.Bytes()); contents != test.expected {
+			t.Errorf("Reverting line %d: expected %q, got %q", test.line, test.expected, contents)
+		}
+	}
+	if tracker.RevertHunk(te, 0) || len(tracker.GetHunks()) != 0 {
+		t.Errorf("Expected nothing to revert, got hunks %v", tracker.GetHunks())
+	}
+}
+
+func TestTrackerWindow(t *testing.T) {
+	te, tracker := newTrackedEdit("a\nx\nc\nd\ne\nf\n")
+	te.Buffer.Insert(4, 0, []byte("new\n"))
+
+	// Only the lines the insertion touched are compared, with the lines of the
+	// base version that are there, and the hunk of line 1 is kept.
+	job := tracker.newJob(te.Buffer)
+	if job.newStart != 4 || job.newEnd != 6 || job.oldStart != 4 || job.oldEnd != 5 {
+		t.Errorf("Expected window [4, 6) of [4, 5), got [%d, %d) of [%d, %d)", job.newStart, job.newEnd, job.oldStart, job.oldEnd)
+	}
+	tracker.UpdateNow(te)
+	expected := []diff.Hunk{
+		{OldStart: 1, OldEnd: 2, NewStart: 1, NewEnd: 2},
+		{OldStart: 4, OldEnd: 4, NewStart: 4, NewEnd: 5},
+	}
+	if hunks := tracker.GetHunks(); !reflect.DeepEqual(hunks, expected) {
+		t.Errorf("Expected hunks %v, got %v", expected, hunks)
+	}
+}
@@ -0,0 +1,217 @@
+package buffer
+
+import (
+	"sync/atomic"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// asyncChunkLines is how many lines an AsyncHighlighter highlights at once, after
+// the visible lines. Smaller chunks are shown sooner, but cost more to deliver.
+const asyncChunkLines = 1000
+
+// A HighlightResult holds the Matches of some lines, highlighted on the worker
+// goroutine of an AsyncHighlighter. It is applied with AsyncHighlighter.ApplyResult.
+type HighlightResult struct {
+	generation int       // Generation of the AsyncHighlighter the snapshot was taken at
+	startLine  int       // Line of matches[0]
+	matches    [][]Match // Matches of each line from startLine
+}
+
+// A highlightJob is the work given to a worker goroutine of an AsyncHighlighter.
+type highlightJob struct {
+	id                       int64
+	generation               int
+	snapshot                 Buffer
+	visibleStart, visibleEnd int // Highlighted first
+	startLine, endLine       int // Range of invalidated lines
+}
+
+// An AsyncHighlighter is a Highlighter that highlights on a worker goroutine, so
+// highlighting large buffers never blocks drawing. The worker uses the Highlighter
+// of the Language, on a snapshot of the buffer taken when lines are updated. The
+// visible lines are highlighted first, then the rest in chunks, each delivered with
+// the `post` function given to NewAsyncHighlighter. Results must then be applied
+// on the goroutine using the AsyncHighlighter, with ApplyResult. Until then, lines
+// keep the Matches they had before they were invalidated. Close stops the worker
+// once the AsyncHighlighter is no longer used.
+type AsyncHighlighter struct {
+	Buffer      Buffer
+	Language    *Language
+	Colorscheme *Colorscheme
+
+	post        func(*HighlightResult) bool // Called on the worker goroutine
+	lineMatches [][]Match
+	valid       []bool // Whether each line was highlighted since it was invalidated
+	generation  int    // Incremented whenever lines are invalidated
+	latestJob   atomic.Int64
+	dropped     atomic.Bool // Whether a result could not be posted, so the job stopped
+	closed      bool
+
+	requestedGeneration      int // Generation of the latest job
+	visibleStart, visibleEnd int // Visible lines of the latest job
+}
+
+// NewAsyncHighlighter returns an AsyncHighlighter that delivers HighlightResults
+// with `post`, which is called on the worker goroutine. Usually, `post` sends an
+// event to the main goroutine, which then calls ApplyResult. It must not block;
+// it returns false if the result could not be delivered, and the lines are then
+// highlighted again by the next call to UpdateInvalidatedLines.
+func NewAsyncHighlighter(buffer Buffer, lang *Language, colorscheme *Colorscheme, post func(*HighlightResult) bool) *AsyncHighlighter {
+	h := &AsyncHighlighter{
+		Buffer:              buffer,
+		Language:            lang,
+		Colorscheme:         colorscheme,
+		post:                post,
+		requestedGeneration: -1,
+	}
+	h.InvalidateLines(0, buffer.Lines()-1)
+	return h
+}
+
+// resizeToBufferLines keeps a slot for each line of the buffer. New lines are
+// invalid.
+func (h *AsyncHighlighter) resizeToBufferLines() {
+	lines := h.Buffer.Lines()
+	for len(h.lineMatches) < lines {
+		h.lineMatches = append(h.lineMatches, nil)
+		h.valid = append(h.valid, false)
+	}
+	h.lineMatches = h.lineMatches[:lines]
+	h.valid = h.valid[:lines]
+}
+
+func (h *AsyncHighlighter) InvalidateLines(startLine, endLine int) {
+	h.resizeToBufferLines()
+	for i := Max(0, startLine); i <= endLine && i < len(h.valid); i++ {
+		h.valid[i] = false
+	}
+	h.generation++ // Results from snapshots before this change are stale
+}
+
+// Close stops the worker goroutine, if it is highlighting, and highlights nothing
+// more. Results it already posted are not applied.
+func (h *AsyncHighlighter) Close() {
+	h.closed = true
+	h.latestJob.Add(1) // Abandons the running job
+}
+
+// UpdateInvalidatedLines starts highlighting on a worker goroutine, if any lines
+// are invalid, with the lines from startLine to endLine first. It returns without
+// waiting for the results. Work for a previous call is abandoned.
+func (h *AsyncHighlighter) UpdateInvalidatedLines(startLine, endLine int) {
+	if h.closed {
+		return
+	}
+	h.resizeToBufferLines()
+	first, last := -1, -1
+	for i := range h.valid {
+		if !h.valid[i] {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		return // Everything is highlighted
+	}
+
+	startLine, endLine = Max(startLine, first), Min(endLine, last)
+	if h.requestedGeneration == h.generation && !h.dropped.Swap(false) {
+		// The current job is still valid; only restart it to prioritize newly
+		// visible lines that are not highlighted yet.
+		if startLine == h.visibleStart && endLine == h.visibleEnd || !h.hasInvalid(startLine, endLine) {
+			return
+		}
+	}
+	h.requestedGeneration = h.generation
+	h.visibleStart, h.visibleEnd = startLine, endLine
+
+	job := &highlightJob{
+		id:           h.latestJob.Add(1),
+		generation:   h.generation,
+		snapshot:     h.Buffer.Snapshot(),
+		visibleStart: startLine,
+		visibleEnd:   endLine,
+		startLine:    first,
+		endLine:      last,
+	}
+	go h.run(job)
+}
+
+// hasInvalid returns true if any line from startLine to endLine is invalid.
+func (h *AsyncHighlighter) hasInvalid(startLine, endLine int) bool {
+	for i := Max(0, startLine); i <= endLine && i < len(h.valid); i++ {
+		if !h.valid[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// run highlights the job on the worker goroutine. It must not access any state
+// of the AsyncHighlighter that is modified on the main goroutine. It stops when a
+// newer job is started, or the AsyncHighlighter is closed.
+func (h *AsyncHighlighter) run(job *highlightJob) {
+	highlighter := NewHighlighter(job.snapshot, h.Language, h.Colorscheme)
+	highlight := func(startLine, endLine int) bool {
+		if h.latestJob.Load() != job.id {
+			return false // Abandoned for a newer job
+		}
+		highlighter.UpdateInvalidatedLines(startLine, endLine)
+		matches := make([][]Match, endLine-startLine+1)
+		for i := range matches {
+			matches[i] = append([]Match(nil), highlighter.GetLineMatches(startLine+i)...)
+		}
+		if h.latestJob.Load() != job.id {
+			return false // Don't deliver results no longer wanted
+		}
+		if !h.post(&HighlightResult{job.generation, startLine, matches}) {
+			h.dropped.Store(true)
+			return false
+		}
+		return true
+	}
+
+	if job.visibleStart <= job.visibleEnd && !highlight(job.visibleStart, job.visibleEnd) {
+		return
+	}
+	for start := job.startLine; start <= job.endLine; start += asyncChunkLines {
+		if !highlight(start, Min(start+asyncChunkLines-1, job.endLine)) {
+			return
+		}
+	}
+}
+
+// ApplyResult uses the Matches of a HighlightResult, unless the buffer was changed
+// since its snapshot was taken. Returns true if the result was applied.
+func (h *AsyncHighlighter) ApplyResult(result *HighlightResult) bool {
+	if h.closed || result.generation != h.generation {
+		return false // Stale; a newer job will replace it
+	}
+	h.resizeToBufferLines()
+	for i, matches := range result.matches {
+		line := result.startLine + i
+		if line < len(h.lineMatches) {
+			h.lineMatches[line] = matches
+			h.valid[line] = true
+		}
+	}
+	return true
+}
+
+func (h *AsyncHighlighter) OnChange(change Change) {
+	invalidateChange(h, change)
+}
+
+func (h *AsyncHighlighter) GetLineMatches(line int) []Match {
+	if line < 0 || line >= len(h.lineMatches) {
+		return nil
+	}
+	return h.lineMatches[line]
+}
+
+func (h *AsyncHighlighter) GetStyle(match Match) tcell.Style {
+	return h.Colorscheme.GetStyle(match.Syntax)
+}
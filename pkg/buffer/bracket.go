@@ -0,0 +1,120 @@
+package buffer
+
+import "math"
+
+// BracketPairs maps each opening bracket to its closing bracket.
+var BracketPairs = map[rune]rune{'(': ')', '[': ']', '{': '}'}
+
+// closingBrackets maps each closing bracket to its opening bracket.
+var closingBrackets = map[rune]rune{')': '(', ']': '[', '}': '{'}
+
+const (
+	// maxBracketSearchLines is how many lines from a bracket are searched for its
+	// match, so a missing match doesn't read the whole buffer.
+	maxBracketSearchLines = 1000
+
+	// maxBracketLookBehind is how many lines before a line are searched for a
+	// string or comment Match which spans that line.
+	maxBracketLookBehind = 100
+)
+
+// isBracketSkipped returns whether a Match of the Syntax hides brackets.
+func isBracketSkipped(s Syntax) bool {
+	return s == String || s == Comment || s == DocComment
+}
+
+// A bracketScanner finds the strings and comments of lines, from the Matches of
+// a Highlighter, so brackets within them can be skipped.
+type bracketScanner struct {
+	highlighter Highlighter
+	skipped     map[int][][2]int // Column ranges to skip in each line, inclusive
+}
+
+// isSkipped returns whether the rune at line, col is in a string or comment.
+func (s *bracketScanner) isSkipped(line, col int) bool {
+	if s.highlighter == nil {
+		return false
+	}
+	ranges, ok := s.skipped[line]
+	if !ok {
+		for l := line; l >= 0 && l >= line-maxBracketLookBehind; l-- {
+			for _, m := range s.highlighter.GetLineMatches(l) {
+				if !isBracketSkipped(m.Syntax) || m.EndLine < line {
+					continue
+				}
+				start, end := 0, math.MaxInt32
+				if l == line {
+					start = m.Col
+				}
+				if m.EndLine == line {
+					end = m.EndCol
+				}
+				ranges = append(ranges, [2]int{start, end})
+			}
+		}
+		s.skipped[line] = ranges
+	}
+	for _, r := range ranges {
+		if col >= r[0] && col <= r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// BracketPair returns the Region from the bracket at the Cursor to its matching
+// bracket, or from the bracket before the Cursor, if there isn't one at it. The
+// Start of the Region is the opening bracket, and the End the closing bracket.
+// Brackets in strings and comments, according to the Matches of `highlighter`,
+// are skipped, unless the bracket at the Cursor is in one. The highlighter may be
+// nil. Returns false if there is no bracket or it has no match.
+func (c Cursor) BracketPair(highlighter Highlighter) (Region, bool) {
+	w := newRuneWalker(c)
+	r := w.rune()
+	if !isBracket(r) {
+		if w.col == 0 || !w.prev() || !isBracket(w.rune()) {
+			return Region{}, false
+		}
+		r = w.rune()
+	}
+
+	scanner := &bracketScanner{highlighter, make(map[int][][2]int)}
+	skipStrings := !scanner.isSkipped(w.line, w.col)
+	bracket := w.cursor(c)
+
+	forwards := true
+	pair, ok := BracketPairs[r]
+	if !ok {
+		pair, forwards = closingBrackets[r], false
+	}
+
+	var depth int
+	for {
+		if forwards && !w.next() || !forwards && !w.prev() {
+			return Region{}, false
+		}
+		if w.line-bracket.line > maxBracketSearchLines || bracket.line-w.line > maxBracketSearchLines {
+			return Region{}, false
+		}
+		next := w.rune()
+		if next != r && next != pair || skipStrings && scanner.isSkipped(w.line, w.col) {
+			continue
+		}
+		if next == r {
+			depth++
+		} else if depth > 0 {
+			depth--
+		} else if forwards {
+			return Region{bracket, w.cursor(c)}, true
+		} else {
+			return Region{w.cursor(c), bracket}, true
+		}
+	}
+}
+
+// isBracket returns whether the rune is an opening or closing bracket.
+func isBracket(r rune) bool {
+	_, opening := BracketPairs[r]
+	_, closing := closingBrackets[r]
+	return opening || closing
+}
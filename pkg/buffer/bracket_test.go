@@ -0,0 +1,46 @@
+package buffer
+
+import "testing"
+
+func TestCursorBracketPair(t *testing.T) {
+	var buf Buffer = NewRopeBuffer([]byte("func f(a []int) {\n\ts := \")}\" // (\n\tg(a[0])\n}\n(x"))
+	highlighter := NewTokenHighlighter(buf, TokenizeGo, nil)
+	highlighter.UpdateInvalidatedLines(0, buf.Lines()-1)
+	at := func(line, col int) Cursor { return Cursor{buffer: &buf, position: position{line, col}} }
+
+	tests := []struct {
+		name       string
+		from       [2]int
+		start, end [2]int
+		ok         bool
+	}{
+		{"Open", [2]int{0, 6}, [2]int{0, 6}, [2]int{0, 14}, true},
+		{"Close", [2]int{0, 14}, [2]int{0, 6}, [2]int{0, 14}, true},
+		{"Nested", [2]int{0, 9}, [2]int{0, 9}, [2]int{0, 10}, true},
+		{"AfterBracket", [2]int{0, 17}, [2]int{0, 16}, [2]int{3, 0}, true},
+		{"SkipsStringsAndComments", [2]int{3, 0}, [2]int{0, 16}, [2]int{3, 0}, true},
+		{"InString", [2]int{1, 7}, [2]int{}, [2]int{}, false},
+		{"Inner", [2]int{2, 2}, [2]int{2, 2}, [2]int{2, 7}, true},
+		{"NoBracket", [2]int{0, 2}, [2]int{}, [2]int{}, false},
+		{"NoMatch", [2]int{4, 0}, [2]int{}, [2]int{}, false},
+	}
+	for _, tt := range tests {
+		pair, ok := at(tt.from[0], tt.from[1]).BracketPair(highlighter)
+		if ok != tt.ok {
+			t.Errorf("%s: expected ok %v, got %v", tt.name, tt.ok, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		startLine, startCol := pair.Start.GetLineCol()
+		endLine, endCol := pair.End.GetLineCol()
+		if startLine != tt.start[0] || startCol != tt.start[1] || endLine != tt.end[0] || endCol != tt.end[1] {
+			t.Errorf("%s: expected %v to %v, got [%d %d] to [%d %d]", tt.name, tt.start, tt.end, startLine, startCol, endLine, endCol)
+		}
+	}
+
+	if _, ok := at(3, 0).BracketPair(nil); ok { // Without a Highlighter, the '}' in the string is counted
+		t.Errorf("Expected no match without a Highlighter")
+	}
+}
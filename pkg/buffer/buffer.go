@@ -86,6 +86,13 @@ type Buffer interface {
 
 	WriteTo(w io.Writer) (int64, error)
 
+	// Snapshot returns a read-only Buffer with the current contents, which does not
+	// change as this Buffer is edited. A Snapshot can be read from other goroutines,
+	// for example, to highlight or search the text while the user is typing. The
+	// Snapshot of a Snapshot is itself. Editing a Snapshot panics, and Cursors do
+	// not need to be registered with it.
+	Snapshot() Buffer
+
 	// RegisterCursor adds the Cursor to a slice which the Buffer uses to update
 	// each Cursor based on changes that occur in the Buffer. Various functions are
 	// called on the Cursor depending upon where the edits occurred and how it should
@@ -97,4 +104,12 @@ type Buffer interface {
 	// It is mandatory that a Cursor be unregistered before being freed from memory,
 	// or otherwise being forgotten.
 	UnregisterCursor(cursor *Cursor)
+
+	// RegisterObserver adds the Observer to be notified of every Change made to the
+	// Buffer, after it is made. Observers are notified in the order they were
+	// registered, after registered Cursors have been moved.
+	RegisterObserver(observer Observer)
+
+	// UnregisterObserver stops notifying the Observer of Changes.
+	UnregisterObserver(observer Observer)
 }
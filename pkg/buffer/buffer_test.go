@@ -0,0 +1,365 @@
+package buffer
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// bufferImpls are the Buffers tested by the conformance tests below. Every
+// implementation of Buffer should be added here.
+var bufferImpls = map[string]func(contents []byte) Buffer{
+	"Rope":       func(contents []byte) Buffer { return NewRopeBuffer(contents) },
+	"GapBuffer":  func(contents []byte) Buffer { return NewGapBuffer(contents) },
+	"PieceTable": func(contents []byte) Buffer { return NewPieceTable(contents) },
+}
+
+// newEditedBuffer returns a Buffer containing `contents`, put together by edits,
+// so it is stored in several nodes, pieces, or with a gap in the middle.
+func newEditedBuffer(newBuffer func([]byte) Buffer, contents string) Buffer {
+	half := len(contents) / 2
+	for half > 0 && half < len(contents) && contents[half]&0xC0 == 0x80 {
+		half++ // Don't split a rune
+	}
+	buf := newBuffer([]byte(contents[half:]))
+	buf.Insert(0, 0, []byte(contents[:half]))
+	return buf
+}
+
+// A changeRecorder is an Observer that records every Change.
+type changeRecorder struct {
+	changes []Change
+}
+
+func (r *changeRecorder) OnChange(change Change) {
+	r.changes = append(r.changes, change)
+}
+
+func TestBufferConformance(t *testing.T) {
+	for name, newBuffer := range bufferImpls {
+		newBuffer := newBuffer
+		t.Run(name+"/Read", func(t *testing.T) {
+			buf := newEditedBuffer(newBuffer, "this\nis (は)\n\tsome\r\ntext\n")
+
+			if lines := buf.Lines(); lines != 5 {
+				t.Errorf("Expected 5 lines, got %v", lines)
+			}
+			if length := buf.Len(); length != 26 {
+				t.Errorf("Expected 26 bytes, got %v", length)
+			}
+			for i, expected := range []string{"this\n", "is (は)\n", "\tsome\r\n", "text\n", ""} {
+				if line := string(buf.Line(i)); line != expected {
+					t.Errorf("Expected line %d to be %#v, got %#v", i, expected, line)
+				}
+			}
+			if runes := buf.RunesInLine(1); runes != 6 {
+				t.Errorf("Expected 6 runes in line 1, got %v", runes)
+			}
+			if runes := buf.RunesInLineWithDelim(1); runes != 7 {
+				t.Errorf("Expected 7 runes with delimiter in line 1, got %v", runes)
+			}
+			if runes := buf.RunesInLineWithDelim(2); runes != 7 {
+				t.Errorf("Expected 7 runes with CRLF delimiter in line 2, got %v", runes)
+			}
+			if runes := buf.RunesInLineWithDelim(4); runes != 0 {
+				t.Errorf("Expected 0 runes in the last line, got %v", runes)
+			}
+			if pos := buf.LineColToPos(1, 4); pos != 9 {
+				t.Errorf("Expected 'は' at byte 9, got %v", pos)
+			}
+			if pos := buf.LineColToPos(1, 100); pos != 13 {
+				t.Errorf("Expected a column past the line to be its delimiter at 13, got %v", pos)
+			}
+			if line, col := buf.PosToLineCol(13); line != 1 || col != 6 {
+				t.Errorf("Expected byte 13 at 1,6 ; got %d,%d", line, col)
+			}
+			if line, col := buf.PosToLineCol(buf.Len()); line != 4 || col != 0 {
+				t.Errorf("Expected the end of the buffer at 4,0 ; got %d,%d", line, col)
+			}
+			if r := buf.RuneAtPos(9); r != 'は' {
+				t.Errorf("Expected 'は' at byte 9, got %q", r)
+			}
+			if r := buf.RuneAtPos(buf.Len()); r != 0 {
+				t.Errorf("Expected no rune at the end of the buffer, got %q", r)
+			}
+			if line, col := buf.ClampLineCol(15, 5); line != 4 || col != 0 {
+				t.Errorf("Expected to clamp to 4,0 ; got %d,%d", line, col)
+			}
+			if line, col := buf.ClampLineCol(0, -1); line != 0 || col != 0 {
+				t.Errorf("Expected to clamp to 0,0 ; got %d,%d", line, col)
+			}
+			if count := buf.Count(0, 0, 3, 0, []byte{'\n'}); count != 3 {
+				t.Errorf("Expected 3 delimiters before line 3, got %v", count)
+			}
+
+			var runes []rune
+			buf.EachRuneAtPos(6, func(pos int, r rune) bool {
+				runes = append(runes, r)
+				return r == ')'
+			})
+			if str := string(runes); str != "s (は)" {
+				t.Errorf("Expected EachRuneAtPos to stop after ')', got %#v", str)
+			}
+
+			var w bytes.Buffer
+			if n, err := buf.WriteTo(&w); err != nil || n != int64(buf.Len()) {
+				t.Errorf("Expected to write %d bytes, wrote %d: %v", buf.Len(), n, err)
+			}
+			if !bytes.Equal(w.Bytes(), buf.Bytes()) {
+				t.Errorf("Expected WriteTo to write %#v, got %#v", string(buf.Bytes()), w.String())
+			}
+		})
+
+		t.Run(name+"/Slice", func(t *testing.T) {
+			buf := newEditedBuffer(newBuffer, "abc\ndéf\n")
+
+			if slice := string(buf.Slice(0, 0, 2, 0)); slice != "abc\ndéf\n" {
+				t.Errorf("Expected the whole buffer, got %#v", slice)
+			}
+			if slice := string(buf.Slice(1, 0, 1, 3)); slice != "déf\n" {
+				t.Errorf("Expected the second line with its delimiter, got %#v", slice)
+			}
+			if slice := string(buf.Slice(1, 1, 1, 1)); slice != "é" {
+				t.Errorf("Expected the whole rune, got %#v", slice)
+			}
+		})
+
+		t.Run(name+"/Index", func(t *testing.T) {
+			buf := newEditedBuffer(newBuffer, "one two\nthé two\n")
+
+			tests := []struct {
+				pos  int
+				sep  string
+				want int
+			}{
+				{0, "two", 4},
+				{5, "two", 13},
+				{0, "é t", 10},
+				{0, "o\nt", 6},
+				{14, "two", -1},
+				{3, "", 3},
+			}
+			for _, tt := range tests {
+				if got := Index(buf, tt.pos, []byte(tt.sep)); got != tt.want {
+					t.Errorf("Index(%d, %#v): expected %d, got %d", tt.pos, tt.sep, tt.want, got)
+				}
+			}
+		})
+
+		t.Run(name+"/Edit", func(t *testing.T) {
+			buf := newBuffer([]byte("some"))
+			buf.Insert(0, 4, []byte(" text\n"))
+			buf.Insert(0, 0, []byte("with\n\t"))
+			buf.Remove(0, 4, 1, 5) // Remove "\n\tsome "
+			if str := string(buf.Bytes()); str != "withtext\n" {
+				t.Errorf("Expected \"withtext\\n\", got %#v", str)
+			}
+
+			buf.Insert(0, 4, []byte("はは"))
+			buf.Remove(0, 5, 0, 5) // Remove the second multi-byte rune
+			if str := string(buf.Bytes()); str != "withはtext\n" {
+				t.Errorf("Expected \"withはtext\\n\", got %#v", str)
+			}
+
+			buf.Remove(0, 0, 1, 0) // Past the end: remove everything
+			if buf.Len() != 0 || buf.Lines() != 1 {
+				t.Errorf("Expected an empty buffer, got %#v", string(buf.Bytes()))
+			}
+		})
+
+		t.Run(name+"/Anchors", func(t *testing.T) {
+			buf := newBuffer([]byte("abc\ndef\nghi"))
+			sameLine := Cursor{buffer: &buf, position: position{0, 2}} // 'c'
+			nextLine := Cursor{buffer: &buf, position: position{1, 1}} // 'e'
+			lastLine := Cursor{buffer: &buf, position: position{2, 2}} // 'i'
+			buf.RegisterCursor(&sameLine)
+			buf.RegisterCursor(&nextLine)
+			buf.RegisterCursor(&lastLine)
+
+			buf.Insert(0, 0, []byte("x"))    // "xabc\ndef\nghi"
+			buf.Insert(0, 1, []byte("1\n2")) // "x1\n2abc\ndef\nghi"
+			buf.Remove(1, 2, 2, 0)           // Remove "bc\nd": "x1\n2aef\nghi"
+
+			for _, c := range []struct {
+				name      string
+				cursor    *Cursor
+				line, col int
+			}{{"sameLine", &sameLine, 1, 2}, {"nextLine", &nextLine, 1, 2}, {"lastLine", &lastLine, 2, 2}} {
+				if line, col := c.cursor.GetLineCol(); line != c.line || col != c.col {
+					t.Errorf("Expected %s at %d,%d ; got %d,%d", c.name, c.line, c.col, line, col)
+				}
+			}
+
+			buf.UnregisterCursor(&sameLine)
+			buf.Insert(0, 0, []byte("\n"))
+			if line, col := sameLine.GetLineCol(); line != 1 || col != 2 {
+				t.Errorf("Expected an unregistered cursor not to move, got %d,%d", line, col)
+			}
+		})
+
+		t.Run(name+"/Observer", func(t *testing.T) {
+			buf := newBuffer([]byte("abc\ndéf\n"))
+			var recorder changeRecorder
+			buf.RegisterObserver(&recorder)
+
+			buf.Insert(1, 1, []byte("x\nyz")) // "abc\ndx\nyzéf\n"
+			buf.Remove(0, 2, 1, 0)            // Remove "c\nd": "abx\nyzéf\n"
+			buf.Remove(1, 2, 1, 2)            // Remove "é": "abx\nyzf\n"
+			buf.UnregisterObserver(&recorder)
+			buf.Insert(0, 0, []byte("unobserved"))
+
+			expected := []Change{
+				{false, 1, 1, 2, 2, 5, 9, []byte("x\nyz")},
+				{true, 0, 2, 1, 1, 2, 5, []byte("c\nd")},
+				{true, 1, 2, 1, 3, 6, 8, []byte("é")},
+			}
+			if !reflect.DeepEqual(recorder.changes, expected) {
+				t.Errorf("Expected changes %v, got %v", expected, recorder.changes)
+			}
+		})
+
+		t.Run(name+"/Snapshot", func(t *testing.T) {
+			buf := newBuffer([]byte("line0\nline1\n"))
+			snapshot := buf.Snapshot()
+
+			buf.Insert(1, 4, []byte("X"))
+			buf.Remove(0, 0, 0, 2)
+			if str := string(buf.Bytes()); str != "e0\nlineX1\n" {
+				t.Errorf("Expected the buffer to be edited, got %#v", str)
+			}
+			if str := string(snapshot.Bytes()); str != "line0\nline1\n" {
+				t.Errorf("Expected the snapshot to be unchanged, got %#v", str)
+			}
+			if snapshot.Snapshot() != snapshot {
+				t.Error("Expected the snapshot of a snapshot to be itself")
+			}
+
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected editing a snapshot to panic")
+				}
+			}()
+			snapshot.Insert(0, 0, []byte("X"))
+		})
+
+		// Should be run with the race detector (go test -race).
+		t.Run(name+"/SnapshotConcurrent", func(t *testing.T) {
+			contents := bytes.Repeat([]byte("Hello, world!\n"), 2000)
+			buf := newBuffer(append([]byte(nil), contents...))
+
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				snapshot, expected := buf.Snapshot(), contents
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 20; j++ {
+						if !bytes.Equal(snapshot.Bytes(), expected) {
+							t.Error("Expected the snapshot to be unchanged while the buffer is edited")
+							return
+						}
+						snapshot.Line(j * 50)
+						snapshot.LineColToPos(1500, 5)
+					}
+				}()
+				for j := 0; j < 200; j++ {
+					buf.Insert(j*7, 3, []byte("é"))
+					buf.Remove(j*7+1, 0, j*7+1, 1)
+				}
+				contents = append([]byte(nil), buf.Bytes()...)
+			}
+			wg.Wait()
+		})
+	}
+}
+
+// TestBufferRandomEdits makes the same random edits to every Buffer and checks
+// that they agree with the RopeBuffer after each one.
+func TestBufferRandomEdits(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	words := []string{"a", "bc", "é", "は", "\n", "\r\n", "\t", "word "}
+
+	expected := NewRopeBuffer([]byte("first\nsecond\n"))
+	bufs := make(map[string]Buffer)
+	for name, newBuffer := range bufferImpls {
+		bufs[name] = newBuffer([]byte("first\nsecond\n"))
+	}
+
+	for i := 0; i < 500; i++ {
+		line := rng.Intn(expected.Lines())
+		line, col := expected.ClampLineCol(line, rng.Intn(expected.RunesInLineWithDelim(line)+1))
+		var edit func(buf Buffer)
+		if rng.Intn(3) > 0 || expected.Len() == 0 {
+			text := []byte(words[rng.Intn(len(words))])
+			edit = func(buf Buffer) { buf.Insert(line, col, text) }
+		} else {
+			endLine := Min(line+rng.Intn(2), expected.Lines()-1)
+			_, endCol := expected.ClampLineCol(endLine, rng.Intn(8))
+			edit = func(buf Buffer) { buf.Remove(line, col, endLine, endCol) }
+		}
+
+		edit(expected)
+		for name, buf := range bufs {
+			edit(buf)
+			if !bytes.Equal(buf.Bytes(), expected.Bytes()) {
+				t.Fatalf("%s: after edit %d, expected %#v, got %#v", name, i, string(expected.Bytes()), string(buf.Bytes()))
+			}
+			for l := 0; l < expected.Lines(); l++ {
+				if !bytes.Equal(buf.Line(l), expected.Line(l)) ||
+					buf.RunesInLine(l) != expected.RunesInLine(l) ||
+					buf.RunesInLineWithDelim(l) != expected.RunesInLineWithDelim(l) {
+					t.Fatalf("%s: after edit %d, line %d differs", name, i, l)
+				}
+			}
+		}
+	}
+}
+
+func benchmarkBuffers(b *testing.B, f func(b *testing.B, buf Buffer)) {
+	for _, lines := range []int{1000, 100000} {
+		contents := bytes.Repeat([]byte("The quick brown fox jumps over the lazy dog.\n"), lines)
+		for name, newBuffer := range bufferImpls {
+			b.Run(fmt.Sprintf("%s/%d", name, lines), func(b *testing.B) {
+				buf := newBuffer(append([]byte(nil), contents...))
+				b.ResetTimer()
+				f(b, buf)
+			})
+		}
+	}
+}
+
+// BenchmarkBufferTyping inserts characters one after another, like typing.
+func BenchmarkBufferTyping(b *testing.B) {
+	benchmarkBuffers(b, func(b *testing.B, buf Buffer) {
+		line := buf.Lines() / 2
+		for i := 0; i < b.N; i++ {
+			buf.Insert(line, i%40, []byte("x"))
+		}
+	})
+}
+
+// BenchmarkBufferRandomEdits inserts and removes at random lines.
+func BenchmarkBufferRandomEdits(b *testing.B) {
+	benchmarkBuffers(b, func(b *testing.B, buf Buffer) {
+		rng := rand.New(rand.NewSource(1))
+		for i := 0; i < b.N; i++ {
+			line := rng.Intn(buf.Lines() - 1)
+			buf.Insert(line, 4, []byte("word "))
+			buf.Remove(line, 4, line, 8)
+		}
+	})
+}
+
+// BenchmarkBufferReadLines reads each line from the start of the buffer, like
+// drawing or highlighting.
+func BenchmarkBufferReadLines(b *testing.B) {
+	benchmarkBuffers(b, func(b *testing.B, buf Buffer) {
+		lines := buf.Lines()
+		for i := 0; i < b.N; i++ {
+			buf.Line(i % lines)
+		}
+	})
+}
@@ -0,0 +1,46 @@
+package buffer
+
+// A Change is an insertion or removal of text in a Buffer. Changes are delivered
+// to each registered Observer after they are made.
+type Change struct {
+	Removed bool // Whether Text was removed; otherwise it was inserted
+
+	// The range of Text, with an exclusive end. For an insertion, the range is of
+	// the buffer after the Change. For a removal, it is of the buffer before it.
+	StartLine, StartCol int
+	EndLine, EndCol     int
+	StartPos, EndPos    int // Byte offsets of the range
+
+	Text []byte // The inserted or removed text. Do not modify it.
+}
+
+// LinesChanged returns true if the Change added or removed any line delimiters,
+// so every line after it has moved.
+func (c Change) LinesChanged() bool {
+	return c.EndLine != c.StartLine
+}
+
+// An Observer is notified of every Change made to a Buffer it is registered with,
+// using Buffer.RegisterObserver.
+type Observer interface {
+	OnChange(change Change)
+}
+
+// newChange returns the Change for `text` at line, col, and byte offset pos.
+func newChange(removed bool, line, col, pos int, text []byte) Change {
+	newlines, lastRunes := measureText(text)
+	endCol := lastRunes
+	if newlines == 0 {
+		endCol += col
+	}
+	return Change{
+		Removed:   removed,
+		StartLine: line,
+		StartCol:  col,
+		EndLine:   line + newlines,
+		EndCol:    endCol,
+		StartPos:  pos,
+		EndPos:    pos + len(text),
+		Text:      text,
+	}
+}
@@ -0,0 +1,337 @@
+package buffer
+
+import (
+	"bytes"
+	"io"
+	"unicode/utf8"
+)
+
+// A chunkSource stores the text of a buffer in a sequence of byte slices, like
+// the two halves of a gap buffer, or the pieces of a piece table. Chunks always
+// begin and end on rune boundaries.
+type chunkSource interface {
+	Len() int
+
+	// eachChunk calls f with each chunk of the text in order, until f returns true.
+	// The chunks are references; not copies.
+	eachChunk(f func(chunk []byte) bool)
+}
+
+// A lineCounter is a chunkSource that keeps count of its line delimiters, so
+// lines can be found without scanning every chunk before them.
+type lineCounter interface {
+	countLines() int
+	lineStartPos(line int) int
+}
+
+// chunkedText implements the methods of Buffer that only read the text, for any
+// chunkSource, by scanning its chunks. A buffer embeds it and points it at itself.
+type chunkedText struct {
+	src chunkSource
+}
+
+// eachChunkFrom calls f with each chunk of the text following byte position
+// `pos`, and the position of the first byte of the chunk, until f returns true.
+func (t chunkedText) eachChunkFrom(pos int, f func(pos int, chunk []byte) bool) {
+	var offset int
+	t.src.eachChunk(func(chunk []byte) bool {
+		end := offset + len(chunk)
+		if end > pos {
+			start := Max(pos-offset, 0)
+			if f(offset+start, chunk[start:]) {
+				return true
+			}
+		}
+		offset = end
+		return false
+	})
+}
+
+// slice returns a copy of the bytes from start to end, exclusive.
+func (t chunkedText) slice(start, end int) []byte {
+	if start >= end {
+		return []byte{}
+	}
+	data := make([]byte, 0, end-start)
+	t.eachChunkFrom(start, func(pos int, chunk []byte) bool {
+		if n := end - pos; n <= len(chunk) {
+			data = append(data, chunk[:n]...)
+			return true
+		}
+		data = append(data, chunk...)
+		return false
+	})
+	return data
+}
+
+// LineColToPos returns the index of the byte at line, col. If line is less than
+// zero, or more than the number of available lines, the function will panic. If
+// col is less than zero, the function will panic. If col is greater than the
+// length of the line, the position of the last byte of the line is returned,
+// instead.
+func (t chunkedText) LineColToPos(line, col int) int {
+	pos := t.getLineStartPos(line)
+
+	if col > 0 {
+		t.eachChunkFrom(pos, func(_ int, chunk []byte) bool {
+			for _, r := range string(chunk) {
+				if col == 0 || r == '\n' {
+					return true // Found the position of the column
+				}
+				pos += utf8.RuneLen(r)
+				col--
+			}
+			return false
+		})
+	}
+
+	return pos
+}
+
+// Line returns a slice of the data at the given line, including the ending line-
+// delimiter. line starts from zero. The data returned is a copy.
+func (t chunkedText) Line(line int) []byte {
+	start := t.getLineStartPos(line)
+	end := t.src.Len()
+
+	t.eachChunkFrom(start, func(pos int, chunk []byte) bool {
+		if i := bytes.IndexByte(chunk, '\n'); i >= 0 {
+			end = pos + i + 1 // Include the delimiter
+			return true
+		}
+		return false
+	})
+
+	return t.slice(start, end)
+}
+
+// Returns a slice of the buffer from startLine, startCol, to endLine, endCol,
+// inclusive bounds. The returned value is a copy.
+func (t chunkedText) Slice(startLine, startCol, endLine, endCol int) []byte {
+	endPos := t.runeEndPos(t.LineColToPos(endLine, endCol))
+	return t.slice(t.LineColToPos(startLine, startCol), endPos)
+}
+
+// RuneAtPos returns the UTF-8 rune at the byte position `pos` of the buffer. The
+// position must be a correct position, otherwise zero is returned.
+func (t chunkedText) RuneAtPos(pos int) rune {
+	length := t.src.Len()
+	if pos < 0 || pos >= length {
+		return 0
+	}
+	r, _ := utf8.DecodeRune(t.slice(pos, Min(pos+utf8.UTFMax, length)))
+	return r
+}
+
+// EachRuneAtPos executes the function `f` at each rune after byte position `pos`.
+// The function returns when the end of the buffer is met or `f` returns true.
+func (t chunkedText) EachRuneAtPos(pos int, f func(pos int, r rune) bool) {
+	t.eachChunkFrom(pos, func(chunkPos int, chunk []byte) bool {
+		for i, r := range string(chunk) {
+			if f(chunkPos+i, r) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// indexFrom is Index for the chunks of the text. Each chunk is searched in place.
+// Only the bytes around the end of a chunk are copied, to find an occurrence that
+// continues into the next chunk.
+func (t chunkedText) indexFrom(pos int, sep []byte) int {
+	found := -1
+	keep := len(sep) - 1
+	var tail []byte // The last bytes before the chunk, up to `keep` of them
+	t.eachChunkFrom(pos, func(chunkPos int, chunk []byte) bool {
+		if len(tail) > 0 {
+			joined := append(tail, chunk[:Min(len(chunk), keep)]...)
+			if i := bytes.Index(joined, sep); i >= 0 {
+				found = chunkPos - len(tail) + i
+				return true
+			}
+		}
+		if i := bytes.Index(chunk, sep); i >= 0 {
+			found = chunkPos + i
+			return true
+		}
+		tail = append(tail, chunk[Max(0, len(chunk)-keep):]...)
+		tail = append(tail[:0], tail[Max(0, len(tail)-keep):]...)
+		return false
+	})
+	return found
+}
+
+// Bytes returns a copy of all of the bytes in the buffer.
+func (t chunkedText) Bytes() []byte {
+	return t.slice(0, t.src.Len())
+}
+
+// Returns the number of occurrences of 'sequence' in the buffer, within the range
+// of start line and col, to end line and col. End is exclusive.
+func (t chunkedText) Count(startLine, startCol, endLine, endCol int, sequence []byte) int {
+	startPos := t.LineColToPos(startLine, startCol)
+	endPos := t.LineColToPos(endLine, endCol)
+	return bytes.Count(t.slice(startPos, endPos), sequence)
+}
+
+// Lines returns the number of lines in the buffer. If the buffer is empty,
+// 1 is returned, because there is always at least one line.
+func (t chunkedText) Lines() int {
+	if c, ok := t.src.(lineCounter); ok {
+		return c.countLines()
+	}
+
+	lines := 1
+	t.src.eachChunk(func(chunk []byte) bool {
+		lines += bytes.Count(chunk, []byte{'\n'})
+		return false
+	})
+	return lines
+}
+
+// runeEndPos returns the byte index after the rune starting at `pos`, clamped to
+// the length of the buffer.
+func (t chunkedText) runeEndPos(pos int) int {
+	length := t.src.Len()
+	if pos >= length {
+		return length
+	}
+	_, size := utf8.DecodeRune(t.slice(pos, Min(pos+utf8.UTFMax, length)))
+	return pos + size
+}
+
+// getLineStartPos returns the first byte index of the given line (starting from zero).
+// The returned index can be equal to the length of the buffer. If line is greater
+// than or equal to the number of lines in the buffer, a panic is issued.
+func (t chunkedText) getLineStartPos(line int) int {
+	if c, ok := t.src.(lineCounter); ok {
+		return c.lineStartPos(line)
+	}
+
+	var pos int
+
+	if line > 0 {
+		t.src.eachChunk(func(chunk []byte) bool {
+			offset := 0
+			for line > 0 {
+				i := bytes.IndexByte(chunk[offset:], '\n')
+				if i < 0 {
+					break
+				}
+				offset += i + 1
+				line--
+			}
+			pos += offset
+			if line > 0 {
+				pos += len(chunk) - offset
+			}
+			return line <= 0
+		})
+	}
+
+	if line > 0 { // If there aren't enough lines to reach line...
+		panic("getLineStartPos: not enough lines in buffer to reach position")
+	}
+
+	return pos
+}
+
+// RunesInLineWithDelim returns the number of runes in the given line. Includes
+// the line delimiter in the count. If that line delimiter is CRLF ('\r\n'), then
+// it adds two.
+func (t chunkedText) RunesInLineWithDelim(line int) int {
+	var count int
+	t.eachChunkFrom(t.getLineStartPos(line), func(_ int, chunk []byte) bool {
+		for _, r := range string(chunk) {
+			count++ // Before: we count the line delimiter
+			if r == '\n' {
+				return true
+			}
+		}
+		return false
+	})
+	return count
+}
+
+// RunesInLine returns the number of runes in the given line. Excludes line
+// delimiters.
+func (t chunkedText) RunesInLine(line int) int {
+	var count int
+	var isCRLF bool // true if the last rune was '\r'
+	t.eachChunkFrom(t.getLineStartPos(line), func(_ int, chunk []byte) bool {
+		for _, r := range string(chunk) {
+			if r == '\n' {
+				if isCRLF {
+					count-- // The '\r' we counted is part of the delimiter
+				}
+				return true
+			}
+			isCRLF = r == '\r'
+			count++
+		}
+		return false
+	})
+	return count
+}
+
+// ClampLineCol clamps any provided line and col to only possible values within
+// the buffer, pointing to runes. It first clamps the line, then clamps the column.
+func (t chunkedText) ClampLineCol(line, col int) (int, int) {
+	if line < 0 {
+		line = 0
+	} else if lines := t.Lines() - 1; line > lines {
+		line = lines
+	}
+
+	if col < 0 {
+		col = 0
+	} else if runes := t.RunesInLine(line); col > runes {
+		col = runes
+	}
+
+	return line, col
+}
+
+// PosToLineCol converts a byte offset (position) of the buffer's bytes, into
+// a line and column. Position will be clamped.
+func (t chunkedText) PosToLineCol(pos int) (int, int) {
+	var line, col int
+
+	if pos <= 0 {
+		return line, col
+	}
+
+	// Count the delimiters a chunk at a time, then the runes after the last one
+	lineStart := 0
+	t.eachChunkFrom(0, func(chunkPos int, chunk []byte) bool {
+		chunk = chunk[:Min(len(chunk), pos-chunkPos)]
+		line += bytes.Count(chunk, []byte{'\n'})
+		if i := bytes.LastIndexByte(chunk, '\n'); i >= 0 {
+			lineStart = chunkPos + i + 1
+		}
+		return chunkPos+len(chunk) >= pos
+	})
+	t.EachRuneAtPos(lineStart, func(rpos int, _ rune) bool {
+		if rpos >= pos {
+			return true
+		}
+		col++
+		return false
+	})
+
+	return line, col
+}
+
+// WriteTo writes each chunk of the text to `w`.
+func (t chunkedText) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	var err error
+	t.src.eachChunk(func(chunk []byte) bool {
+		var n int
+		n, err = w.Write(chunk)
+		written += int64(n)
+		return err != nil
+	})
+	return written, err
+}
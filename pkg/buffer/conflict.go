@@ -0,0 +1,239 @@
+package buffer
+
+import (
+	"bytes"
+	"sort"
+)
+
+// A Resolution chooses which sides of a Conflict are kept when it is resolved.
+type Resolution uint8
+
+const (
+	ResolveOurs   Resolution = iota // Keep the lines of the current change
+	ResolveTheirs                   // Keep the lines of the incoming change
+	ResolveBoth                     // Keep ours, followed by theirs
+	ResolveBase                     // Keep the lines of the common ancestor
+)
+
+// A Conflict is a region of a buffer delimited by merge conflict markers, as
+// written by version control tools like git. All fields are line indexes of
+// the marker lines:
+//
+//	<<<<<<< ours      (Start)
+//	...
+//	||||||| base      (Base, only in the diff3 style; otherwise -1)
+//	...
+//	=======           (Separator)
+//	...
+//	>>>>>>> theirs    (End)
+type Conflict struct {
+	Start     int
+	Base      int
+	Separator int
+	End       int
+}
+
+// Ours returns the range of lines [start, end) of the current change.
+func (c Conflict) Ours() (int, int) {
+	if c.Base >= 0 {
+		return c.Start + 1, c.Base
+	}
+	return c.Start + 1, c.Separator
+}
+
+// BaseLines returns the range of lines [start, end) of the common ancestor. The
+// range is empty if the Conflict does not include the base.
+func (c Conflict) BaseLines() (int, int) {
+	if c.Base >= 0 {
+		return c.Base + 1, c.Separator
+	}
+	return c.Separator, c.Separator
+}
+
+// Theirs returns the range of lines [start, end) of the incoming change.
+func (c Conflict) Theirs() (int, int) {
+	return c.Separator + 1, c.End
+}
+
+// Contains returns whether the line is within the Conflict, including its markers.
+func (c Conflict) Contains(line int) bool {
+	return line >= c.Start && line <= c.End
+}
+
+// Resolve returns the text that replaces the lines of the Conflict, from Start
+// to End inclusively, to resolve it as `r`. The text is taken from the lines of
+// `buf`.
+func (c Conflict) Resolve(buf Buffer, r Resolution) []byte {
+	var ranges [][2]int
+	switch r {
+	case ResolveOurs:
+		start, end := c.Ours()
+		ranges = [][2]int{{start, end}}
+	case ResolveTheirs:
+		start, end := c.Theirs()
+		ranges = [][2]int{{start, end}}
+	case ResolveBoth:
+		oStart, oEnd := c.Ours()
+		tStart, tEnd := c.Theirs()
+		ranges = [][2]int{{oStart, oEnd}, {tStart, tEnd}}
+	case ResolveBase:
+		start, end := c.BaseLines()
+		ranges = [][2]int{{start, end}}
+	}
+
+	var result []byte
+	for _, rng := range ranges {
+		for i := rng[0]; i < rng[1]; i++ {
+			result = append(result, buf.Line(i)...)
+		}
+	}
+	return result
+}
+
+// isMarker returns true if the line starts with seven of `c`, followed by a
+// space or the end of the line.
+func isMarker(line []byte, c byte) bool {
+	if len(line) < 7 {
+		return false
+	}
+	for i := 0; i < 7; i++ {
+		if line[i] != c {
+			return false
+		}
+	}
+	if len(line) == 7 {
+		return true
+	}
+	switch line[7] {
+	case ' ', '\t', '\r', '\n':
+		return true
+	}
+	return false
+}
+
+// A conflictMarker is a line beginning with a conflict marker.
+type conflictMarker struct {
+	line int
+	kind byte // The character repeated by the marker: '<', '|', '=', or '>'
+}
+
+// markerKind returns the character repeated by the conflict marker at the start
+// of `line`, or zero if it does not begin with one.
+func markerKind(line []byte) byte {
+	for _, c := range []byte("<|=>") {
+		if isMarker(line, c) {
+			return c
+		}
+	}
+	return 0
+}
+
+// findMarkers returns the conflict markers in `text`, whose first line is the
+// line numbered `line`.
+func findMarkers(text []byte, line int) []conflictMarker {
+	var markers []conflictMarker
+	for ; len(text) > 0; line++ {
+		end := bytes.IndexByte(text, '\n') + 1
+		if end == 0 {
+			end = len(text)
+		}
+		if kind := markerKind(text[:end]); kind != 0 {
+			markers = append(markers, conflictMarker{line, kind})
+		}
+		text = text[end:]
+	}
+	return markers
+}
+
+// conflictsOf returns every complete Conflict delimited by the markers, which are
+// in order. Incomplete or improperly nested markers are ignored.
+func conflictsOf(markers []conflictMarker) []Conflict {
+	var conflicts []Conflict
+
+	current := Conflict{Start: -1, Base: -1, Separator: -1, End: -1}
+	for _, m := range markers {
+		switch {
+		case m.kind == '<':
+			current = Conflict{Start: m.line, Base: -1, Separator: -1, End: -1} // Restarts any incomplete Conflict
+		case current.Start < 0:
+			continue // Other markers are only meaningful after a start marker
+		case m.kind == '|' && current.Separator < 0 && current.Base < 0:
+			current.Base = m.line
+		case m.kind == '=' && current.Separator < 0:
+			current.Separator = m.line
+		case m.kind == '>' && current.Separator >= 0:
+			current.End = m.line
+			conflicts = append(conflicts, current)
+			current = Conflict{Start: -1, Base: -1, Separator: -1, End: -1}
+		}
+	}
+	return conflicts
+}
+
+// FindConflicts returns every complete Conflict in the buffer, in order.
+// Incomplete or improperly nested markers are ignored.
+func FindConflicts(buf Buffer) []Conflict {
+	return conflictsOf(findMarkers(buf.Bytes(), 0))
+}
+
+// A ConflictFinder keeps the Conflicts of a Buffer as it is edited. The Buffer is
+// read once, when the ConflictFinder is created. After that, only the lines each
+// Change touches are looked at again for markers. It must be registered as an
+// Observer of the Buffer.
+type ConflictFinder struct {
+	Buffer Buffer
+
+	markers   []conflictMarker // In order of line
+	conflicts []Conflict
+	stale     bool // Whether the conflicts must be found again from the markers
+}
+
+// NewConflictFinder returns a ConflictFinder of the Conflicts in `buf`.
+func NewConflictFinder(buf Buffer) *ConflictFinder {
+	markers := findMarkers(buf.Bytes(), 0)
+	return &ConflictFinder{Buffer: buf, markers: markers, conflicts: conflictsOf(markers)}
+}
+
+// OnChange moves the markers after the Change, and looks for markers again on
+// the lines it touched.
+func (f *ConflictFinder) OnChange(change Change) {
+	moved := change.EndLine - change.StartLine
+	first, last := change.StartLine, change.StartLine // Lines to look at again
+	if !change.Removed {
+		last = change.EndLine
+	}
+
+	kept := f.markers[:0]
+	for _, m := range f.markers {
+		switch {
+		case m.line < first:
+		case m.line == first || change.Removed && m.line <= change.EndLine:
+			continue // Looked at again, or removed
+		case change.Removed:
+			m.line -= moved
+		default:
+			m.line += moved
+		}
+		kept = append(kept, m)
+	}
+
+	var found []conflictMarker
+	for line := first; line <= last && line < f.Buffer.Lines(); line++ {
+		if kind := markerKind(f.Buffer.Line(line)); kind != 0 {
+			found = append(found, conflictMarker{line, kind})
+		}
+	}
+	i := sort.Search(len(kept), func(i int) bool { return kept[i].line > last })
+	f.markers = append(kept[:i], append(found, kept[i:]...)...)
+	f.stale = true
+}
+
+// GetConflicts returns every complete Conflict in the Buffer, in order. Do not
+// modify the slice.
+func (f *ConflictFinder) GetConflicts() []Conflict {
+	if f.stale {
+		f.conflicts = conflictsOf(f.markers)
+		f.stale = false
+	}
+	return f.conflicts
+}
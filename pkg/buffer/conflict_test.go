@@ -0,0 +1,85 @@
+package buffer
+
+import (
+	"reflect"
+	"testing"
+)
+
+const conflictText = `package main
+<<<<<<< HEAD
+ours
+||||||| base
+base
+=======
+theirs
+>>>>>>> feature
+between
+<<<<<<< HEAD
+=======
+only theirs
+>>>>>>> feature
+<<<<<<< incomplete
+`
+
+func TestFindConflicts(t *testing.T) {
+	var buf Buffer = NewRopeBuffer([]byte(conflictText))
+
+	conflicts := FindConflicts(buf)
+	if len(conflicts) != 2 {
+		t.Fatalf("Expected 2 conflicts, got %v", conflicts)
+	}
+
+	if c := conflicts[0]; c != (Conflict{Start: 1, Base: 3, Separator: 5, End: 7}) {
+		t.Errorf("Unexpected first conflict %+v", c)
+	}
+	if c := conflicts[1]; c != (Conflict{Start: 9, Base: -1, Separator: 10, End: 12}) {
+		t.Errorf("Unexpected second conflict %+v", c)
+	}
+
+	if start, end := conflicts[1].Ours(); start != end {
+		t.Errorf("Expected no lines of ours in second conflict, got [%d, %d)", start, end)
+	}
+	if !conflicts[0].Contains(7) || conflicts[0].Contains(8) {
+		t.Errorf("Contains includes the wrong lines")
+	}
+}
+
+func TestResolveConflict(t *testing.T) {
+	buf := NewRopeBuffer([]byte(conflictText))
+	c := Conflict{Start: 1, Base: 3, Separator: 5, End: 7}
+
+	expected := map[Resolution]string{
+		ResolveOurs:   "ours\n",
+		ResolveTheirs: "theirs\n",
+		ResolveBoth:   "ours\ntheirs\n",
+		ResolveBase:   "base\n",
+	}
+	for r, text := range expected {
+		if result := string(c.Resolve(buf, r)); result != text {
+			t.Errorf("Expected resolution %d to be %q, got %q", r, text, result)
+		}
+	}
+}
+
+func TestConflictFinder(t *testing.T) {
+	var buf Buffer = NewRopeBuffer([]byte(conflictText))
+	finder := NewConflictFinder(buf)
+	buf.RegisterObserver(finder)
+
+	edits := []func(){
+		func() { buf.Insert(0, 0, []byte("// Comment\n\n")) },        // Moves the conflicts down
+		func() { buf.Insert(3, 0, []byte("x")) },                     // Breaks the first start marker
+		func() { buf.Remove(3, 0, 3, 0) },                            // Fixes it
+		func() { buf.Insert(16, 0, []byte("=======\n>>>>>>> b\n")) }, // Completes the last conflict
+		func() { buf.Remove(5, 0, 8, 0) },                            // Removes the base and separator
+		func() { buf.Insert(2, 0, []byte("<<<<<<< a\n=======\n>>>>>>> b\n")) },
+		func() { buf.Remove(0, 0, 1, 0) },
+	}
+	for i, edit := range edits {
+		edit()
+		expected := FindConflicts(buf)
+		if conflicts := finder.GetConflicts(); !reflect.DeepEqual(conflicts, expected) {
+			t.Errorf("After edit %d, expected conflicts %v, got %v", i, expected, conflicts)
+		}
+	}
+}
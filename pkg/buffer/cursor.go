@@ -54,8 +54,9 @@ func (c Cursor) Left() Cursor {
 		// Go to the end of the above line
 		c.line--
 		c.col = (*c.buffer).RunesInLine(c.line)
-	} else {
-		c.col = Max(c.col-1, 0)
+	} else if c.col > 0 {
+		c.col-- // Go to the start of the grapheme cluster before the cursor
+		c = c.snapToGrapheme()
 	}
 	return c
 }
@@ -66,7 +67,14 @@ func (c Cursor) Right() Cursor {
 	if c.col >= (*c.buffer).RunesInLine(c.line) && c.line < (*c.buffer).Lines()-1 {
 		c.line, c.col = (*c.buffer).ClampLineCol(c.line+1, 0) // Go to beginning of line below
 	} else {
-		c.line, c.col = (*c.buffer).ClampLineCol(c.line, c.col+1)
+		// Go to the start of the next grapheme cluster, or the end of the line
+		for _, start := range c.graphemeStarts(c.line) {
+			if start > c.col {
+				c.col = start
+				break
+			}
+		}
+		c.line, c.col = (*c.buffer).ClampLineCol(c.line, c.col)
 	}
 	return c
 }
@@ -76,6 +84,7 @@ func (c Cursor) Up() Cursor {
 		c.line, c.col = 0, 0 // Go to beginning
 	} else {
 		c.line, c.col = (*c.buffer).ClampLineCol(c.line-1, c.col)
+		c = c.snapToGrapheme()
 	}
 	return c
 }
@@ -85,43 +94,35 @@ func (c Cursor) Down() Cursor {
 		c.line, c.col = (*c.buffer).ClampLineCol(c.line, math.MaxInt32) // Go to end of current line
 	} else {
 		c.line, c.col = (*c.buffer).ClampLineCol(c.line+1, c.col)
+		c = c.snapToGrapheme()
 	}
 	return c
 }
 
-// NextWordBoundaryEnd proceeds to the position after the last character of the
-// next word boundary to the right of the Cursor. A word boundary is the
-// beginning or end of any sequence of similar or same-classed characters.
-// Whitespace is skipped.
-func (c Cursor) NextWordBoundaryEnd() Cursor {
-	// Get position of cursor in buffer as pos
-	// get classification of character at pos or assume none if whitespace
-	// for each pos until end of buffer: pos + 1 (at end)
-	//	 if pos char is not of previous pos char class:
-	//      set cursor position as pos
-	//
-
-	// only skip contiguous characters for word characters
-	// jump to position *after* any symbols
-
-	pos := (*c.buffer).LineColToPos(c.line, c.col)
-	startClass := getRuneCharclass((*c.buffer).RuneAtPos(pos))
-	pos++
-	(*c.buffer).EachRuneAtPos(pos, func(rpos int, r rune) bool {
-		class := getRuneCharclass(r)
-		if class != startClass && class != charwhitespace {
-			return true
+// Word returns the Region of the word at the Cursor, or the word ending before
+// it, if the Cursor is just after a word. A word is a sequence of letters, digits,
+// and underscores. If there is no word, false is returned.
+func (c Cursor) Word() (Region, bool) {
+	runes := []rune(string((*c.buffer).Line(c.line)))
+	col := c.col
+	if col >= len(runes) || getRuneCharclass(runes[col]) != charword {
+		col-- // Try the word before the Cursor
+		if col < 0 || col >= len(runes) || getRuneCharclass(runes[col]) != charword {
+			return Region{}, false
 		}
-		return false
-	})
-
-	c.line, c.col = (*c.buffer).PosToLineCol(pos)
+	}
 
-	return c
-}
+	start, end := col, col
+	for start > 0 && getRuneCharclass(runes[start-1]) == charword {
+		start--
+	}
+	for end+1 < len(runes) && getRuneCharclass(runes[end+1]) == charword {
+		end++
+	}
 
-func (c Cursor) PrevWordBoundaryStart() Cursor {
-	return c
+	startCursor, endCursor := c, c
+	startCursor.col, endCursor.col = start, end
+	return Region{startCursor, endCursor}, true
 }
 
 func (c Cursor) GetLineCol() (line, col int) {
@@ -0,0 +1,128 @@
+package buffer
+
+import (
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A FoldRange is a range of lines of a buffer which can be folded, so only its
+// first line is shown. Start and End are line indexes, and End is inclusive:
+//
+//	func main() {    (Start)
+//		...
+//		...           (End)
+//	}
+type FoldRange struct {
+	Start int
+	End   int
+}
+
+// Contains returns whether the line is within the FoldRange, including its first line.
+func (f FoldRange) Contains(line int) bool {
+	return line >= f.Start && line <= f.End
+}
+
+// FindFolds returns the BracketFolds of the buffer, and the IndentFolds of the
+// lines which begin no bracket fold, ordered by Start. Ranges with the same Start
+// are ordered from the largest.
+func FindFolds(buf Buffer, highlighter Highlighter, tabSize int) []FoldRange {
+	folds := BracketFolds(buf, highlighter)
+	starts := make(map[int]bool, len(folds))
+	for _, f := range folds {
+		starts[f.Start] = true
+	}
+	for _, f := range IndentFolds(buf, tabSize) {
+		if !starts[f.Start] {
+			folds = append(folds, f)
+		}
+	}
+	sort.Slice(folds, func(i, j int) bool {
+		if folds[i].Start != folds[j].Start {
+			return folds[i].Start < folds[j].Start
+		}
+		return folds[i].End > folds[j].End
+	})
+	return folds
+}
+
+// BracketFolds returns a FoldRange for each line ending with an opening bracket
+// whose closing bracket is at least two lines later. The range ends before the
+// line of the closing bracket, so that line is shown. Brackets in strings and
+// comments, according to the Matches of `highlighter`, are skipped. The
+// highlighter may be nil.
+func BracketFolds(buf Buffer, highlighter Highlighter) []FoldRange {
+	var folds []FoldRange
+	scanner := &bracketScanner{highlighter, make(map[int][][2]int)}
+	cursor := NewCursor(&buf)
+	for line := 0; line < buf.Lines(); line++ {
+		text := buf.Line(line)
+		col := utf8.RuneCount(text)
+		var r rune
+		for len(text) > 0 { // Find the last rune which is not a space
+			var size int
+			r, size = utf8.DecodeLastRune(text)
+			text = text[:len(text)-size]
+			col--
+			if !unicode.IsSpace(r) {
+				break
+			}
+		}
+		if _, ok := BracketPairs[r]; !ok || scanner.isSkipped(line, col) {
+			continue
+		}
+		if pair, ok := cursor.SetLineCol(line, col).BracketPair(highlighter); ok {
+			if endLine, _ := pair.End.GetLineCol(); endLine-1 > line {
+				folds = append(folds, FoldRange{line, endLine - 1})
+			}
+		}
+	}
+	return folds
+}
+
+// IndentFolds returns a FoldRange for each line followed by lines indented more
+// than it is. The range ends at the last of those lines, not counting blank lines
+// after it. A tab indents to the next multiple of `tabSize` columns.
+func IndentFolds(buf Buffer, tabSize int) []FoldRange {
+	var folds []FoldRange
+	type open struct{ line, indent int }
+	var opened []open // Lines which may begin a fold, by increasing indent
+	lastLine := -1    // The last line which is not blank
+	for line := 0; line <= buf.Lines(); line++ {
+		indent := -1 // Past the end of the buffer, every fold is closed
+		if line < buf.Lines() {
+			if indent = getIndent(buf.Line(line), tabSize); indent < 0 {
+				continue // Blank lines don't end folds
+			}
+		}
+		for len(opened) > 0 && opened[len(opened)-1].indent >= indent {
+			if o := opened[len(opened)-1]; lastLine > o.line {
+				folds = append(folds, FoldRange{o.line, lastLine})
+			}
+			opened = opened[:len(opened)-1]
+		}
+		opened = append(opened, open{line, indent})
+		lastLine = line
+	}
+	sort.Slice(folds, func(i, j int) bool { return folds[i].Start < folds[j].Start })
+	return folds
+}
+
+// getIndent returns the number of columns `line` is indented, or -1 if it is blank.
+func getIndent(line []byte, tabSize int) int {
+	tabSize = Max(1, tabSize)
+	var indent int
+	for _, b := range line {
+		switch b {
+		case ' ':
+			indent++
+		case '\t':
+			indent += tabSize - indent%tabSize
+		case '\r', '\n':
+			return -1
+		default:
+			return indent
+		}
+	}
+	return -1
+}
@@ -0,0 +1,44 @@
+package buffer
+
+import (
+	"reflect"
+	"testing"
+)
+
+const foldText = "package main\n" +
+	"\n" +
+	"func main() {\n" +
+	"\tif x {\n" +
+	"\t\ty()\n" +
+	"\t}\n" +
+	"\ts := \"{\"\n" +
+	"}\n" +
+	"\n" +
+	"def f():\n" +
+	"    a\n" +
+	"\n" +
+	"    b\n" +
+	"c\n"
+
+func TestFindFolds(t *testing.T) {
+	var buf Buffer = NewRopeBuffer([]byte(foldText))
+
+	highlighter := NewTokenHighlighter(buf, TokenizeGo, nil)
+	highlighter.UpdateInvalidatedLines(0, buf.Lines()-1)
+	if folds := BracketFolds(buf, highlighter); !reflect.DeepEqual(folds, []FoldRange{{2, 6}, {3, 4}}) {
+		t.Errorf("Unexpected bracket folds %v", folds)
+	}
+	if folds := BracketFolds(buf, nil); !reflect.DeepEqual(folds, []FoldRange{{3, 4}}) { // The '{' in the string is counted
+		t.Errorf("Unexpected bracket folds without a Highlighter %v", folds)
+	}
+	if folds := IndentFolds(buf, 4); !reflect.DeepEqual(folds, []FoldRange{{2, 6}, {3, 4}, {9, 12}}) {
+		t.Errorf("Unexpected indent folds %v", folds)
+	}
+	if folds := FindFolds(buf, highlighter, 4); !reflect.DeepEqual(folds, []FoldRange{{2, 6}, {3, 4}, {9, 12}}) {
+		t.Errorf("Unexpected folds %v", folds)
+	}
+
+	if !(FoldRange{2, 6}).Contains(2) || (FoldRange{2, 6}).Contains(7) {
+		t.Errorf("Contains includes the wrong lines")
+	}
+}
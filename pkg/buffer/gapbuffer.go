@@ -0,0 +1,127 @@
+package buffer
+
+// gapBufferMinGap is the smallest gap a GapBuffer leaves for insertions when it
+// allocates a new array.
+const gapBufferMinGap = 64
+
+// A GapBuffer stores its text in one array, with a gap of unused bytes at the
+// position of the last edit. Typing only fills the gap, and moving to another
+// position moves the bytes between it and the gap. Reading is as fast as reading
+// a slice, so it suits small and medium files that are edited in one place at a
+// time.
+type GapBuffer struct {
+	data     []byte
+	gapStart int
+	gapEnd   int
+
+	// When shared, data is used by a Snapshot, so it is copied before it is edited.
+	shared   bool
+	readOnly bool // Whether this is a Snapshot
+
+	listeners
+	chunkedText
+}
+
+func NewGapBuffer(contents []byte) *GapBuffer {
+	data := make([]byte, len(contents)+gapBufferMinGap)
+	copy(data, contents)
+	b := &GapBuffer{data: data, gapStart: len(contents), gapEnd: len(data)}
+	b.src = b
+	return b
+}
+
+// Snapshot returns a read-only view of the buffer as it is now, which is safe to
+// read from other goroutines while the buffer is edited. The Snapshot shares the
+// array of the buffer until the next edit, which copies it.
+func (b *GapBuffer) Snapshot() Buffer {
+	if b.readOnly {
+		return b
+	}
+	b.shared = true
+	snapshot := &GapBuffer{data: b.data, gapStart: b.gapStart, gapEnd: b.gapEnd, readOnly: true}
+	snapshot.src = snapshot
+	return snapshot
+}
+
+// Len returns the number of bytes in the buffer.
+func (b *GapBuffer) Len() int {
+	return len(b.data) - (b.gapEnd - b.gapStart)
+}
+
+func (b *GapBuffer) eachChunk(f func(chunk []byte) bool) {
+	if !f(b.data[:b.gapStart]) {
+		f(b.data[b.gapEnd:])
+	}
+}
+
+// beginEdit must be called before the array is edited.
+func (b *GapBuffer) beginEdit() {
+	if b.readOnly {
+		panic("buffer: cannot edit a Snapshot")
+	}
+	if b.shared {
+		b.realloc(b.gapEnd - b.gapStart)
+		b.shared = false
+	}
+}
+
+// realloc copies the text into a new array with a gap of `gap` bytes.
+func (b *GapBuffer) realloc(gap int) {
+	after := len(b.data) - b.gapEnd
+	data := make([]byte, b.gapStart+gap+after)
+	copy(data, b.data[:b.gapStart])
+	copy(data[b.gapStart+gap:], b.data[b.gapEnd:])
+	b.data, b.gapEnd = data, b.gapStart+gap
+}
+
+// moveGap moves the gap to begin at byte position `pos`.
+func (b *GapBuffer) moveGap(pos int) {
+	if pos < b.gapStart {
+		n := b.gapStart - pos
+		copy(b.data[b.gapEnd-n:b.gapEnd], b.data[pos:b.gapStart])
+		b.gapStart, b.gapEnd = pos, b.gapEnd-n
+	} else if pos > b.gapStart {
+		n := pos - b.gapStart
+		copy(b.data[b.gapStart:], b.data[b.gapEnd:b.gapEnd+n])
+		b.gapStart, b.gapEnd = pos, b.gapEnd+n
+	}
+}
+
+// Insert copies a byte slice (inserting it) into the position at line, col.
+func (b *GapBuffer) Insert(line, col int, value []byte) {
+	b.beginEdit()
+	pos := b.LineColToPos(line, col)
+
+	if gap := b.gapEnd - b.gapStart; gap < len(value) {
+		// Grow in proportion to the text, so repeated insertions are amortized
+		b.realloc(len(value) + Max(gapBufferMinGap, b.Len()))
+	}
+	b.moveGap(pos)
+	copy(b.data[b.gapStart:], value)
+	b.gapStart += len(value)
+
+	value = append([]byte(nil), value...) // Keep our own copy for Observers
+	b.shiftAnchorsInserted(line, col, value)
+	b.notify(newChange(false, line, col, pos, value))
+}
+
+// Remove deletes any characters between startLine, startCol, and endLine,
+// endCol, inclusive bounds.
+func (b *GapBuffer) Remove(startLine, startCol, endLine, endCol int) {
+	start := b.LineColToPos(startLine, startCol)
+	end := b.runeEndPos(b.LineColToPos(endLine, endCol))
+
+	if start >= end {
+		return
+	}
+
+	removed := b.slice(start, end) // A copy
+	nl, lastRunes := measureText(removed)
+
+	b.beginEdit()
+	b.moveGap(start)
+	b.gapEnd += end - start
+
+	b.shiftAnchorsRemoved(startLine, startCol, nl, lastRunes)
+	b.notify(newChange(true, startLine, startCol, start, removed))
+}
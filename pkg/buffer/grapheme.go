@@ -0,0 +1,39 @@
+package buffer
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+// graphemeStarts returns the column of the first rune of each grapheme cluster
+// of the line, not including the line delimiter, followed by the number of runes
+// in the line. A grapheme cluster is what a user sees as one character, like a
+// letter with combining accents, an emoji sequence, or a flag.
+func (c Cursor) graphemeStarts(line int) []int {
+	text := strings.TrimSuffix(strings.TrimSuffix(string((*c.buffer).Line(line)), "\n"), "\r")
+	var starts []int
+	var col int
+	state := -1
+	for len(text) > 0 {
+		var cluster string
+		cluster, text, _, state = uniseg.FirstGraphemeClusterInString(text, state)
+		starts = append(starts, col)
+		col += utf8.RuneCountInString(cluster)
+	}
+	return append(starts, col)
+}
+
+// snapToGrapheme moves the column of the Cursor back to the start of the grapheme
+// cluster it is within.
+func (c Cursor) snapToGrapheme() Cursor {
+	starts := c.graphemeStarts(c.line)
+	for i := len(starts) - 1; i >= 0; i-- {
+		if starts[i] <= c.col {
+			c.col = starts[i]
+			break
+		}
+	}
+	return c
+}
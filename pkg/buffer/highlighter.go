@@ -1,8 +1,7 @@
 package buffer
 
 import (
-	"regexp"
-	"sort"
+	"math"
 
 	"github.com/gdamore/tcell/v2"
 )
@@ -26,14 +25,6 @@ func (c *Colorscheme) GetStyle(s Syntax) tcell.Style {
 	return tcell.StyleDefault // No value for Default; use default style.
 }
 
-type RegexpRegion struct {
-	Start    *regexp.Regexp
-	End      *regexp.Regexp   // Should be "$" by default
-	Skip     *regexp.Regexp   // Optional
-	Error    *regexp.Regexp   // Optional
-	Specials []*regexp.Regexp // Optional (nil or zero len)
-}
-
 type Match struct {
 	Col     int
 	EndLine int // Inclusive
@@ -48,146 +39,51 @@ func (c ByCol) Len() int           { return len(c) }
 func (c ByCol) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
 func (c ByCol) Less(i, j int) bool { return c[i].Col < c[j].Col }
 
-// A Highlighter can answer how to color any part of a provided Buffer. It does so
-// by applying regular expressions over a region of the buffer.
-type Highlighter struct {
-	Buffer      Buffer
-	Language    *Language
-	Colorscheme *Colorscheme
-
-	lineMatches [][]Match
-}
-
-func NewHighlighter(buffer Buffer, lang *Language, colorscheme *Colorscheme) *Highlighter {
-	return &Highlighter{
-		buffer,
-		lang,
-		colorscheme,
-		make([][]Match, buffer.Lines()),
-	}
-}
-
-func (h *Highlighter) expandToBufferLines() {
-	if lines := h.Buffer.Lines(); len(h.lineMatches) < lines {
-		h.lineMatches = append(h.lineMatches, make([][]Match, lines-len(h.lineMatches))...) // Extend from Slice Tricks
-	}
-}
-
-// UpdateLines forces the highlighting matches for lines between startLine to
-// endLine, inclusively, to be updated. It is more efficient to mark lines as
-// invalidated when changes occur and call UpdateInvalidatedLines(...).
-func (h *Highlighter) UpdateLines(startLine, endLine int) {
-	h.expandToBufferLines()
-	h.updateLines(startLine, endLine)
-}
-
-func (h *Highlighter) updateLines(startLine, endLine int) {
-	for i := startLine; i <= endLine && i < len(h.lineMatches); i++ {
-		if h.lineMatches[i] != nil {
-			h.lineMatches[i] = h.lineMatches[i][:0] // Shrink slice to zero (hopefully save allocs)
-		}
-	}
-
-	// If the rule k does not have an End, then it can be optimized that we search from the start
-	// of view until the end of view. For any k that has an End, we search for ends from start
-	// of view, backtracking when one is found, to fulfill a multiline highlight.
-
-	endLine, endCol := h.Buffer.ClampLineCol(endLine, (h.Buffer).RunesInLineWithDelim(endLine)-1)
-	startPos := h.Buffer.LineColToPos(startLine, 0)
-	bytes := h.Buffer.Slice(startLine, 0, endLine, endCol)
-
-	for k, v := range h.Language.Rules {
-		var indexes [][]int                        // [][2]int
-		if k.End != nil && k.End.String() != "$" { // If this range might be a multiline range...
-			endIndexes := k.End.FindAllIndex(bytes, -1)     // Attempt to find every ending match
-			startIndexes := k.Start.FindAllIndex(bytes, -1) // Attempt to find every starting match
-			// ...
-			_ = endIndexes
-			_ = startIndexes
-		} else { // A standard single-line match
-			indexes = k.Start.FindAllIndex(bytes, -1) // Attempt to find the start match
-		}
-
-		for i := range indexes {
-			startLine, startCol := h.Buffer.PosToLineCol(indexes[i][0] + startPos)
-			endLine, endCol := h.Buffer.PosToLineCol(indexes[i][1] - 1 + startPos)
-
-			match := Match{startCol, endLine, endCol, v}
-
-			h.lineMatches[startLine] = append(h.lineMatches[startLine], match) // Unsorted
-		}
-	}
-
-	h.validateLines(startLine, endLine) // Marks any "unvalidated" or nil lines as valued
-}
-
-// UpdateInvalidatedLines only updates the highlighting for lines that are invalidated
-// between lines startLine and endLine, inclusively.
-func (h *Highlighter) UpdateInvalidatedLines(startLine, endLine int) {
-	h.expandToBufferLines()
-
-	// Move startLine to first line with invalidated changes
-	for startLine <= endLine && startLine < len(h.lineMatches)-1 {
-		if h.lineMatches[startLine] == nil {
-			break
-		}
-		startLine++
-	}
-
-	// Keep endLine clamped
-	if endLine >= len(h.lineMatches) {
-		endLine = len(h.lineMatches) - 1
-	}
-
-	// Move endLine back to first line at or before endLine with invalidated changes
-	for endLine >= startLine && endLine > 0 {
-		if h.lineMatches[endLine] == nil {
-			break
-		}
-		endLine--
-	}
-
-	if startLine > endLine {
-		return // Do nothing; no invalidated lines
-	}
-
-	h.updateLines(startLine, endLine)
-}
-
-func (h *Highlighter) HasInvalidatedLines(startLine, endLine int) bool {
-	h.expandToBufferLines()
-	for i := startLine; i <= endLine && i < len(h.lineMatches); i++ {
-		if h.lineMatches[i] == nil {
-			return true
-		}
-	}
-	return false
+// A Highlighter can answer how to color any part of a provided Buffer. Lines
+// that are edited are invalidated, and highlighted again when they are updated.
+// Implementations can be chosen per Language with NewHighlighter. A Highlighter
+// is an Observer, which invalidates the lines of each Change when it is registered
+// with its Buffer.
+type Highlighter interface {
+	Observer
+
+	// InvalidateLines marks the lines from startLine to endLine, inclusively, as
+	// needing to be highlighted again. Lines beyond the end of the buffer are
+	// ignored.
+	InvalidateLines(startLine, endLine int)
+
+	// UpdateInvalidatedLines highlights any invalidated lines between startLine and
+	// endLine, inclusively. A Highlighter may update more lines than requested.
+	UpdateInvalidatedLines(startLine, endLine int)
+
+	// GetLineMatches returns the Matches starting on `line`, sorted by column. The
+	// Matches are only current if the line has been updated since it was last
+	// invalidated. Returns nil if the line is out of range. Do not modify the
+	// returned slice.
+	GetLineMatches(line int) []Match
+
+	// GetStyle returns the style of a Match, which is its Syntax in the Colorscheme.
+	GetStyle(match Match) tcell.Style
 }
 
-func (h *Highlighter) validateLines(startLine, endLine int) {
-	for i := startLine; i <= endLine && i < len(h.lineMatches); i++ {
-		if h.lineMatches[i] == nil {
-			h.lineMatches[i] = make([]Match, 0)
-		}
-	}
-}
-
-func (h *Highlighter) InvalidateLines(startLine, endLine int) {
-	h.expandToBufferLines()
-	for i := startLine; i <= endLine && i < len(h.lineMatches); i++ {
-		h.lineMatches[i] = nil
+// NewHighlighter returns the Highlighter for the Language: the one created by its
+// NewHighlighter function, or a TokenHighlighter if it has a Tokenizer, otherwise
+// a RegexpHighlighter of its Rules.
+func NewHighlighter(buffer Buffer, lang *Language, colorscheme *Colorscheme) Highlighter {
+	if lang.NewHighlighter != nil {
+		return lang.NewHighlighter(buffer, colorscheme)
+	} else if lang.Tokenizer != nil {
+		return NewTokenHighlighter(buffer, lang.Tokenizer, colorscheme)
 	}
+	return NewRegexpHighlighter(buffer, lang, colorscheme)
 }
 
-func (h *Highlighter) GetLineMatches(line int) []Match {
-	if line < 0 || line >= len(h.lineMatches) {
-		return nil
+// invalidateChange invalidates the lines of the Change, and every line after them
+// if lines were added or removed, because they have moved.
+func invalidateChange(h Highlighter, change Change) {
+	if change.LinesChanged() {
+		h.InvalidateLines(change.StartLine, math.MaxInt32)
+	} else {
+		h.InvalidateLines(change.StartLine, change.StartLine)
 	}
-	data := h.lineMatches[line]
-	sort.Sort(ByCol(data))
-	return data
-}
-
-func (h *Highlighter) GetStyle(match Match) tcell.Style {
-	return h.Colorscheme.GetStyle(match.Syntax)
 }
@@ -0,0 +1,213 @@
+package buffer
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// highlighterImpls are the Highlighters tested by the conformance tests below.
+// Every implementation of Highlighter should be added here.
+var highlighterImpls = map[string]func(buf Buffer, colorscheme *Colorscheme) Highlighter{
+	"Regexp": func(buf Buffer, colorscheme *Colorscheme) Highlighter {
+		lang := &Language{Name: "Test", Rules: map[*RegexpRegion]Syntax{
+			{Start: regexp.MustCompile(`\b(package|func|var)\b`)}: Keyword,
+		}}
+		return NewRegexpHighlighter(buf, lang, colorscheme)
+	},
+	"Token": func(buf Buffer, colorscheme *Colorscheme) Highlighter {
+		return NewTokenHighlighter(buf, TokenizeGo, colorscheme)
+	},
+	"Async": func(buf Buffer, colorscheme *Colorscheme) Highlighter {
+		results := make(chan *HighlightResult, 100)
+		h := NewAsyncHighlighter(buf, Go, colorscheme, func(r *HighlightResult) bool { results <- r; return true })
+		return &waitingHighlighter{h, results}
+	},
+}
+
+// A waitingHighlighter makes an AsyncHighlighter synchronous, for the tests, by
+// waiting for the lines to be highlighted in UpdateInvalidatedLines.
+type waitingHighlighter struct {
+	*AsyncHighlighter
+	results chan *HighlightResult
+}
+
+func (w *waitingHighlighter) UpdateInvalidatedLines(startLine, endLine int) {
+	w.AsyncHighlighter.UpdateInvalidatedLines(startLine, endLine)
+	for w.hasInvalid(startLine, endLine) {
+		w.ApplyResult(<-w.results)
+	}
+}
+
+var testColorscheme = &Colorscheme{
+	Default: tcell.Style{}.Foreground(tcell.ColorWhite),
+	Keyword: tcell.Style{}.Foreground(tcell.ColorBlue),
+}
+
+// findMatch returns the Match with the Syntax starting at `col` of `line`.
+func findMatch(h Highlighter, line, col int, syntax Syntax) (Match, bool) {
+	for _, m := range h.GetLineMatches(line) {
+		if m.Col == col && m.Syntax == syntax {
+			return m, true
+		}
+	}
+	return Match{}, false
+}
+
+func TestHighlighterConformance(t *testing.T) {
+	for name, newHighlighter := range highlighterImpls {
+		t.Run(name+"/Matches", func(t *testing.T) {
+			buf := NewRopeBuffer([]byte("package main\n\nfunc f() { var x int }\n"))
+			h := newHighlighter(buf, testColorscheme)
+			h.UpdateInvalidatedLines(0, buf.Lines()-1)
+
+			if m, ok := findMatch(h, 2, 0, Keyword); !ok || m.EndLine != 2 || m.EndCol != 3 {
+				t.Errorf("expected a Keyword Match of `func` at 2:0-3, got %v in %v", m, h.GetLineMatches(2))
+			}
+			if _, ok := findMatch(h, 2, 11, Keyword); !ok {
+				t.Errorf("expected a Keyword Match of `var` at 2:11, got %v", h.GetLineMatches(2))
+			}
+			if matches := h.GetLineMatches(1); len(matches) != 0 {
+				t.Errorf("expected no Matches on an empty line, got %v", matches)
+			}
+			for line := 0; line < buf.Lines(); line++ {
+				matches := h.GetLineMatches(line)
+				if !sort.SliceIsSorted(matches, func(i, j int) bool { return matches[i].Col < matches[j].Col }) {
+					t.Errorf("expected Matches of line %d sorted by column, got %v", line, matches)
+				}
+			}
+		})
+
+		t.Run(name+"/OutOfRange", func(t *testing.T) {
+			buf := NewRopeBuffer([]byte("package main\nfunc f() {}\nvar x int\n"))
+			h := newHighlighter(buf, testColorscheme)
+			h.UpdateInvalidatedLines(0, buf.Lines()-1)
+
+			buf.Remove(0, 0, 1, 11) // The last two lines are now out of range
+			h.InvalidateLines(0, 0)
+			h.UpdateInvalidatedLines(0, buf.Lines()-1)
+
+			if matches := h.GetLineMatches(-1); matches != nil {
+				t.Errorf("expected nil for line -1, got %v", matches)
+			}
+			if matches := h.GetLineMatches(buf.Lines()); matches != nil {
+				t.Errorf("expected nil for the line after the buffer, got %v", matches)
+			}
+			h.InvalidateLines(0, buf.Lines()+10) // Must not panic
+		})
+
+		t.Run(name+"/Invalidate", func(t *testing.T) {
+			buf := NewRopeBuffer([]byte("package main\nfunc f() {}\n"))
+			h := newHighlighter(buf, testColorscheme)
+			h.UpdateInvalidatedLines(0, buf.Lines()-1)
+
+			buf.RegisterObserver(h)
+			buf.Insert(1, 0, []byte("var x int\n")) // Invalidates the lines as an Observer
+			h.UpdateInvalidatedLines(0, buf.Lines()-1)
+
+			if _, ok := findMatch(h, 1, 0, Keyword); !ok {
+				t.Errorf("expected a Keyword Match of the inserted `var` at 1:0, got %v", h.GetLineMatches(1))
+			}
+			if _, ok := findMatch(h, 2, 0, Keyword); !ok {
+				t.Errorf("expected the Keyword Match of `func` to move to line 2, got %v", h.GetLineMatches(2))
+			}
+		})
+
+		t.Run(name+"/GetStyle", func(t *testing.T) {
+			h := newHighlighter(NewRopeBuffer([]byte{}), testColorscheme)
+			if style := h.GetStyle(Match{Syntax: Keyword}); style != (*testColorscheme)[Keyword] {
+				t.Errorf("expected the Keyword style, got %v", style)
+			}
+			if style := h.GetStyle(Match{Syntax: Number}); style != (*testColorscheme)[Default] {
+				t.Errorf("expected the Default style for a Syntax not in the Colorscheme, got %v", style)
+			}
+		})
+	}
+}
+
+func TestNewHighlighter(t *testing.T) {
+	buf := NewRopeBuffer([]byte{})
+	if _, ok := NewHighlighter(buf, Go, testColorscheme).(*TokenHighlighter); !ok {
+		t.Error("expected a TokenHighlighter for a Language with a Tokenizer")
+	}
+	if _, ok := NewHighlighter(buf, PlainText, testColorscheme).(*RegexpHighlighter); !ok {
+		t.Error("expected a RegexpHighlighter for a Language with only Rules")
+	}
+
+	custom := NewRegexpHighlighter(buf, PlainText, testColorscheme)
+	lang := &Language{Tokenizer: TokenizeGo, NewHighlighter: func(Buffer, *Colorscheme) Highlighter { return custom }}
+	if h := NewHighlighter(buf, lang, testColorscheme); h != custom {
+		t.Error("expected the Highlighter from the NewHighlighter function of the Language")
+	}
+}
+
+func TestAsyncHighlighterStale(t *testing.T) {
+	buf := NewRopeBuffer([]byte("package main\n"))
+	results := make(chan *HighlightResult, 100)
+	h := NewAsyncHighlighter(buf, Go, testColorscheme, func(r *HighlightResult) bool { results <- r; return true })
+	h.UpdateInvalidatedLines(0, 0)
+	result := <-results
+
+	buf.Insert(0, 0, []byte("// "))
+	h.InvalidateLines(0, 0)
+	if h.ApplyResult(result) {
+		t.Error("expected a result from before the buffer was changed to not be applied")
+	}
+
+	h.UpdateInvalidatedLines(0, 0)
+	for h.hasInvalid(0, 0) { // Earlier results of the first job are skipped
+		h.ApplyResult(<-results)
+	}
+	if _, ok := findMatch(h, 0, 0, Comment); !ok {
+		t.Errorf("expected a Comment Match at 0:0, got %v", h.GetLineMatches(0))
+	}
+}
+
+func TestAsyncHighlighterClose(t *testing.T) {
+	buf := NewRopeBuffer(bytes.Repeat([]byte("package main\n"), 3*asyncChunkLines))
+	results := make(chan *HighlightResult, 100)
+	closed := make(chan struct{})
+	h := NewAsyncHighlighter(buf, Go, testColorscheme, func(r *HighlightResult) bool {
+		results <- r
+		<-closed // Keep the job running until it is closed
+		return true
+	})
+	h.UpdateInvalidatedLines(0, 0)
+	<-results // The visible lines
+
+	h.Close()
+	close(closed)
+	h.UpdateInvalidatedLines(0, 0)
+	select {
+	case r := <-results:
+		t.Errorf("Expected no more results after Close, got lines from %d", r.startLine)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestAsyncHighlighterDropped(t *testing.T) {
+	buf := NewRopeBuffer([]byte("package main\n"))
+	results := make(chan *HighlightResult, 100)
+	deliver := false
+	h := NewAsyncHighlighter(buf, Go, testColorscheme, func(r *HighlightResult) bool {
+		if deliver {
+			results <- r
+		}
+		return deliver
+	})
+	h.UpdateInvalidatedLines(0, 0)
+	for !h.dropped.Load() { // Wait for the job to give up
+		time.Sleep(time.Millisecond)
+	}
+
+	deliver = true
+	h.UpdateInvalidatedLines(0, 0) // The same lines are highlighted again
+	h.ApplyResult(<-results)
+	if _, ok := findMatch(h, 0, 0, Keyword); !ok {
+		t.Errorf("Expected a Keyword Match at 0:0, got %v", h.GetLineMatches(0))
+	}
+}
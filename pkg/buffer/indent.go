@@ -0,0 +1,58 @@
+package buffer
+
+import "bytes"
+
+// indentSizes are the indentation widths that DetectIndentation can infer for
+// files indented with spaces.
+var indentSizes = []int{2, 3, 4, 8}
+
+// DetectIndentation infers whether `contents` is indented with tabs or spaces, and
+// how many spaces make one level of indentation. It returns false if the contents
+// are not indented enough to tell, in which case defaults should be used. A tab
+// size of 0 is returned for contents indented with tabs, where it can't be known.
+func DetectIndentation(contents []byte) (hardTabs bool, tabSize int, ok bool) {
+	var tabLines, spaceLines int
+	deltas := make(map[int]int) // Counts of the changes in spaces between lines
+	prevSpaces := 0
+	for len(contents) > 0 {
+		var line []byte
+		if i := bytes.IndexByte(contents, '\n'); i >= 0 {
+			line, contents = contents[:i], contents[i+1:]
+		} else {
+			line, contents = contents, nil
+		}
+		text := bytes.TrimLeft(line, " \t")
+		if len(bytes.TrimSpace(text)) == 0 {
+			continue // Blank lines say nothing about indentation
+		}
+
+		if line[0] == '\t' {
+			tabLines++
+			continue
+		}
+		spaces := len(line) - len(bytes.TrimLeft(line, " "))
+		if text[0] == '*' {
+			continue // The continuation of a block comment, aligned by one space
+		}
+		if spaces > 0 {
+			spaceLines++
+		}
+		if delta := spaces - prevSpaces; delta > 0 {
+			deltas[delta]++
+		}
+		prevSpaces = spaces
+	}
+
+	if tabLines == 0 && spaceLines == 0 {
+		return false, 0, false
+	}
+	if tabLines >= spaceLines {
+		return true, 0, true
+	}
+	for _, size := range indentSizes {
+		if deltas[size] > deltas[tabSize] {
+			tabSize = size
+		}
+	}
+	return false, tabSize, tabSize > 0
+}
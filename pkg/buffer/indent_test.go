@@ -0,0 +1,25 @@
+package buffer
+
+import "testing"
+
+func TestDetectIndentation(t *testing.T) {
+	tests := []struct {
+		contents string
+		hardTabs bool
+		tabSize  int
+		ok       bool
+	}{
+		{"a\nb\n", false, 0, false},
+		{"func f() {\n\tif x {\n\t\ty()\n\t}\n}\n", true, 0, true},
+		{"a:\n  b:\n    c: 1\n  d: 2\n", false, 2, true},
+		{"def f():\n    if x:\n        y()\n    z()\n", false, 4, true},
+		{"/*\n * Comment\n */\nf() {\n\tx()\n}\n", true, 0, true},
+		{"a\n  b\n  c\n\td\n", false, 2, true},
+	}
+	for i, test := range tests {
+		hardTabs, tabSize, ok := DetectIndentation([]byte(test.contents))
+		if hardTabs != test.hardTabs || tabSize != test.tabSize || ok != test.ok {
+			t.Errorf("Test %d: got (%v, %d, %v), expected (%v, %d, %v)", i, hardTabs, tabSize, ok, test.hardTabs, test.tabSize, test.ok)
+		}
+	}
+}
@@ -1,5 +1,14 @@
 package buffer
 
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
 type Syntax uint8
 
 const (
@@ -19,5 +28,107 @@ type Language struct {
 	Name      string
 	Filetypes []string // .go, .c, etc.
 	Rules     map[*RegexpRegion]Syntax
+	Tokenizer Tokenizer // Used for highlighting instead of the Rules, if not nil
+
+	// NewHighlighter creates a custom Highlighter for the Language, such as one
+	// using semantic tokens from a language server. It is preferred over the
+	// Tokenizer and Rules when not nil.
+	NewHighlighter func(buffer Buffer, colorscheme *Colorscheme) Highlighter
+
+	// Formatter formats source code in process. It is preferred over FormatCommand.
+	Formatter func(src []byte) ([]byte, error)
+	// FormatCommand is an external formatter, which is given the source code on
+	// standard input, and writes the formatted code to standard output.
+	FormatCommand []string
+
+	// Folds finds the ranges of lines which can be folded. When nil, FindFolds is
+	// used, which finds them from brackets and indentation.
+	Folds func(buffer Buffer, highlighter Highlighter, tabSize int) []FoldRange
+
+	// IndentAfter matches the text of a line before a new line is begun, when the
+	// new line is indented one more level than it, like after an opening bracket.
+	IndentAfter *regexp.Regexp
+	// DedentOn matches a line which is indented one level less than the line
+	// before it, like a line beginning with a closing bracket.
+	DedentOn *regexp.Regexp
+
+	// LineComment begins a comment which ends with the line, like "//".
+	LineComment string
+	// BlockComment begins and ends a comment which can span lines, like "/*" and
+	// "*/". Lines are commented with it when the Language has no LineComment.
+	BlockComment [2]string
+
+	// UseSpaces is true if files of the Language are indented with spaces rather
+	// than tabs, when it can't be told from their contents.
+	UseSpaces bool
+	// TabSize is the width of one level of indentation, when it can't be told from
+	// the contents of a file. The editor's default is used when it is zero.
+	TabSize int
 	// TODO: add other language details
 }
+
+// CanFormat returns true if the Language has a Formatter or FormatCommand.
+func (l *Language) CanFormat() bool {
+	return l.Formatter != nil || len(l.FormatCommand) > 0
+}
+
+// Format returns the formatted `src`, using the Formatter or FormatCommand of
+// the Language. If the Language cannot format, `src` is returned unchanged.
+func (l *Language) Format(src []byte) ([]byte, error) {
+	if l.Formatter != nil {
+		return l.Formatter(src)
+	} else if len(l.FormatCommand) == 0 {
+		return src, nil
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(l.FormatCommand[0], l.FormatCommand[1:]...)
+	cmd.Stdin = bytes.NewReader(src)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %v\n%s", l.FormatCommand[0], err, msg)
+		}
+		return nil, fmt.Errorf("%s: %v", l.FormatCommand[0], err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// GetFolds returns the ranges of lines of the buffer which can be folded, from
+// the Folds of the Language, if it has them, or otherwise FindFolds.
+func (l *Language) GetFolds(buffer Buffer, highlighter Highlighter, tabSize int) []FoldRange {
+	if l.Folds != nil {
+		return l.Folds(buffer, highlighter, tabSize)
+	}
+	return FindFolds(buffer, highlighter, tabSize)
+}
+
+// IndentsAfter returns whether a new line begun after `text` is indented one more
+// level than the line of `text`, according to the IndentAfter of the Language.
+func (l *Language) IndentsAfter(text string) bool {
+	return l.IndentAfter != nil && l.IndentAfter.MatchString(text)
+}
+
+// DedentsLine returns whether the line `text` is indented one level less than
+// the line before it, according to the DedentOn of the Language.
+func (l *Language) DedentsLine(text string) bool {
+	return l.DedentOn != nil && l.DedentOn.MatchString(text)
+}
+
+// LanguageByFilename returns the Language from Languages with a Filetype matching
+// the extension of `path`, or PlainText if there is none.
+func LanguageByFilename(path string) *Language {
+	ext := filepath.Ext(path)
+	if ext == "" {
+		return PlainText
+	}
+	for _, lang := range Languages {
+		for _, filetype := range lang.Filetypes {
+			if strings.EqualFold(filetype, ext) {
+				return lang
+			}
+		}
+	}
+	return PlainText
+}
@@ -0,0 +1,61 @@
+package buffer
+
+import "testing"
+
+func TestLanguageByFilename(t *testing.T) {
+	if lang := LanguageByFilename("cmd/main.go"); lang != Go {
+		t.Errorf("expected Go for a .go file, got %v", lang.Name)
+	}
+	if lang := LanguageByFilename("README"); lang != PlainText {
+		t.Errorf("expected PlainText for a file without extension, got %v", lang.Name)
+	}
+	if lang := LanguageByFilename("notes.txt"); lang != PlainText {
+		t.Errorf("expected PlainText for an unknown filetype, got %v", lang.Name)
+	}
+}
+
+func TestLanguageFormat(t *testing.T) {
+	formatted, err := Go.Format([]byte("package main\nfunc  main( ) {\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "package main\n\nfunc main() {\n}\n"; string(formatted) != expected {
+		t.Errorf("expected %q, got %q", expected, formatted)
+	}
+
+	if _, err := Go.Format([]byte("package main\nfunc {")); err == nil {
+		t.Error("expected an error formatting invalid code")
+	}
+
+	cat := &Language{Name: "Cat", FormatCommand: []string{"cat"}}
+	if formatted, err := cat.Format([]byte("unchanged\n")); err != nil || string(formatted) != "unchanged\n" {
+		t.Errorf("expected the external formatter output, got %q, %v", formatted, err)
+	}
+}
+
+func TestLanguageIndentation(t *testing.T) {
+	for _, text := range []string{"func main() {", "\tx := f(", "\tcase 1, 2:", "\tdefault: // comment", "a := []int{ "} {
+		if !Go.IndentsAfter(text) {
+			t.Errorf("expected a new line after %q to be indented", text)
+		}
+	}
+	for _, text := range []string{"\tx := f()", "}", "\tcase 1: x()", "// {"} {
+		if Go.IndentsAfter(text) {
+			t.Errorf("expected a new line after %q not to be indented", text)
+		}
+	}
+
+	for _, text := range []string{"\t}", "})", "\tcase ", "\tdefault:"} {
+		if !Go.DedentsLine(text) {
+			t.Errorf("expected %q to be dedented", text)
+		}
+	}
+	for _, text := range []string{"\tx()", "\tcases", "\tdefault"} {
+		if Go.DedentsLine(text) {
+			t.Errorf("expected %q not to be dedented", text)
+		}
+	}
+	if PlainText.IndentsAfter("{") || PlainText.DedentsLine("}") {
+		t.Error("expected no indentation rules for PlainText")
+	}
+}
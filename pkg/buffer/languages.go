@@ -0,0 +1,71 @@
+package buffer
+
+import (
+	"go/format"
+	"regexp"
+)
+
+// Languages are the languages known to the editor, which LanguageByFilename
+// chooses from. More can be added, or the built-in ones customized, at startup.
+var Languages = []*Language{Go, YAML}
+
+// PlainText is the Language of files with no known filetype.
+var PlainText = &Language{
+	Name:  "Plain Text",
+	Rules: map[*RegexpRegion]Syntax{},
+}
+
+var Go = &Language{
+	Name:      "Go",
+	Filetypes: []string{".go"},
+	Rules: map[*RegexpRegion]Syntax{
+		{Start: regexp.MustCompile(`\/\/.*`)}:          Comment,
+		{Start: regexp.MustCompile(`"(\\.|[^"\\])*"`)}: String,
+		{
+			Start: regexp.MustCompile(`\b(var|const|if|else|range|for|switch|fallthrough|case|default|break|continue|go|func|return|defer|import|type|package)\b`),
+		}: Keyword,
+		{
+			Start: regexp.MustCompile(`\b(u?int(8|16|32|64)?|rune|byte|string|bool|struct)\b`),
+		}: Type,
+		{
+			Start: regexp.MustCompile(`\b([1-9][0-9]*|0[0-7]*|0[Xx][0-9A-Fa-f]+|0[Bb][01]+)\b`),
+		}: Number,
+		{
+			Start: regexp.MustCompile(`\b(len|cap|panic|make|copy|append)\b`),
+		}: Builtin,
+		{
+			Start: regexp.MustCompile(`\b(nil|true|false)\b`),
+		}: Special,
+	},
+	Tokenizer: TokenizeGo,
+	Formatter: format.Source, // gofmt
+
+	IndentAfter: regexp.MustCompile(`^(([^/]|/[^/])*[{(\[]|\s*(case\s.*|default\s*):)\s*(//.*)?$`),
+	DedentOn:    regexp.MustCompile(`^\s*([})\]]|case\s|default\s*:)`),
+
+	LineComment:  "//",
+	BlockComment: [2]string{"/*", "*/"},
+}
+
+var YAML = &Language{
+	Name:      "YAML",
+	Filetypes: []string{".yaml", ".yml"},
+	Rules: map[*RegexpRegion]Syntax{
+		{Start: regexp.MustCompile(`#.*`)}:                               Comment,
+		{Start: regexp.MustCompile(`"(\\.|[^"\\])*"|'([^']|'')*'`)}:      String,
+		{Start: regexp.MustCompile(`(?m)^[ \t]*(- )?[\w.-]+:([ \t]|$)`)}: Keyword,
+		{
+			Start: regexp.MustCompile(`\b(-?[0-9]+(\.[0-9]+)?)\b`),
+		}: Number,
+		{
+			Start: regexp.MustCompile(`\b(true|false|null|yes|no|on|off)\b|~`),
+		}: Special,
+	},
+
+	IndentAfter: regexp.MustCompile(`:\s*(#.*)?$`),
+
+	LineComment: "#",
+
+	UseSpaces: true,
+	TabSize:   2,
+}
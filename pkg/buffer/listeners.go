@@ -0,0 +1,118 @@
+package buffer
+
+// listeners holds the Cursors and Observers registered with a Buffer, and moves
+// and notifies them as the Buffer is edited. Each Buffer implementation embeds
+// it, so they all move Cursors the same way.
+type listeners struct {
+	anchors   []*Cursor
+	observers []Observer
+}
+
+// measureText returns the number of line delimiters in `text`, and the number
+// of runes following the last delimiter (or in the whole text, if there are no
+// delimiters). Together, they describe how far `text` moves a line and column.
+func measureText(text []byte) (newlines, lastRunes int) {
+	for _, r := range string(text) {
+		if r == '\n' {
+			newlines++
+			lastRunes = 0
+		} else {
+			lastRunes++
+		}
+	}
+	return
+}
+
+// shiftAnchorsInserted moves every anchor at or after line, col to account for
+// the inserted `value`. Anchors exactly at the position of insertion are moved
+// to the end of the inserted text.
+func (l *listeners) shiftAnchorsInserted(line, col int, value []byte) {
+	newlines, lastRunes := measureText(value)
+	for _, v := range l.anchors {
+		if v.line > line {
+			v.line += newlines
+		} else if v.line == line && v.col >= col {
+			if newlines == 0 {
+				v.col += lastRunes
+			} else {
+				v.line += newlines
+				v.col = v.col - col + lastRunes
+			}
+		}
+	}
+}
+
+// shiftAnchorsRemoved moves anchors after a removed region back by the size of
+// the region, which started at startLine, startCol, and spanned `newlines` line
+// delimiters and `lastRunes` runes on its last line. Anchors within the region
+// are moved to its start.
+func (l *listeners) shiftAnchorsRemoved(startLine, startCol, newlines, lastRunes int) {
+	// Exclusive end of the removed region, as it was before the removal
+	endLine, endCol := startLine+newlines, lastRunes
+	if newlines == 0 {
+		endCol += startCol
+	}
+
+	for _, v := range l.anchors {
+		if v.line < startLine || (v.line == startLine && v.col < startCol) {
+			continue // Before the region
+		}
+
+		if v.line > endLine {
+			v.line -= newlines
+		} else if v.line == endLine && v.col >= endCol {
+			v.line, v.col = startLine, startCol+(v.col-endCol)
+		} else { // Within the region
+			v.line, v.col = startLine, startCol
+		}
+	}
+}
+
+// RegisterCursor adds the Cursor to a slice which the Buffer uses to update
+// each Cursor based on changes that occur in the Buffer. Various functions are
+// called on the Cursor depending upon where the edits occurred and how it should
+// modify the Cursor's position. Unregister a Cursor before deleting it from
+// memory, or forgetting it, with UnregisterPosition.
+func (l *listeners) RegisterCursor(cursor *Cursor) {
+	if cursor == nil {
+		return
+	}
+	l.anchors = append(l.anchors, cursor)
+}
+
+// UnregisterCursor will remove the cursor from the list of watched Cursors.
+// It is mandatory that a Cursor be unregistered before being freed from memory,
+// or otherwise being forgotten.
+func (l *listeners) UnregisterCursor(cursor *Cursor) {
+	for i, v := range l.anchors {
+		if cursor == v {
+			// Delete item at i without preserving order
+			l.anchors[i] = l.anchors[len(l.anchors)-1]
+			l.anchors[len(l.anchors)-1] = nil
+			l.anchors = l.anchors[:len(l.anchors)-1]
+		}
+	}
+}
+
+// RegisterObserver adds the Observer to be notified of every Change made to the
+// buffer, after it is made.
+func (l *listeners) RegisterObserver(observer Observer) {
+	l.observers = append(l.observers, observer)
+}
+
+// UnregisterObserver stops notifying the Observer of Changes.
+func (l *listeners) UnregisterObserver(observer Observer) {
+	for i, v := range l.observers {
+		if v == observer {
+			l.observers = append(l.observers[:i], l.observers[i+1:]...)
+			return
+		}
+	}
+}
+
+// notify gives the Change to each Observer, in the order they were registered.
+func (l *listeners) notify(change Change) {
+	for _, observer := range l.observers {
+		observer.OnChange(change)
+	}
+}
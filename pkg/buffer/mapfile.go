@@ -0,0 +1,78 @@
+package buffer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime/debug"
+)
+
+// ErrFileTruncated is returned by Guard when a mapped file has been truncated.
+var ErrFileTruncated = errors.New("the file was truncated by another program")
+
+// A MappedFile is the contents of a file, mapped into memory where the operating
+// system supports it, so that opening it takes no time, and pages of the file are
+// only read when they are accessed. Elsewhere, the whole file is read.
+//
+// The Data must not be modified, nor used after Close. If the file is truncated
+// by another program while it is mapped, reading past its new end faults, which
+// crashes the program unless the read is made within Guard. A PieceTable guards
+// every read of its original text, so it is safe to make one of the Data. The
+// file should be saved by replacing it, instead of writing over it.
+type MappedFile struct {
+	Data []byte
+
+	unmap func() error
+}
+
+// MapFile maps the file at `path` into memory.
+func MapFile(path string) (*MappedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() // The mapping remains valid after the file is closed
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := int(info.Size())
+	if int64(size) != info.Size() {
+		return nil, fmt.Errorf("%s is too large to be opened (%d bytes)", path, info.Size())
+	}
+
+	data, unmap, err := mapFile(f, size)
+	if err != nil {
+		return nil, err
+	}
+	return &MappedFile{Data: data, unmap: unmap}, nil
+}
+
+// Close unmaps the file.
+func (m *MappedFile) Close() error {
+	m.Data = nil
+	return m.unmap()
+}
+
+// Guard calls f, which reads the Data on the calling goroutine, and returns
+// ErrFileTruncated if a read faulted because the file was truncated, instead of
+// crashing. Long reads, like searching the file, should be guarded.
+func (m *MappedFile) Guard(f func()) error {
+	return guardFault(f)
+}
+
+// guardFault calls f, and returns ErrFileTruncated if it faults reading memory.
+func guardFault(f func()) (err error) {
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(interface{ Addr() uintptr }); !ok { // Not a fault
+				panic(r)
+			}
+			err = ErrFileTruncated
+		}
+	}()
+	f()
+	return nil
+}
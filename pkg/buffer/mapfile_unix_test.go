@@ -0,0 +1,67 @@
+//go:build unix
+
+package buffer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMapFileTruncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, bytes.Repeat([]byte("x"), 1<<16), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := MapFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var b byte
+	err = file.Guard(func() { b = file.Data[len(file.Data)-1] })
+	if !errors.Is(err, ErrFileTruncated) {
+		t.Errorf("Expected ErrFileTruncated reading past the end of the file, got %v (read %q)", err, b)
+	}
+}
+
+func TestPieceTableTruncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, bytes.Repeat([]byte("line\n"), pieceTablePageSize/2), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := MapFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	buf := NewPieceTable(file.Data)
+	if str := string(buf.Line(1)); str != "line\n" {
+		t.Fatalf("Expected the second line, got %q", str)
+	}
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// None of these may crash
+	buf.Lines()
+	line := pieceTablePageSize / 4
+	if str := buf.Line(line); len(str) != 0 {
+		t.Errorf("Expected nothing read past the end of the file, got %q", str)
+	}
+	buf.LineColToPos(line, 2)
+	buf.Insert(0, 0, []byte("x"))
+	if _, err := buf.WriteTo(io.Discard); !errors.Is(err, ErrFileTruncated) {
+		t.Errorf("Expected ErrFileTruncated writing the buffer, got %v", err)
+	}
+	if err := buf.Err(); !errors.Is(err, ErrFileTruncated) {
+		t.Errorf("Expected ErrFileTruncated, got %v", err)
+	}
+}
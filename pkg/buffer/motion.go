@@ -0,0 +1,258 @@
+package buffer
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// A runeWalker steps through the runes of a buffer from a line and column, in
+// either direction, reading a line at a time. The line delimiter is seen as one
+// '\n' rune, at the column after the last rune of the line, like a Cursor sees it.
+type runeWalker struct {
+	buffer    *Buffer
+	line, col int
+	runes     []rune // Runes of the line, with '\n' unless it is the last line
+}
+
+func newRuneWalker(c Cursor) *runeWalker {
+	w := &runeWalker{buffer: c.buffer, line: c.line}
+	w.load()
+	w.col = Min(c.col, len(w.runes))
+	return w
+}
+
+// load reads the runes of the current line.
+func (w *runeWalker) load() {
+	line := strings.TrimSuffix(strings.TrimSuffix(string((*w.buffer).Line(w.line)), "\n"), "\r")
+	w.runes = []rune(line)
+	if w.line < (*w.buffer).Lines()-1 {
+		w.runes = append(w.runes, '\n')
+	}
+}
+
+// rune returns the rune at the position, or zero at the end of the buffer.
+func (w *runeWalker) rune() rune {
+	if w.col < len(w.runes) {
+		return w.runes[w.col]
+	}
+	return 0
+}
+
+// prevRune returns the rune before the position, or zero at the start of the buffer.
+func (w *runeWalker) prevRune() rune {
+	if w.col > 0 {
+		return w.runes[w.col-1]
+	} else if w.line > 0 {
+		return '\n'
+	}
+	return 0
+}
+
+// next steps forward one rune. Returns false at the end of the buffer.
+func (w *runeWalker) next() bool {
+	if w.col >= len(w.runes) {
+		return false
+	}
+	w.col++
+	if w.col == len(w.runes) && w.runes[w.col-1] == '\n' {
+		w.line, w.col = w.line+1, 0
+		w.load()
+	}
+	return true
+}
+
+// prev steps back one rune. Returns false at the start of the buffer.
+func (w *runeWalker) prev() bool {
+	if w.col == 0 {
+		if w.line == 0 {
+			return false
+		}
+		w.line--
+		w.load()
+		w.col = len(w.runes) // Past the delimiter, which is stepped back over below
+	}
+	w.col--
+	return true
+}
+
+// cursor returns a Cursor at the position.
+func (w *runeWalker) cursor(c Cursor) Cursor {
+	c.line, c.col = w.line, w.col
+	return c
+}
+
+// skipForward steps forward while the rune at the position is of the class.
+func (w *runeWalker) skipForward(class charclass) {
+	for w.rune() != 0 && getRuneCharclass(w.rune()) == class {
+		w.next()
+	}
+}
+
+// skipBack steps back while the rune before the position is of the class.
+func (w *runeWalker) skipBack(class charclass) {
+	for w.prevRune() != 0 && getRuneCharclass(w.prevRune()) == class {
+		w.prev()
+	}
+}
+
+// NextWordStart moves the Cursor to the first character of the next word, or
+// sequence of symbols, to its right. Whitespace and line delimiters are skipped.
+func (c Cursor) NextWordStart() Cursor {
+	w := newRuneWalker(c)
+	if r := w.rune(); r != 0 && getRuneCharclass(r) != charwhitespace {
+		w.skipForward(getRuneCharclass(r))
+	}
+	w.skipForward(charwhitespace)
+	return w.cursor(c)
+}
+
+// NextWordEnd moves the Cursor to the position after the last character of the
+// next word, or sequence of symbols, to its right. Whitespace is skipped.
+func (c Cursor) NextWordEnd() Cursor {
+	w := newRuneWalker(c)
+	w.skipForward(charwhitespace)
+	if r := w.rune(); r != 0 {
+		w.skipForward(getRuneCharclass(r))
+	}
+	return w.cursor(c)
+}
+
+// PrevWordStart moves the Cursor to the first character of the previous word,
+// or sequence of symbols, to its left. Whitespace is skipped.
+func (c Cursor) PrevWordStart() Cursor {
+	w := newRuneWalker(c)
+	w.skipBack(charwhitespace)
+	if r := w.prevRune(); r != 0 {
+		w.skipBack(getRuneCharclass(r))
+	}
+	return w.cursor(c)
+}
+
+// PrevWordEnd moves the Cursor to the position after the last character of the
+// previous word, or sequence of symbols, to its left.
+func (c Cursor) PrevWordEnd() Cursor {
+	w := newRuneWalker(c)
+	if r := w.prevRune(); r != 0 && getRuneCharclass(r) != charwhitespace {
+		w.skipBack(getRuneCharclass(r))
+	}
+	w.skipBack(charwhitespace)
+	return w.cursor(c)
+}
+
+// isSubwordBoundary returns whether a subword begins at runes[i], within a word.
+// Subwords are separated by underscores, by a change from lowercase to uppercase
+// or between letters and digits, and before the last capital of an acronym
+// followed by lowercase, like "HTTP" and "Server" in "HTTPServer".
+func isSubwordBoundary(runes []rune, i int) bool {
+	a, b := runes[i-1], runes[i]
+	switch {
+	case getRuneCharclass(a) != charword || getRuneCharclass(b) != charword:
+		return true
+	case (a == '_') != (b == '_'):
+		return true
+	case unicode.IsDigit(a) != unicode.IsDigit(b):
+		return true
+	case unicode.IsLower(a) && unicode.IsUpper(b):
+		return true
+	case unicode.IsUpper(a) && unicode.IsUpper(b) && i+1 < len(runes) && unicode.IsLower(runes[i+1]):
+		return true
+	}
+	return false
+}
+
+// NextSubwordEnd moves the Cursor to the end of the next subword to its right,
+// which is part of a camelCase or snake_case word. Outside of words, it moves like
+// NextWordEnd.
+func (c Cursor) NextSubwordEnd() Cursor {
+	w := newRuneWalker(c)
+	w.skipForward(charwhitespace)
+	if getRuneCharclass(w.rune()) != charword {
+		return w.cursor(c).NextWordEnd()
+	}
+	for w.next() && w.col > 0 && w.col < len(w.runes) && !isSubwordBoundary(w.runes, w.col) {
+	}
+	return w.cursor(c)
+}
+
+// PrevSubwordStart moves the Cursor to the start of the previous subword to its
+// left. Outside of words, it moves like PrevWordStart.
+func (c Cursor) PrevSubwordStart() Cursor {
+	w := newRuneWalker(c)
+	w.skipBack(charwhitespace)
+	if getRuneCharclass(w.prevRune()) != charword {
+		return w.cursor(c).PrevWordStart()
+	}
+	for w.prev() && w.col > 0 && !isSubwordBoundary(w.runes, w.col) {
+	}
+	return w.cursor(c)
+}
+
+// isBlankLine returns whether the line has only whitespace.
+func (c Cursor) isBlankLine(line int) bool {
+	return len(strings.TrimSpace(string((*c.buffer).Line(line)))) == 0
+}
+
+// ParagraphDown moves the Cursor to the next blank line after a paragraph of text,
+// or to the end of the buffer if there is none.
+func (c Cursor) ParagraphDown() Cursor {
+	lines := (*c.buffer).Lines()
+	line := c.line
+	for line < lines && c.isBlankLine(line) { // Skip blank lines before the paragraph
+		line++
+	}
+	for line < lines && !c.isBlankLine(line) {
+		line++
+	}
+	if line >= lines {
+		return c.BufferEnd()
+	}
+	c.line, c.col = line, 0
+	return c
+}
+
+// ParagraphUp moves the Cursor to the previous blank line before a paragraph of
+// text, or to the start of the buffer if there is none.
+func (c Cursor) ParagraphUp() Cursor {
+	line := c.line
+	for line >= 0 && c.isBlankLine(line) {
+		line--
+	}
+	for line >= 0 && !c.isBlankLine(line) {
+		line--
+	}
+	if line < 0 {
+		return c.BufferStart()
+	}
+	c.line, c.col = line, 0
+	return c
+}
+
+// FirstNonBlank moves the Cursor to the first character of its line that is not
+// whitespace, or to the end of the line if it is blank.
+func (c Cursor) FirstNonBlank() Cursor {
+	runes := []rune(string((*c.buffer).Line(c.line)))
+	col := 0
+	for col < len(runes) && runes[col] != '\n' && runes[col] != '\r' && unicode.IsSpace(runes[col]) {
+		col++
+	}
+	c.line, c.col = (*c.buffer).ClampLineCol(c.line, col)
+	return c
+}
+
+// BufferStart moves the Cursor to the first character of the buffer.
+func (c Cursor) BufferStart() Cursor {
+	c.line, c.col = 0, 0
+	return c
+}
+
+// BufferEnd moves the Cursor to the position after the last character of the buffer.
+func (c Cursor) BufferEnd() Cursor {
+	c.line, c.col = (*c.buffer).ClampLineCol(math.MaxInt32, math.MaxInt32)
+	return c
+}
+
+// Less returns whether the Cursor is before the `other` Cursor.
+func (c Cursor) Less(other Cursor) bool {
+	return c.line < other.line || c.line == other.line && c.col < other.col
+}
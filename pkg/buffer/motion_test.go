@@ -0,0 +1,84 @@
+package buffer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCursorMotions(t *testing.T) {
+	var buf Buffer = NewRopeBuffer([]byte("  foo.bar(baz)\r\n\n  parseHTTPServer2 snake_case\nend"))
+	at := func(line, col int) Cursor { return Cursor{buffer: &buf, position: position{line, col}} }
+
+	tests := []struct {
+		name   string
+		motion func(Cursor) Cursor
+		from   [2]int
+		want   [2]int
+	}{
+		{"NextWordStart", Cursor.NextWordStart, [2]int{0, 2}, [2]int{0, 5}},
+		{"NextWordStart/Symbol", Cursor.NextWordStart, [2]int{0, 5}, [2]int{0, 6}},
+		{"NextWordStart/Lines", Cursor.NextWordStart, [2]int{0, 13}, [2]int{2, 2}},
+		{"NextWordEnd", Cursor.NextWordEnd, [2]int{0, 0}, [2]int{0, 5}},
+		{"NextWordEnd/Lines", Cursor.NextWordEnd, [2]int{0, 14}, [2]int{2, 18}},
+		{"NextWordEnd/BufferEnd", Cursor.NextWordEnd, [2]int{3, 1}, [2]int{3, 3}},
+		{"PrevWordStart", Cursor.PrevWordStart, [2]int{0, 9}, [2]int{0, 6}},
+		{"PrevWordStart/Lines", Cursor.PrevWordStart, [2]int{2, 2}, [2]int{0, 13}},
+		{"PrevWordStart/BufferStart", Cursor.PrevWordStart, [2]int{0, 3}, [2]int{0, 2}},
+		{"PrevWordEnd", Cursor.PrevWordEnd, [2]int{2, 20}, [2]int{2, 18}},
+		{"PrevWordEnd/Lines", Cursor.PrevWordEnd, [2]int{2, 4}, [2]int{0, 14}},
+		{"NextSubwordEnd/Camel", Cursor.NextSubwordEnd, [2]int{2, 2}, [2]int{2, 7}},
+		{"NextSubwordEnd/Acronym", Cursor.NextSubwordEnd, [2]int{2, 7}, [2]int{2, 11}},
+		{"NextSubwordEnd/Digit", Cursor.NextSubwordEnd, [2]int{2, 11}, [2]int{2, 17}},
+		{"NextSubwordEnd/Snake", Cursor.NextSubwordEnd, [2]int{2, 18}, [2]int{2, 24}},
+		{"PrevSubwordStart/Snake", Cursor.PrevSubwordStart, [2]int{2, 29}, [2]int{2, 25}},
+		{"PrevSubwordStart/Acronym", Cursor.PrevSubwordStart, [2]int{2, 11}, [2]int{2, 7}},
+		{"ParagraphDown", Cursor.ParagraphDown, [2]int{0, 4}, [2]int{1, 0}},
+		{"ParagraphDown/BufferEnd", Cursor.ParagraphDown, [2]int{1, 0}, [2]int{3, 3}},
+		{"ParagraphUp", Cursor.ParagraphUp, [2]int{3, 1}, [2]int{1, 0}},
+		{"ParagraphUp/BufferStart", Cursor.ParagraphUp, [2]int{1, 0}, [2]int{0, 0}},
+		{"FirstNonBlank", Cursor.FirstNonBlank, [2]int{2, 10}, [2]int{2, 2}},
+		{"BufferStart", Cursor.BufferStart, [2]int{2, 10}, [2]int{0, 0}},
+		{"BufferEnd", Cursor.BufferEnd, [2]int{0, 0}, [2]int{3, 3}},
+	}
+	for _, tt := range tests {
+		got := tt.motion(at(tt.from[0], tt.from[1]))
+		if line, col := got.GetLineCol(); line != tt.want[0] || col != tt.want[1] {
+			t.Errorf("%s from %v: expected %v, got [%d %d]", tt.name, tt.from, tt.want, line, col)
+		}
+	}
+}
+
+func TestCursorGraphemes(t *testing.T) {
+	// A letter with a combining accent, a ZWJ emoji sequence, a flag, and a wide rune
+	var buf Buffer = NewRopeBuffer([]byte("aé👨‍👩‍👧🇫🇷界\r\nx"))
+	c := Cursor{buffer: &buf}
+
+	var cols []int
+	for i := 0; i < 7; i++ {
+		_, col := c.GetLineCol()
+		cols = append(cols, col)
+		c = c.Right()
+	}
+	expected := []int{0, 1, 3, 8, 10, 11, 0}
+	if !reflect.DeepEqual(cols, expected) {
+		t.Errorf("Expected Right to stop at columns %v, got %v", expected, cols)
+	}
+
+	cols = cols[:0]
+	for i := 0; i < 7; i++ {
+		c = c.Left()
+		_, col := c.GetLineCol()
+		cols = append(cols, col)
+	}
+	expected = []int{0, 11, 10, 8, 3, 1, 0}
+	if !reflect.DeepEqual(cols, expected) {
+		t.Errorf("Expected Left to stop at columns %v, got %v", expected, cols)
+	}
+
+	if _, col := (Cursor{buffer: &buf, position: position{1, 1}}).Up().GetLineCol(); col != 1 {
+		t.Errorf("Expected Up to keep column 1, got %d", col)
+	}
+	if _, col := (Cursor{buffer: &buf, position: position{0, 2}}).snapToGrapheme().GetLineCol(); col != 1 {
+		t.Errorf("Expected to snap to the grapheme cluster at column 1, got %d", col)
+	}
+}
@@ -0,0 +1,365 @@
+package buffer
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// pieceTablePageSize is the size of the pieces the original text of a PieceTable
+// is divided into, so the lines of a large file can be counted a page at a time.
+const pieceTablePageSize = 1 << 20
+
+// A piece is a span of the original or added text of a PieceTable.
+type piece struct {
+	added    bool // Whether the span is of the added text, instead of the original
+	start    int
+	length   int
+	newlines int // Number of line delimiters, or -1 for a whole page counted by the lineIndex
+}
+
+// A lineIndex counts the line delimiters in each page of the original text of a
+// PieceTable, when they are first needed, or in order by IndexLines. It is shared
+// with Snapshots, so it is safe to use from any goroutine.
+type lineIndex struct {
+	counts    []atomic.Int64 // Delimiters in each page plus one, or zero if not counted
+	indexing  atomic.Bool    // Whether IndexLines is counting pages
+	truncated atomic.Bool    // Whether reading the original text faulted
+}
+
+// A PieceTable stores the original text it was created with, which is never
+// modified, and the text of every insertion, appended to one slice. The contents
+// of the buffer are a list of pieces of the two. An edit only changes the list,
+// so editing is cheap no matter how large the file is, and the original text is
+// never copied. Each piece knows how many lines it contains, so lines are found
+// without reading the text before them.
+//
+// Every read of the original text is guarded, so if it is the Data of a
+// MappedFile that is truncated by another program, the text past the new end of
+// the file reads as missing, and Err returns ErrFileTruncated, instead of the
+// program crashing.
+type PieceTable struct {
+	original []byte
+	add      []byte
+	pieces   []piece
+	length   int
+	index    *lineIndex
+
+	// When shared, the pieces are used by a Snapshot, so they are copied before
+	// they are edited. The original and added text is never modified.
+	shared   bool
+	readOnly bool // Whether this is a Snapshot
+
+	listeners
+	chunkedText
+}
+
+// NewPieceTable returns a PieceTable with `contents` as its original text. The
+// contents are not copied, so they must not be modified afterwards. This makes it
+// suitable for the data of a MappedFile.
+func NewPieceTable(contents []byte) *PieceTable {
+	pages := (len(contents) + pieceTablePageSize - 1) / pieceTablePageSize
+	b := &PieceTable{
+		original: contents,
+		pieces:   make([]piece, pages),
+		length:   len(contents),
+		index:    &lineIndex{counts: make([]atomic.Int64, pages)},
+	}
+	for i := range b.pieces {
+		start := i * pieceTablePageSize
+		b.pieces[i] = piece{start: start, length: Min(pieceTablePageSize, len(contents)-start), newlines: -1}
+	}
+	b.src = b
+	return b
+}
+
+// IndexLines counts the lines of the original text in the background, one page
+// at a time, and calls `progress` from its goroutine with the number of bytes
+// counted so far and in total. Until counting is done, Lines returns only the
+// lines before the first page that has not been counted, instead of reading the
+// whole text. The returned function stops counting, and waits for it to stop.
+func (b *PieceTable) IndexLines(progress func(done, total int)) (stop func()) {
+	var stopped atomic.Bool
+	var wg sync.WaitGroup
+
+	b.index.indexing.Store(true)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer b.index.indexing.Store(false)
+		for page := range b.index.counts {
+			if stopped.Load() {
+				return
+			}
+			if _, ok := b.pageNewlines(page, true); !ok {
+				return // The file was truncated
+			}
+			if progress != nil {
+				progress(Min((page+1)*pieceTablePageSize, len(b.original)), len(b.original))
+			}
+		}
+	}()
+
+	return func() {
+		stopped.Store(true)
+		wg.Wait()
+	}
+}
+
+// guard calls f, which reads the original text, and returns false if a read
+// faulted because the file it is mapped from was truncated.
+func (b *PieceTable) guard(f func()) bool {
+	if err := guardFault(f); err != nil {
+		b.index.truncated.Store(true)
+		return false
+	}
+	return true
+}
+
+// Err returns ErrFileTruncated if reading the original text faulted, because the
+// file it is mapped from was truncated by another program. The text read since
+// is incomplete, so it should not be saved.
+func (b *PieceTable) Err() error {
+	if b.index.truncated.Load() {
+		return ErrFileTruncated
+	}
+	return nil
+}
+
+// pageNewlines returns the number of line delimiters in a page of the original
+// text. If the page has not been counted yet, it is counted if `count` is true,
+// otherwise false is returned. False is also returned if the page can't be read.
+func (b *PieceTable) pageNewlines(page int, count bool) (int, bool) {
+	if n := b.index.counts[page].Load(); n > 0 {
+		return int(n - 1), true
+	}
+	if !count {
+		return 0, false
+	}
+	start := page * pieceTablePageSize
+	text := b.original[start:Min(start+pieceTablePageSize, len(b.original))]
+	var n int
+	if !b.guard(func() { n = bytes.Count(text, []byte{'\n'}) }) {
+		return 0, false
+	}
+	b.index.counts[page].Store(int64(n + 1))
+	return n, true
+}
+
+// newlines returns the number of line delimiters in the piece, like pageNewlines.
+func (b *PieceTable) newlines(p piece, count bool) (int, bool) {
+	if p.newlines >= 0 {
+		return p.newlines, true
+	}
+	return b.pageNewlines(p.start/pieceTablePageSize, count)
+}
+
+// countLines returns the number of lines, from the number of line delimiters in
+// each piece. While IndexLines is counting, pages not yet counted end the count.
+func (b *PieceTable) countLines() int {
+	lines := 1
+	count := !b.index.indexing.Load()
+	for _, p := range b.pieces {
+		n, ok := b.newlines(p, count)
+		if !ok {
+			break // Not indexed, yet
+		}
+		lines += n
+	}
+	return lines
+}
+
+// lineStartPos returns the first byte index of the given line, skipping pieces
+// by their number of line delimiters. A panic is issued if there is no such line.
+// Like countLines, pages are not counted while IndexLines is counting them, so
+// the lines after them are not found until then. Neither are the lines after a
+// page that can't be read, once the file was truncated; the position the lines
+// could be read to is returned for them.
+func (b *PieceTable) lineStartPos(line int) int {
+	var pos int
+	count := !b.index.indexing.Load()
+	for _, p := range b.pieces {
+		if line <= 0 {
+			break
+		}
+		n, ok := b.newlines(p, count)
+		if !ok && b.index.truncated.Load() {
+			return pos
+		}
+		if !ok {
+			break // Not indexed, yet
+		}
+		if line > n {
+			line -= n
+			pos += p.length
+			continue
+		}
+
+		text := b.text(p) // The line starts after the line-th delimiter of this piece
+		if !b.guard(func() {
+			for ; line > 0; line-- {
+				i := bytes.IndexByte(text, '\n')
+				pos += i + 1
+				text = text[i+1:]
+			}
+		}) {
+			return pos
+		}
+	}
+
+	if line > 0 { // If there aren't enough lines to reach line...
+		panic("getLineStartPos: not enough lines in buffer to reach position")
+	}
+
+	return pos
+}
+
+// Snapshot returns a read-only view of the buffer as it is now, which is safe to
+// read from other goroutines while the buffer is edited. The Snapshot shares the
+// list of pieces with the buffer until the next edit, which copies it.
+func (b *PieceTable) Snapshot() Buffer {
+	if b.readOnly {
+		return b
+	}
+	b.shared = true
+	snapshot := &PieceTable{
+		original: b.original,
+		add:      b.add[:len(b.add):len(b.add)],
+		pieces:   b.pieces,
+		length:   b.length,
+		index:    b.index,
+		readOnly: true,
+	}
+	snapshot.src = snapshot
+	return snapshot
+}
+
+// Len returns the number of bytes in the buffer.
+func (b *PieceTable) Len() int {
+	return b.length
+}
+
+// text returns the bytes of a piece.
+func (b *PieceTable) text(p piece) []byte {
+	if p.added {
+		return b.add[p.start : p.start+p.length]
+	}
+	return b.original[p.start : p.start+p.length]
+}
+
+// eachChunk calls f with the text of each piece. If reading it faults, because
+// the file was truncated, the chunks end there.
+func (b *PieceTable) eachChunk(f func(chunk []byte) bool) {
+	b.guard(func() {
+		for _, p := range b.pieces {
+			if f(b.text(p)) {
+				return
+			}
+		}
+	})
+}
+
+// WriteTo writes each chunk of the text to `w`. ErrFileTruncated is returned if
+// the text could not all be read, because the file was truncated.
+func (b *PieceTable) WriteTo(w io.Writer) (int64, error) {
+	n, err := b.chunkedText.WriteTo(w)
+	if err == nil {
+		err = b.Err()
+	}
+	return n, err
+}
+
+// beginEdit must be called before the pieces are edited.
+func (b *PieceTable) beginEdit() {
+	if b.readOnly {
+		panic("buffer: cannot edit a Snapshot")
+	}
+	if b.shared {
+		b.pieces = append([]piece(nil), b.pieces...)
+		b.shared = false
+	}
+}
+
+// splitAt splits the piece containing byte position `pos`, if `pos` is within it,
+// and returns the index of the first piece at or after `pos`.
+func (b *PieceTable) splitAt(pos int) int {
+	var offset int
+	for i, p := range b.pieces {
+		if pos == offset {
+			return i
+		}
+		if pos < offset+p.length {
+			n := pos - offset
+			text := b.text(p)
+			var left, right int
+			b.guard(func() { // The counts of a truncated file don't matter
+				left = bytes.Count(text[:n], []byte{'\n'})
+				var ok bool
+				if right, ok = b.newlines(p, false); ok {
+					right -= left
+				} else {
+					right = bytes.Count(text[n:], []byte{'\n'})
+				}
+			})
+
+			b.pieces = append(b.pieces, piece{})
+			copy(b.pieces[i+1:], b.pieces[i:])
+			b.pieces[i] = piece{p.added, p.start, n, left}
+			b.pieces[i+1] = piece{p.added, p.start + n, p.length - n, right}
+			return i + 1
+		}
+		offset += p.length
+	}
+	return len(b.pieces)
+}
+
+// Insert copies a byte slice (inserting it) into the position at line, col.
+func (b *PieceTable) Insert(line, col int, value []byte) {
+	b.beginEdit()
+	pos := b.LineColToPos(line, col)
+
+	start := len(b.add)
+	b.add = append(b.add, value...)
+	value = b.add[start:len(b.add):len(b.add)] // Never modified, so Observers can keep it
+
+	if len(value) > 0 {
+		newlines := bytes.Count(value, []byte{'\n'})
+		i := b.splitAt(pos)
+		if prev := i - 1; prev >= 0 && b.pieces[prev].added && b.pieces[prev].start+b.pieces[prev].length == start {
+			b.pieces[prev].length += len(value) // Typing continues the last insertion
+			b.pieces[prev].newlines += newlines
+		} else {
+			b.pieces = append(b.pieces, piece{})
+			copy(b.pieces[i+1:], b.pieces[i:])
+			b.pieces[i] = piece{true, start, len(value), newlines}
+		}
+		b.length += len(value)
+	}
+
+	b.shiftAnchorsInserted(line, col, value)
+	b.notify(newChange(false, line, col, pos, value))
+}
+
+// Remove deletes any characters between startLine, startCol, and endLine,
+// endCol, inclusive bounds.
+func (b *PieceTable) Remove(startLine, startCol, endLine, endCol int) {
+	start := b.LineColToPos(startLine, startCol)
+	end := b.runeEndPos(b.LineColToPos(endLine, endCol))
+
+	if start >= end {
+		return
+	}
+
+	removed := b.slice(start, end) // A copy
+	nl, lastRunes := measureText(removed)
+
+	b.beginEdit()
+	i := b.splitAt(start)
+	j := b.splitAt(end)
+	b.pieces = append(b.pieces[:i], b.pieces[j:]...)
+	b.length -= end - start
+
+	b.shiftAnchorsRemoved(startLine, startCol, nl, lastRunes)
+	b.notify(newChange(true, startLine, startCol, start, removed))
+}
@@ -0,0 +1,101 @@
+package buffer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPieceTableIndexLines(t *testing.T) {
+	contents := bytes.Repeat([]byte("line\n"), pieceTablePageSize/2) // Three pages
+	buf := NewPieceTable(contents)
+
+	finished := make(chan struct{})
+	buf.IndexLines(func(done, total int) {
+		if done == total {
+			close(finished)
+		}
+	})
+	<-finished
+	if lines := buf.Lines(); lines != pieceTablePageSize/2+1 {
+		t.Errorf("Expected %d lines, got %d", pieceTablePageSize/2+1, lines)
+	}
+
+	line := pieceTablePageSize / 5 // "line\n" crosses the end of the first page here
+	buf.Insert(line, 2, []byte("X\nY"))
+	buf.Remove(line+2, 0, line+3, 0)
+	if str := string(buf.Slice(line, 0, line+2, 0)); str != "liX\nYne\ni" {
+		t.Errorf("Expected edits across pages, got %#v", str)
+	}
+	if lines := buf.Lines(); lines != pieceTablePageSize/2+1 {
+		t.Errorf("Expected %d lines after edits, got %d", pieceTablePageSize/2+1, lines)
+	}
+}
+
+func TestPieceTableIndexingLines(t *testing.T) {
+	buf := NewPieceTable(bytes.Repeat([]byte("line\n"), pieceTablePageSize/2))
+	buf.index.indexing.Store(true) // As if IndexLines had counted only the first page
+	buf.pageNewlines(0, true)
+
+	if lines, expected := buf.Lines(), pieceTablePageSize/5+1; lines != expected {
+		t.Errorf("Expected only the %d indexed lines to be counted, got %d", expected, lines)
+	}
+	if str := string(buf.Line(pieceTablePageSize / 10)); str != "line\n" {
+		t.Errorf("Expected a line of the first page, got %#v", str)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected a line of the second page not to be found before it is indexed")
+		}
+	}()
+	buf.Line(pieceTablePageSize / 4) // Would count the page on this goroutine
+}
+
+func TestMapFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, []byte("mapped\nfile\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := MapFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := NewPieceTable(file.Data)
+	buf.Insert(1, 0, []byte("edited "))
+	if str := string(buf.Bytes()); str != "mapped\nedited file\n" {
+		t.Errorf("Expected the edited file, got %#v", str)
+	}
+	if err := file.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPieceTableIndex(t *testing.T) {
+	buf := NewPieceTable([]byte("one two\nthree four\n"))
+	buf.Insert(0, 4, []byte("T"))   // "one Ttwo"
+	buf.Insert(1, 2, []byte("R\n")) // "thR\nree four"
+	if len(buf.pieces) < 4 {
+		t.Fatalf("Expected the text to be split into pieces, got %d", len(buf.pieces))
+	}
+
+	tests := []struct {
+		pos  int
+		sep  string
+		want int
+	}{
+		{0, "Ttw", 4},     // Across the end of a piece
+		{0, "hR\nre", 10}, // Across two ends of pieces
+		{5, "T", -1},
+		{0, "four\n", 17},
+	}
+	for _, tt := range tests {
+		if got := Index(buf, tt.pos, []byte(tt.sep)); got != tt.want {
+			t.Errorf("Index(%d, %#v): expected %d, got %d", tt.pos, tt.sep, tt.want, got)
+		}
+	}
+	if line, col := buf.PosToLineCol(17); line != 2 || col != 4 {
+		t.Errorf("Expected position 17 at 2, 4, got %d, %d", line, col)
+	}
+}
@@ -0,0 +1,27 @@
+package buffer
+
+import "github.com/gdamore/tcell/v2"
+
+// A PlainHighlighter does not highlight anything: no line has any Matches. It is
+// used where highlighting would cost too much, like in very large files.
+type PlainHighlighter struct {
+	Colorscheme *Colorscheme
+}
+
+func NewPlainHighlighter(colorscheme *Colorscheme) *PlainHighlighter {
+	return &PlainHighlighter{colorscheme}
+}
+
+func (h *PlainHighlighter) OnChange(change Change) {}
+
+func (h *PlainHighlighter) InvalidateLines(startLine, endLine int) {}
+
+func (h *PlainHighlighter) UpdateInvalidatedLines(startLine, endLine int) {}
+
+func (h *PlainHighlighter) GetLineMatches(line int) []Match {
+	return nil
+}
+
+func (h *PlainHighlighter) GetStyle(match Match) tcell.Style {
+	return h.Colorscheme.GetStyle(match.Syntax)
+}
@@ -1,21 +1,58 @@
 package buffer
 
 import (
+	"bytes"
 	"io"
 	"unicode/utf8"
 
 	ropes "github.com/zyedidia/rope"
 )
 
+// ropeCompactEdits is how many edits a RopeBuffer makes without modifying nodes
+// in place, after a Snapshot, before copying its rope into a new, balanced one.
+const ropeCompactEdits = 1024
+
 type RopeBuffer struct {
-	rope    *ropes.Node
-	anchors []*Cursor
+	rope *ropes.Node
+	listeners
+
+	// When shared, the nodes of the rope may be used by a Snapshot, so edits create
+	// new nodes instead of modifying them in place (copy-on-write). Each edit adds
+	// to the depth of the tree, so it is compacted after ropeCompactEdits.
+	shared      bool
+	sharedEdits int
+	readOnly    bool // Whether this is a Snapshot
 }
 
 func NewRopeBuffer(contents []byte) *RopeBuffer {
 	return &RopeBuffer{
-		ropes.New(contents),
-		nil,
+		rope: ropes.New(contents),
+	}
+}
+
+// Snapshot returns a read-only view of the buffer as it is now, which is safe to
+// read from other goroutines while the buffer is edited. Taking a Snapshot is
+// cheap: it shares the nodes of the rope, which are no longer modified in place.
+func (b *RopeBuffer) Snapshot() Buffer {
+	if b.readOnly {
+		return b
+	}
+	b.shared = true
+	return &RopeBuffer{rope: b.rope, readOnly: true}
+}
+
+// beginEdit must be called before the rope is edited.
+func (b *RopeBuffer) beginEdit() {
+	if b.readOnly {
+		panic("buffer: cannot edit a Snapshot")
+	}
+	if b.shared {
+		b.sharedEdits++
+		if b.sharedEdits >= ropeCompactEdits {
+			// Copy into new nodes, which no Snapshot uses, so they can be edited in place
+			b.rope = ropes.New(append([]byte(nil), b.rope.Value()...))
+			b.shared, b.sharedEdits = false, 0
+		}
 	}
 }
 
@@ -40,7 +77,7 @@ func (b *RopeBuffer) LineColToPos(line, col int) int {
 				if col == 0 || r == '\n' {
 					return true // Found the position of the column
 				}
-				pos++
+				pos += utf8.RuneLen(r) // Runes may be more than one byte
 				col--
 			}
 			return false // Have not gotten to the appropriate position, yet
@@ -55,50 +92,39 @@ func (b *RopeBuffer) LineColToPos(line, col int) int {
 // write it.
 func (b *RopeBuffer) Line(line int) []byte {
 	pos := b.getLineStartPos(line)
-	bytes := 0
+	size := 0
 
 	_, r := b.rope.SplitAt(pos)
 	l, _ := r.SplitAt(b.rope.Len() - pos)
 
-	var isCRLF bool // true if the last byte was '\r'
 	l.EachLeaf(func(n *ropes.Node) bool {
 		data := n.Value() // Reference; not a copy.
-		for i, r := range string(data) {
-			if r == '\r' {
-				isCRLF = true
-			} else if r == '\n' {
-				bytes += i // Add bytes before i
-				if isCRLF {
-					bytes += 2 // Add the CRLF bytes
-				} else {
-					bytes += 1 // Add LF byte
-				}
-				return true // Read (past-tense) the whole line
-			} else {
-				isCRLF = false
-			}
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			size += i + 1 // Add bytes before and including the LF (after any '\r')
+			return true   // Read (past-tense) the whole line
 		}
-		bytes += len(data)
+		size += len(data)
 		return false // Have not read the whole line, yet
 	})
 
-	return b.rope.Slice(pos, pos+bytes) // NOTE: may be faster to do it ourselves
+	return b.rope.Slice(pos, pos+size) // NOTE: may be faster to do it ourselves
 }
 
 // Returns a slice of the buffer from startLine, startCol, to endLine, endCol,
 // inclusive bounds. The returned value may or may not be a copy of the data,
 // so do not write to it.
 func (b *RopeBuffer) Slice(startLine, startCol, endLine, endCol int) []byte {
-	endPos := b.LineColToPos(endLine, endCol)
-	if length := b.rope.Len(); endPos >= length {
-		endPos = length - 1
-	}
-	return b.rope.Slice(b.LineColToPos(startLine, startCol), endPos+1)
+	endPos := b.runeEndPos(b.LineColToPos(endLine, endCol))
+	return b.rope.Slice(b.LineColToPos(startLine, startCol), endPos)
 }
 
 // RuneAtPos returns the UTF-8 rune at the byte position `pos` of the buffer. The
 // position must be a correct position, otherwise zero is returned.
 func (b *RopeBuffer) RuneAtPos(pos int) (val rune) {
+	if pos < 0 || pos >= b.rope.Len() {
+		return 0
+	}
+
 	_, r := b.rope.SplitAt(pos)
 	l, _ := r.SplitAt(b.rope.Len() - pos)
 
@@ -108,7 +134,7 @@ func (b *RopeBuffer) RuneAtPos(pos int) (val rune) {
 		return true
 	})
 
-	return 0
+	return val
 }
 
 // EachRuneAtPos executes the function `f` at each rune after byte position `pos`.
@@ -139,28 +165,44 @@ func (b *RopeBuffer) Bytes() []byte {
 
 // Insert copies a byte slice (inserting it) into the position at line, col.
 func (b *RopeBuffer) Insert(line, col int, value []byte) {
-	b.rope.Insert(b.LineColToPos(line, col), value)
-	b.shiftAnchors(line, col, utf8.RuneCount(value))
+	b.beginEdit()
+	pos := b.LineColToPos(line, col)
+	value = append([]byte(nil), value...) // Keep our own copy for the rope and Observers
+
+	if b.shared { // Split and join, which only creates new nodes
+		l, r := b.rope.SplitAt(pos)
+		b.rope = ropes.Join(l, ropes.New(value), r)
+	} else {
+		b.rope.Insert(pos, value)
+	}
+	b.shiftAnchorsInserted(line, col, value)
+	b.notify(newChange(false, line, col, pos, value))
 }
 
 // Remove deletes any characters between startLine, startCol, and endLine,
 // endCol, inclusive bounds.
 func (b *RopeBuffer) Remove(startLine, startCol, endLine, endCol int) {
 	start := b.LineColToPos(startLine, startCol)
-	end := b.LineColToPos(endLine, endCol) + 1
+	end := b.runeEndPos(b.LineColToPos(endLine, endCol))
 
-	if len := b.rope.Len(); end >= len {
-		end = len
-		if start > end {
-			return
-		}
+	if start >= end {
+		return
 	}
 
-	b.rope.Remove(start, end)
-	// Shift anchors within the range
-	b.shiftAnchorsRemovedRange(start, startLine, startCol, endLine, endCol)
-	// Shift anchors after the range
-	b.shiftAnchors(endLine, endCol+1, start-end-1)
+	// Copy the removed text before it is gone, so anchors can be moved
+	removed := append([]byte(nil), b.rope.Slice(start, end)...)
+	nl, lastRunes := measureText(removed)
+
+	b.beginEdit()
+	if b.shared { // Split and join, which only creates new nodes
+		l, _ := b.rope.SplitAt(start)
+		_, r := b.rope.SplitAt(end)
+		b.rope = ropes.Join(l, r)
+	} else {
+		b.rope.Remove(start, end)
+	}
+	b.shiftAnchorsRemoved(startLine, startCol, nl, lastRunes)
+	b.notify(newChange(true, startLine, startCol, start, removed))
 }
 
 // Returns the number of occurrences of 'sequence' in the buffer, within the range
@@ -184,6 +226,18 @@ func (b *RopeBuffer) Lines() int {
 	return rope.Count(0, rope.Len(), []byte{'\n'}) + 1
 }
 
+// runeEndPos returns the byte index after the rune starting at `pos`, clamped to
+// the length of the buffer. This prevents inclusive ranges from splitting the
+// bytes of a multi-byte rune.
+func (b *RopeBuffer) runeEndPos(pos int) int {
+	length := b.rope.Len()
+	if pos >= length {
+		return length
+	}
+	_, size := utf8.DecodeRune(b.rope.Slice(pos, Min(pos+utf8.UTFMax, length)))
+	return pos + size
+}
+
 // getLineStartPos returns the first byte index of the given line (starting from zero).
 // The returned index can be equal to the length of the buffer, not pointing to any byte,
 // which means the byte is on the last, and empty, line of the buffer. If line is greater
@@ -253,20 +307,17 @@ func (b *RopeBuffer) RunesInLine(line int) int {
 	_, r := b.rope.SplitAt(linePos)
 	l, _ := r.SplitAt(ropeLen - linePos)
 
-	var isCRLF bool
+	var isCRLF bool // true if the last rune was '\r'
 	l.EachLeaf(func(n *ropes.Node) bool {
 		data := n.Value() // Reference; not a copy.
 		for _, r := range string(data) {
-			if r == '\r' {
-				isCRLF = true
-			} else if r == '\n' {
-				return true // Read (past-tense) the whole line
-			} else {
+			if r == '\n' {
 				if isCRLF {
-					isCRLF = false
-					count++ // Add the '\r' we previously thought was part of the delim.
+					count-- // The '\r' we counted is part of the delimiter
 				}
+				return true // Read (past-tense) the whole line
 			}
+			isCRLF = r == '\r'
 			count++
 		}
 		return false // Have not read the whole line, yet
@@ -300,7 +351,6 @@ func (b *RopeBuffer) ClampLineCol(line, col int) (int, int) {
 // is unlikely to be useful to you. Position will be clamped.
 func (b *RopeBuffer) PosToLineCol(pos int) (int, int) {
 	var line, col int
-	var wasAtNewline bool
 
 	if pos <= 0 {
 		return line, col
@@ -310,25 +360,19 @@ func (b *RopeBuffer) PosToLineCol(pos int) (int, int) {
 		data := n.Value()
 		var i int
 		for i < len(data) {
-			if wasAtNewline { // Start of line
-				if data[i] != '\n' { // If the start of this line does not happen to be a delim...
-					wasAtNewline = false // Say we weren't previously at a delimiter
-				}
+			if pos <= 0 {
+				return true // Reached the position
+			}
+
+			r, size := utf8.DecodeRune(data[i:])
+			if r == '\n' { // Every byte after a delimiter is on the next line
 				line, col = line+1, 0
-			} else if data[i] == '\n' { // End of line
-				wasAtNewline = true
-				col++
 			} else {
 				col++
 			}
 
-			_, size := utf8.DecodeRune(data[i:])
 			i += size
 			pos -= size
-
-			if pos < 0 {
-				return true
-			}
 		}
 		return false
 	})
@@ -339,54 +383,3 @@ func (b *RopeBuffer) PosToLineCol(pos int) (int, int) {
 func (b *RopeBuffer) WriteTo(w io.Writer) (int64, error) {
 	return b.rope.WriteTo(w)
 }
-
-// Currently meant for the Remove function: imagine if the removed region passes through a Cursor position.
-// We want to shift the cursor to the start of the region, based upon where the cursor position is.
-func (b *RopeBuffer) shiftAnchorsRemovedRange(startPos, startLine, startCol, endLine, endCol int) {
-	for _, v := range b.anchors {
-		cursorLine, cursorCol := v.GetLineCol()
-		if cursorLine >= startLine && cursorLine <= endLine {
-			// If the anchor is not within the start or end columns
-			if (cursorLine == startLine && cursorCol < startCol) || (cursorLine == endLine && cursorCol > endCol) {
-				continue
-			}
-			cursorPos := b.LineColToPos(cursorLine, cursorCol)
-			v.line, v.col = b.PosToLineCol(cursorPos + (startPos - cursorPos))
-		}
-	}
-}
-
-func (b *RopeBuffer) shiftAnchors(insertLine, insertCol, runeCount int) {
-	for _, v := range b.anchors {
-		cursorLine, cursorCol := v.GetLineCol()
-		if insertLine < cursorLine || (insertLine == cursorLine && insertCol <= cursorCol) {
-			v.line, v.col = b.PosToLineCol(b.LineColToPos(cursorLine, cursorCol) + runeCount)
-		}
-	}
-}
-
-// RegisterCursor adds the Cursor to a slice which the Buffer uses to update
-// each Cursor based on changes that occur in the Buffer. Various functions are
-// called on the Cursor depending upon where the edits occurred and how it should
-// modify the Cursor's position. Unregister a Cursor before deleting it from
-// memory, or forgetting it, with UnregisterPosition.
-func (b *RopeBuffer) RegisterCursor(cursor *Cursor) {
-	if cursor == nil {
-		return
-	}
-	b.anchors = append(b.anchors, cursor)
-}
-
-// UnregisterCursor will remove the cursor from the list of watched Cursors.
-// It is mandatory that a Cursor be unregistered before being freed from memory,
-// or otherwise being forgotten.
-func (b *RopeBuffer) UnregisterCursor(cursor *Cursor) {
-	for i, v := range b.anchors {
-		if cursor == v {
-			// Delete item at i without preserving order
-			b.anchors[i] = b.anchors[len(b.anchors)-1]
-			b.anchors[len(b.anchors)-1] = nil
-			b.anchors = b.anchors[:len(b.anchors)-1]
-		}
-	}
-}
@@ -146,3 +146,43 @@ func TestRopeAnchors(t *testing.T) {
 
 	buf.UnregisterCursor(&myCursor)
 }
+
+func TestRopePosToLineColFirstLine(t *testing.T) {
+	var buf Buffer = NewRopeBuffer([]byte("ab\ncd\n"))
+
+	expected := [][2]int{{0, 0}, {0, 1}, {0, 2}, {1, 0}, {1, 1}, {1, 2}, {2, 0}}
+	for pos, lineCol := range expected {
+		if line, col := buf.PosToLineCol(pos); line != lineCol[0] || col != lineCol[1] {
+			t.Errorf("Expected pos %d at line,col %d,%d ; got %d,%d", pos, lineCol[0], lineCol[1], line, col)
+		}
+	}
+}
+
+func TestRopeRemoveMultibyte(t *testing.T) {
+	var buf Buffer = NewRopeBuffer([]byte("aはb"))
+
+	if slice := string(buf.Slice(0, 1, 0, 1)); slice != "は" {
+		t.Errorf("Expected slice to contain the whole rune, got %#v", slice)
+	}
+
+	buf.Remove(0, 1, 0, 1)
+	if str := string(buf.Bytes()); str != "ab" {
+		t.Errorf("Expected \"ab\" after removing multi-byte rune, got %#v", str)
+	}
+}
+
+func TestRopeSnapshotCompacts(t *testing.T) {
+	var buf Buffer = NewRopeBuffer([]byte("abc"))
+	snapshot := buf.Snapshot()
+
+	for i := 0; i < ropeCompactEdits+10; i++ { // Past compaction, then edits in place
+		buf.Insert(0, 1, []byte("x"))
+	}
+	buf.Remove(0, 0, 0, ropeCompactEdits+10)
+	if str := string(buf.Bytes()); str != "bc" {
+		t.Errorf("Expected \"bc\", got %#v", str)
+	}
+	if str := string(snapshot.Bytes()); str != "abc" {
+		t.Errorf("Expected the snapshot to be unchanged, got %#v", str)
+	}
+}
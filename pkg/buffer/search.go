@@ -0,0 +1,39 @@
+package buffer
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// A chunkIndexer is a Buffer which can search its chunks of text directly.
+type chunkIndexer interface {
+	indexFrom(pos int, sep []byte) int
+}
+
+// Index returns the byte position of the first occurrence of `sep` in the buffer
+// at or after byte position `pos`, or -1 if there is none. The buffer is not
+// copied: buffers made of chunks are searched a chunk at a time, and others a rune
+// at a time.
+func Index(buf Buffer, pos int, sep []byte) int {
+	if len(sep) == 0 {
+		return pos
+	}
+	if c, ok := buf.(chunkIndexer); ok {
+		return c.indexFrom(pos, sep)
+	}
+
+	found := -1
+	window := make([]byte, 0, len(sep)+utf8.UTFMax) // The last bytes read
+	buf.EachRuneAtPos(pos, func(rpos int, r rune) bool {
+		window = utf8.AppendRune(window, r)
+		if len(window) > len(sep) {
+			window = append(window[:0], window[len(window)-len(sep):]...)
+		}
+		if bytes.Equal(window, sep) {
+			found = rpos + utf8.RuneLen(r) - len(sep)
+			return true
+		}
+		return false
+	})
+	return found
+}
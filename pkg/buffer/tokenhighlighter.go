@@ -0,0 +1,52 @@
+package buffer
+
+import "github.com/gdamore/tcell/v2"
+
+// A TokenHighlighter is a Highlighter that uses a Tokenizer. Tokens can depend on
+// any text before them, like the start of a multiline comment, so the whole buffer
+// is tokenized whenever any line is invalidated.
+type TokenHighlighter struct {
+	Buffer      Buffer
+	Tokenizer   Tokenizer
+	Colorscheme *Colorscheme
+
+	lineMatches [][]Match
+	invalidated bool // Whether any line was invalidated since the last update
+}
+
+func NewTokenHighlighter(buffer Buffer, tokenizer Tokenizer, colorscheme *Colorscheme) *TokenHighlighter {
+	return &TokenHighlighter{
+		Buffer:      buffer,
+		Tokenizer:   tokenizer,
+		Colorscheme: colorscheme,
+		invalidated: true,
+	}
+}
+
+func (h *TokenHighlighter) InvalidateLines(startLine, endLine int) {
+	h.invalidated = true
+}
+
+func (h *TokenHighlighter) UpdateInvalidatedLines(startLine, endLine int) {
+	if !h.invalidated {
+		return
+	}
+	src := h.Buffer.Bytes()
+	h.lineMatches = tokenMatches(src, h.Tokenizer(src), h.Buffer.Lines())
+	h.invalidated = false
+}
+
+func (h *TokenHighlighter) OnChange(change Change) {
+	invalidateChange(h, change)
+}
+
+func (h *TokenHighlighter) GetLineMatches(line int) []Match {
+	if line < 0 || line >= len(h.lineMatches) {
+		return nil
+	}
+	return h.lineMatches[line] // Already sorted, as Tokens are in order
+}
+
+func (h *TokenHighlighter) GetStyle(match Match) tcell.Style {
+	return h.Colorscheme.GetStyle(match.Syntax)
+}
@@ -0,0 +1,162 @@
+package buffer
+
+import (
+	"go/scanner"
+	"go/token"
+	"unicode/utf8"
+)
+
+// A Token is a region of source code with a Syntax, as the byte offsets [Start, End).
+type Token struct {
+	Start, End int
+	Syntax     Syntax
+}
+
+// A Tokenizer returns the Tokens of the source code `src`, in ascending order and
+// without overlaps. Unlike regular expressions, a Tokenizer reads the whole source
+// at once, so it knows the context of every Token, such as whether it is inside a
+// string or comment.
+type Tokenizer func(src []byte) []Token
+
+// tokenMatches converts the Tokens of `src` into the Matches of each of its lines.
+// Tokens spanning multiple lines are split into a Match for each line, so every
+// Match ends on the line it starts on. All `lines` of the result are non-nil.
+func tokenMatches(src []byte, tokens []Token, lines int) [][]Match {
+	lineMatches := make([][]Match, lines)
+	for i := range lineMatches {
+		lineMatches[i] = make([]Match, 0)
+	}
+
+	var pos, line, col int
+	advance := func(to int) { // Moves pos to `to`, counting lines and runes
+		for pos < to && pos < len(src) {
+			r, size := utf8.DecodeRune(src[pos:])
+			if r == '\n' {
+				line, col = line+1, 0
+			} else {
+				col++
+			}
+			pos += size
+		}
+	}
+	addMatch := func(startCol, endCol int, syntax Syntax) {
+		if line < lines && endCol >= startCol {
+			lineMatches[line] = append(lineMatches[line], Match{startCol, line, endCol, syntax})
+		}
+	}
+
+	for _, tok := range tokens {
+		advance(tok.Start)
+		startCol := col
+		for pos < tok.End && pos < len(src) {
+			if src[pos] == '\n' { // Split the Match at the end of the line
+				addMatch(startCol, col-1, tok.Syntax)
+				startCol = 0
+			}
+			advance(pos + 1)
+		}
+		addMatch(startCol, col-1, tok.Syntax)
+	}
+	return lineMatches
+}
+
+// goTypes are the predeclared types of Go.
+var goTypes = map[string]bool{
+	"any": true, "bool": true, "byte": true, "comparable": true, "complex64": true,
+	"complex128": true, "error": true, "float32": true, "float64": true, "int": true,
+	"int8": true, "int16": true, "int32": true, "int64": true, "rune": true,
+	"string": true, "uint": true, "uint8": true, "uint16": true, "uint32": true,
+	"uint64": true, "uintptr": true,
+}
+
+// goBuiltins are the predeclared functions of Go.
+var goBuiltins = map[string]bool{
+	"append": true, "cap": true, "clear": true, "close": true, "complex": true,
+	"copy": true, "delete": true, "imag": true, "len": true, "make": true,
+	"max": true, "min": true, "new": true, "panic": true, "print": true,
+	"println": true, "real": true, "recover": true,
+}
+
+// TokenizeGo is a Tokenizer for Go, using the go/scanner package. Comments directly
+// above a declaration are tokenized as DocComments.
+func TokenizeGo(src []byte) []Token {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+
+	var s scanner.Scanner
+	s.Init(file, src, nil, scanner.ScanComments) // Errors are ignored: the code is being edited
+
+	var tokens []Token
+	var comments []int // Indexes of Tokens of the comments directly above the current token
+	prevLine := 0      // Line of the previous token that is not a comment
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		start := file.Offset(pos)
+
+		var syntax Syntax
+		switch {
+		case tok == token.COMMENT:
+			if file.Line(pos) == prevLine { // A comment after code documents nothing
+				comments = comments[:0]
+				tokens = append(tokens, Token{start, tokenEnd(src, start, lit), Comment})
+				continue
+			}
+			if len(comments) > 0 && file.Line(pos) > file.Line(file.Pos(tokens[comments[len(comments)-1]].End-1))+1 {
+				comments = comments[:0] // Separated from the previous comment by a blank line
+			}
+			comments = append(comments, len(tokens))
+			tokens = append(tokens, Token{start, tokenEnd(src, start, lit), Comment})
+			continue
+		case tok == token.STRING || tok == token.CHAR:
+			syntax = String
+		case tok == token.INT || tok == token.FLOAT || tok == token.IMAG:
+			syntax = Number
+		case tok.IsKeyword():
+			syntax = Keyword
+			lit = tok.String()
+		case tok == token.IDENT && (lit == "nil" || lit == "true" || lit == "false" || lit == "iota"):
+			syntax = Special
+		case tok == token.IDENT && goTypes[lit]:
+			syntax = Type
+		case tok == token.IDENT && goBuiltins[lit]:
+			syntax = Builtin
+		}
+
+		if tok == token.SEMICOLON && lit == "\n" {
+			continue // An automatic semicolon does not end the comments above a declaration
+		}
+		prevLine = file.Line(pos)
+		if len(comments) > 0 {
+			lastComment := tokens[comments[len(comments)-1]]
+			isDecl := tok == token.FUNC || tok == token.TYPE || tok == token.VAR || tok == token.CONST || tok == token.PACKAGE
+			if isDecl && file.Line(pos) == file.Line(file.Pos(lastComment.End-1))+1 {
+				for _, i := range comments {
+					tokens[i].Syntax = DocComment
+				}
+			}
+			comments = comments[:0]
+		}
+
+		if syntax != Default {
+			tokens = append(tokens, Token{start, tokenEnd(src, start, lit), syntax})
+		}
+	}
+	return tokens
+}
+
+// tokenEnd returns the offset of the end of the token `lit` starting at `start` in
+// `src`. The scanner removes carriage returns from comments and raw strings, so
+// they are skipped over.
+func tokenEnd(src []byte, start int, lit string) int {
+	end, n := start, 0
+	for n < len(lit) && end < len(src) {
+		if src[end] != '\r' || lit[n] == '\r' {
+			n++
+		}
+		end++
+	}
+	return end
+}
@@ -0,0 +1,51 @@
+package buffer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenizeGoLiterals(t *testing.T) {
+	src := "x := `raw` + \"a\\\"b\" + 'c' + 1.5 + 2i + 0x1F"
+	expected := []Match{
+		{5, 0, 9, String},   // `raw`
+		{13, 0, 18, String}, // "a\"b"
+		{22, 0, 24, String}, // 'c'
+		{28, 0, 30, Number}, // 1.5
+		{34, 0, 35, Number}, // 2i
+		{39, 0, 42, Number}, // 0x1F
+	}
+
+	matches := tokenMatches([]byte(src), TokenizeGo([]byte(src)), 1)
+	if !reflect.DeepEqual(matches[0], expected) {
+		t.Errorf("expected %v, got %v", expected, matches[0])
+	}
+}
+
+func TestTokenizeGoMultiline(t *testing.T) {
+	src := "a := `one\r\ntwo`\n/* é\nb */ var"
+	expected := [][]Match{
+		{{5, 0, 9, String}},                      // "`one\r"
+		{{0, 1, 3, String}},                      // "two`"
+		{{0, 2, 3, Comment}},                     // "/* é"
+		{{0, 3, 3, Comment}, {5, 3, 7, Keyword}}, // "b */", "var"
+	}
+
+	matches := tokenMatches([]byte(src), TokenizeGo([]byte(src)), 4)
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("expected %v, got %v", expected, matches)
+	}
+}
+
+func TestTokenizeGoDocComments(t *testing.T) {
+	src := "// Doc\nfunc f() {} // Trailing\n\n// Separated\n\nvar x int"
+	var syntaxes []Syntax
+	for _, tok := range TokenizeGo([]byte(src)) {
+		syntaxes = append(syntaxes, tok.Syntax)
+	}
+
+	expected := []Syntax{DocComment, Keyword, Comment, Comment, Keyword, Type}
+	if !reflect.DeepEqual(syntaxes, expected) {
+		t.Errorf("expected %v, got %v", expected, syntaxes)
+	}
+}
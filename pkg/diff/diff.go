@@ -0,0 +1,158 @@
+// Package diff computes the differences between two sequences, like the lines
+// of two versions of a file, using the Myers algorithm.
+package diff
+
+// A Hunk is a region of changes between two sequences `a` and `b`. The elements
+// [OldStart, OldEnd) of `a` were replaced by the elements [NewStart, NewEnd) of
+// `b`. If OldStart equals OldEnd, the Hunk is a pure insertion. If NewStart
+// equals NewEnd, the Hunk is a pure deletion.
+type Hunk struct {
+	OldStart, OldEnd int
+	NewStart, NewEnd int
+}
+
+// IsInsertion returns true if the Hunk only adds elements.
+func (h Hunk) IsInsertion() bool {
+	return h.OldStart == h.OldEnd
+}
+
+// IsDeletion returns true if the Hunk only removes elements.
+func (h Hunk) IsDeletion() bool {
+	return h.NewStart == h.NewEnd
+}
+
+// Diff returns the Hunks that transform `a` into `b`, in ascending order. The
+// returned Hunks never overlap nor touch each other.
+func Diff[T comparable](a, b []T) []Hunk {
+	// Trim the common prefix and suffix. Edits in a text editor are usually
+	// small and local, so this makes most diffs nearly linear.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var hunks []Hunk
+	eq := myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+
+	// Walk the matching pairs, emitting a Hunk for each gap between them
+	i, j := 0, 0
+	for _, m := range append(eq, [2]int{len(a) - prefix - suffix, len(b) - prefix - suffix}) {
+		if m[0] > i || m[1] > j {
+			hunks = append(hunks, Hunk{prefix + i, prefix + m[0], prefix + j, prefix + m[1]})
+		}
+		i, j = m[0]+1, m[1]+1
+	}
+	return hunks
+}
+
+// myers returns the pairs of indexes of equal elements of `a` and `b` that make
+// up a longest common subsequence, in ascending order. It uses the linear space
+// variant of the algorithm: the middle snake of the shortest edit script is
+// found from both ends at once, and the parts before and after it are diffed in
+// turn. Only O(len(a)+len(b)) memory is used, however much the sequences differ.
+func myers[T comparable](a, b []T) [][2]int {
+	var eq [][2]int
+	lcs(a, b, 0, 0, &eq)
+	return eq
+}
+
+// lcs appends the pairs of equal elements of a longest common subsequence of `a`
+// and `b` to `eq`, in ascending order. The indexes are offset by `x` and `y`.
+func lcs[T comparable](a, b []T, x, y int, eq *[][2]int) {
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		*eq = append(*eq, [2]int{x, y})
+		a, b, x, y = a[1:], b[1:], x+1, y+1
+	}
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	if len(a) > 0 && len(b) > 0 {
+		// Both ends differ, so the snake is between two edits, and both parts are smaller
+		startX, startY, endX, endY := middleSnake(a, b)
+		lcs(a[:startX], b[:startY], x, y, eq)
+		for i := startX; i < endX; i++ {
+			*eq = append(*eq, [2]int{x + i, y + i - startX + startY})
+		}
+		lcs(a[endX:], b[endY:], x+endX, y+endY, eq)
+	}
+
+	for i := 0; i < suffix; i++ {
+		*eq = append(*eq, [2]int{x + len(a) + i, y + len(b) + i})
+	}
+}
+
+// middleSnake returns where the snake in the middle of a shortest edit script of
+// `a` and `b` starts and ends, by searching forward from the start and backward
+// from the end of the sequences until the searches overlap.
+func middleSnake[T comparable](a, b []T) (startX, startY, endX, endY int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	maxD := (n + m + 1) / 2
+	offset := maxD + 1
+	forward := make([]int, 2*maxD+3)  // The furthest x reached on each diagonal k = x - y
+	backward := make([]int, 2*maxD+3) // The same, counted from the ends of the sequences
+
+	for d := 0; d <= maxD; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && forward[offset+k-1] < forward[offset+k+1] {
+				x = forward[offset+k+1] // Move down (insertion)
+			} else {
+				x = forward[offset+k-1] + 1 // Move right (deletion)
+			}
+			y := x - k
+			sx, sy := x, y
+			for x < n && y < m && a[x] == b[y] { // Follow the diagonal
+				x, y = x+1, y+1
+			}
+			forward[offset+k] = x
+			// The backward search has taken d-1 steps, on the diagonals delta-k
+			if c := delta - k; odd && c >= -(d-1) && c <= d-1 && x+backward[offset+c] >= n {
+				return sx, sy, x, y
+			}
+		}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && backward[offset+k-1] < backward[offset+k+1] {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			sx, sy := x, y
+			for x < n && y < m && a[n-1-x] == b[m-1-y] {
+				x, y = x+1, y+1
+			}
+			backward[offset+k] = x
+			if c := delta - k; !odd && c >= -d && c <= d && x+forward[offset+c] >= n {
+				return n - x, m - y, n - sx, m - sy
+			}
+		}
+	}
+	panic("unreachable")
+}
+
+// SplitLines splits `text` into lines, keeping the line delimiters at the end
+// of each line. A final line without a delimiter is included if it is not empty.
+func SplitLines(text []byte) []string {
+	var lines []string
+	start := 0
+	for i, c := range text {
+		if c == '\n' {
+			lines = append(lines, string(text[start:i+1]))
+			start = i + 1
+		}
+	}
+	if start < len(text) {
+		lines = append(lines, string(text[start:]))
+	}
+	return lines
+}
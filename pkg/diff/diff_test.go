@@ -0,0 +1,146 @@
+package diff
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// apply reconstructs `b` from `a` and the hunks of Diff(a, b).
+func apply(a, b []string, hunks []Hunk) []string {
+	var result []string
+	i := 0
+	for _, h := range hunks {
+		result = append(result, a[i:h.OldStart]...)
+		result = append(result, b[h.NewStart:h.NewEnd]...)
+		i = h.OldEnd
+	}
+	return append(result, a[i:]...)
+}
+
+func TestDiffHunks(t *testing.T) {
+	a := []string{"a", "b", "c", "d", "e"}
+	b := []string{"a", "x", "c", "e", "f"}
+
+	hunks := Diff(a, b)
+	expected := []Hunk{{1, 2, 1, 2}, {3, 4, 3, 3}, {5, 5, 4, 5}}
+	if !reflect.DeepEqual(hunks, expected) {
+		t.Errorf("Expected hunks %v, got %v", expected, hunks)
+	}
+
+	if !hunks[2].IsInsertion() || !hunks[1].IsDeletion() || hunks[0].IsInsertion() || hunks[0].IsDeletion() {
+		t.Errorf("Hunk kinds were not classified correctly: %v", hunks)
+	}
+}
+
+func TestDiffApply(t *testing.T) {
+	cases := [][2]string{
+		{"", ""},
+		{"", "abc"},
+		{"abc", ""},
+		{"abcabba", "cbabac"},
+		{"the quick brown fox", "the slow brown dog"},
+		{"aaaa", "aaaaaa"},
+		{"xyz", "xyz"},
+	}
+
+	for _, c := range cases {
+		a, b := strings.Split(c[0], ""), strings.Split(c[1], "")
+		hunks := Diff(a, b)
+		if result := apply(a, b, hunks); strings.Join(result, "") != c[1] {
+			t.Errorf("Diff(%q, %q) applied to %q, hunks %v", c[0], c[1], strings.Join(result, ""), hunks)
+		}
+		for i := 1; i < len(hunks); i++ {
+			if hunks[i].OldStart <= hunks[i-1].OldEnd || hunks[i].NewStart <= hunks[i-1].NewEnd {
+				t.Errorf("Diff(%q, %q) hunks overlap or touch: %v", c[0], c[1], hunks)
+			}
+		}
+	}
+
+	if hunks := Diff(strings.Split("abcabba", ""), strings.Split("cbabac", "")); len(hunks) == 0 {
+		t.Errorf("Expected differences")
+	}
+}
+
+// lcsLength returns the length of a longest common subsequence of `a` and `b`.
+func lcsLength(a, b []string) int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(cur[j], prev[j+1])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func TestDiffMinimal(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := func() []string {
+		s := make([]string, rng.Intn(20))
+		for i := range s {
+			s[i] = string(rune('a' + rng.Intn(3)))
+		}
+		return s
+	}
+
+	for i := 0; i < 500; i++ {
+		a, b := random(), random()
+		hunks := Diff(a, b)
+		if result := apply(a, b, hunks); strings.Join(result, "") != strings.Join(b, "") {
+			t.Fatalf("Diff(%q, %q) applied to %q", a, b, result)
+		}
+		removed := 0
+		for _, h := range hunks {
+			removed += h.OldEnd - h.OldStart
+		}
+		if common := len(a) - removed; common != lcsLength(a, b) {
+			t.Fatalf("Diff(%q, %q) kept %d elements, expected %d: %v", a, b, common, lcsLength(a, b), hunks)
+		}
+	}
+}
+
+func TestDiffLarge(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	a := make([]string, 10000)
+	for i := range a {
+		a[i] = fmt.Sprintf("line %d\n", i)
+	}
+	b := append([]string(nil), a...)
+	for i := 0; i < 300; i++ { // Replace random lines
+		b[rng.Intn(len(b))] = fmt.Sprintf("edit %d\n", i)
+	}
+
+	hunks := Diff(a, b)
+	if len(hunks) > 300 || len(hunks) == 0 {
+		t.Errorf("Expected at most 300 hunks, got %d", len(hunks))
+	}
+	if result := apply(a, b, hunks); !reflect.DeepEqual(result, b) {
+		t.Errorf("Expected the hunks to reconstruct b")
+	}
+	changed := 0
+	for _, h := range hunks {
+		changed += h.OldEnd - h.OldStart
+	}
+	if changed > 300 {
+		t.Errorf("Expected at most 300 lines changed, got %d", changed)
+	}
+}
+
+func TestSplitLines(t *testing.T) {
+	lines := SplitLines([]byte("one\ntwo\r\n\nthree"))
+	expected := []string{"one\n", "two\r\n", "\n", "three"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Expected %q, got %q", expected, lines)
+	}
+
+	if lines := SplitLines([]byte("end\n")); len(lines) != 1 {
+		t.Errorf("Expected one line, got %q", lines)
+	}
+}
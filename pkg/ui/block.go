@@ -0,0 +1,264 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/fivemoreminix/qedit/pkg/buffer"
+	"github.com/gdamore/tcell/v2"
+)
+
+// A columnBlock is a rectangular selection between two corners, each a line and a
+// display column. Display columns count the cells a line is drawn in, so the
+// block is the same width on every line, no matter its tabs or wide runes. The block
+// may extend past the end of a line.
+type columnBlock struct {
+	anchorLine, anchorX int // The corner the block was started at
+	line, x             int // The corner at the cursor
+}
+
+// getBlockLines returns the first and last lines of the block.
+func (t *TextEdit) getBlockLines() (top, bottom int) {
+	last := t.Buffer.Lines() - 1
+	top, bottom = Min(t.block.anchorLine, t.block.line), Max(t.block.anchorLine, t.block.line)
+	return Min(top, last), Min(bottom, last)
+}
+
+// getBlockCols returns the left and right display columns of the block. The
+// right column is exclusive, so the block is empty if they are equal.
+func (t *TextEdit) getBlockCols() (left, right int) {
+	return Min(t.block.anchorX, t.block.x), Max(t.block.anchorX, t.block.x)
+}
+
+// getBlockColsOnLine returns the columns of the runes of `line` within the block.
+// A grapheme cluster partly within the block, like a tab or a wide rune, is
+// included. The end is exclusive, so no rune is included if they are equal.
+func (t *TextEdit) getBlockColsOnLine(line int) (start, end int) {
+	left, right := t.getBlockCols()
+	start, end = -1, -1
+	var length int
+	t.eachGrapheme(line, func(col, x, runes, width int) bool {
+		if start < 0 && x+width > left {
+			start = col
+		}
+		if end < 0 && x >= right {
+			end = col
+		}
+		length = col + runes
+		return false
+	})
+	if start < 0 {
+		start = length
+	}
+	if end < 0 {
+		end = length
+	}
+	return start, Max(start, end)
+}
+
+// IsBlockSelecting returns whether the selection is a rectangular block.
+func (t *TextEdit) IsBlockSelecting() bool {
+	return t.blockMode
+}
+
+// startBlock begins a block selection at the cursor, if there is not one already.
+func (t *TextEdit) startBlock() {
+	if t.blockMode {
+		return
+	}
+	t.RemoveExtraCursors()
+	t.selectMode = false
+	line, col := t.cursor.GetLineCol()
+	x := t.GetDisplayCol(line, col)
+	t.block = columnBlock{line, x, line, x}
+	t.blockMode = true
+}
+
+// moveBlock moves the corner of the block at the cursor, and the cursor with it.
+func (t *TextEdit) moveBlock(line, x int) {
+	t.block.line = Clamp(line, 0, t.Buffer.Lines()-1)
+	t.block.x = Max(0, x)
+	t.cursor = t.cursor.SetLineCol(t.block.line, t.GetColAtDisplayCol(t.block.line, t.block.x))
+	t.ScrollToCursor()
+	t.updateCursorVisibility()
+}
+
+// getBlockSelectionOnLine returns the Region of the runes of `line` within the
+// block, or false if there are none.
+func (t *TextEdit) getBlockSelectionOnLine(line int) (buffer.Region, bool) {
+	if top, bottom := t.getBlockLines(); !t.blockMode || line < top || line > bottom {
+		return buffer.Region{}, false
+	}
+	start, end := t.getBlockColsOnLine(line)
+	if start == end {
+		return buffer.Region{}, false
+	}
+	cursor := buffer.NewCursor(&t.Buffer)
+	return buffer.Region{Start: cursor.SetLineCol(line, start), End: cursor.SetLineCol(line, end-1)}, true
+}
+
+// getBlockBytes returns the text of the block, with each of its lines ending
+// with the line delimiter of the file.
+func (t *TextEdit) getBlockBytes() []byte {
+	var text []byte
+	top, bottom := t.getBlockLines()
+	for line := top; line <= bottom; line++ {
+		if start, end := t.getBlockColsOnLine(line); start < end {
+			text = append(text, t.Buffer.Slice(line, start, line, end-1)...)
+		}
+		text = append(text, t.GetLineDelimiter()...)
+	}
+	return text
+}
+
+// blockToCursors deletes the contents of the block, and ends the block with a
+// cursor at its left column on each of its lines, so typing edits every line.
+// Returns whether anything was deleted.
+func (t *TextEdit) blockToCursors() bool {
+	top, bottom := t.getBlockLines()
+	cursorLine := Clamp(t.block.line, top, bottom)
+	t.blockMode = false
+
+	var deleted bool
+	for line := top; line <= bottom; line++ {
+		start, end := t.getBlockColsOnLine(line)
+		if start < end {
+			t.Buffer.Remove(line, start, line, end-1)
+			deleted = true
+		}
+		cursor := buffer.NewCursor(&t.Buffer).SetLineCol(line, start)
+		if line == cursorLine {
+			t.cursor = cursor
+		} else {
+			t.addCaret(&caret{cursor: cursor, selection: buffer.NewRegion(&t.Buffer)})
+		}
+	}
+	return deleted
+}
+
+// InsertBlock inserts each line of `contents` on consecutive lines, beginning
+// at the cursor, at the display column of the cursor. Lines ending before that
+// column are padded with spaces, and lines are added to the end of the buffer if
+// there are too few. Any selection is deleted first, and for a block selection,
+// the contents are inserted at its top left corner.
+func (t *TextEdit) InsertBlock(contents string) {
+	if t.blockMode {
+		top, _ := t.getBlockLines()
+		left, _ := t.getBlockCols()
+		t.blockToCursors()
+		t.RemoveExtraCursors()
+		t.cursor = t.cursor.SetLineCol(top, t.getColAfterDisplayCol(top, left))
+	} else if t.selectMode {
+		t.delete(true)
+	}
+	t.RemoveExtraCursors()
+
+	contents = strings.ReplaceAll(contents, "\r\n", "\n")
+	contents = strings.TrimSuffix(contents, "\n")
+
+	line, col := t.cursor.GetLineCol()
+	x := t.GetDisplayCol(line, col)
+	for i, text := range strings.Split(contents, "\n") {
+		if line+i >= t.Buffer.Lines() {
+			last := t.Buffer.Lines() - 1
+			t.Buffer.Insert(last, t.Buffer.RunesInLine(last), []byte(t.GetLineDelimiter()))
+		}
+		col := t.getColAfterDisplayCol(line+i, x)
+		padding := Max(0, x-t.GetDisplayCol(line+i, col))
+		t.Buffer.Insert(line+i, col, []byte(strings.Repeat(" ", padding)+text))
+	}
+
+	t.ScrollToCursor()
+	t.updateCursorVisibility()
+}
+
+// handleBlockKey handles the keys that extend a block selection, which are the
+// arrow keys with Alt and Shift, and the keys that edit one. Other keys end the
+// block selection. Returns whether the key was handled.
+func (t *TextEdit) handleBlockKey(ev *tcell.EventKey) bool {
+	if ev.Modifiers()&(tcell.ModAlt|tcell.ModShift) == tcell.ModAlt|tcell.ModShift {
+		switch ev.Key() {
+		case tcell.KeyUp, tcell.KeyDown, tcell.KeyLeft, tcell.KeyRight:
+			t.startBlock()
+		}
+		switch ev.Key() {
+		case tcell.KeyUp:
+			t.moveBlock(t.block.line-1, t.block.x)
+			return true
+		case tcell.KeyDown:
+			t.moveBlock(t.block.line+1, t.block.x)
+			return true
+		case tcell.KeyLeft:
+			t.moveBlock(t.block.line, t.block.x-1)
+			return true
+		case tcell.KeyRight:
+			t.moveBlock(t.block.line, t.block.x+1)
+			return true
+		}
+	}
+
+	if !t.blockMode {
+		return false
+	}
+	switch ev.Key() {
+	case tcell.KeyBackspace, tcell.KeyBackspace2, tcell.KeyDelete:
+		// Deleting the contents of the block is enough, unless it has none
+		if t.blockToCursors() {
+			t.mergeCarets()
+			t.updateCursorVisibility()
+			return true
+		}
+	case tcell.KeyRune, tcell.KeyTab, tcell.KeyEnter:
+		t.blockToCursors() // Typing is done at every line of the block
+	default:
+		t.blockMode = false
+	}
+	return false
+}
+
+// handleBlockMouse selects a block while the mouse is dragged with the primary
+// button and Alt. Returns whether the event was handled.
+func (t *TextEdit) handleBlockMouse(ev *tcell.EventMouse) bool {
+	if ev.Buttons()&tcell.Button1 == 0 {
+		t.dragging = false
+		return false
+	}
+
+	mx, my := ev.Position()
+	columnWidth := t.getColumnWidth()
+	if !t.dragging && (ev.Modifiers()&tcell.ModAlt == 0 ||
+		mx < t.x+columnWidth || mx >= t.x+t.width || my < t.y || my >= t.y+t.height) {
+		return false
+	}
+
+	line, left := t.getLineAtViewRow(my - t.y)
+	x := mx - t.x - columnWidth + left
+	if !t.dragging {
+		t.dragging = true
+		t.blockMode = false // Begin a new block where the mouse was pressed
+		t.moveBlock(line, x)
+		t.startBlock()
+		t.block.anchorX = Max(0, x) // The press may be past the end of the line
+	}
+	t.moveBlock(line, x)
+	return true
+}
+
+// drawBlockCursors draws a cursor at the column of a block that has no width, on
+// each of its lines other than the line of the primary cursor, at screen row `y`,
+// if the column is on `row` of `line`.
+func (t *TextEdit) drawBlockCursors(s tcell.Screen, y, line, row int) {
+	left, right := t.getBlockCols()
+	if top, bottom := t.getBlockLines(); !t.blockMode || left != right || line < top || line > bottom || line == t.block.line {
+		return
+	}
+	blockRow, viewLeft := t.getRowAt(line, t.getColAfterDisplayCol(line, left))
+	if blockRow != row {
+		return
+	}
+	columnWidth := t.getColumnWidth()
+	x := t.x + columnWidth + left - viewLeft
+	if x >= t.x+columnWidth && x < t.x+t.width {
+		r, combc, _, _ := s.GetContent(x, y)
+		s.SetContent(x, y, r, combc, t.theme.GetOrDefault("TextEditCursor"))
+	}
+}
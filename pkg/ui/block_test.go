@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// selectBlock selects a block with Alt+Shift and the arrow keys, beginning at
+// the cursor.
+func selectBlock(te *TextEdit, keys ...tcell.Key) {
+	for _, key := range keys {
+		te.HandleEvent(tcell.NewEventKey(key, 0, tcell.ModAlt|tcell.ModShift))
+	}
+}
+
+func TestBlockKeys(t *testing.T) {
+	te := newTestTextEdit("abcd\nef\nghij\n")
+	selectBlock(te, tcell.KeyUp, tcell.KeyLeft) // Stays within the buffer
+	if !te.IsBlockSelecting() {
+		t.Fatalf("Expected a block selection")
+	}
+	if te.block != (columnBlock{0, 0, 0, 0}) {
+		t.Errorf("Expected the block to stay at 0, 0, got %v", te.block)
+	}
+
+	selectBlock(te, tcell.KeyRight, tcell.KeyDown, tcell.KeyDown, tcell.KeyDown, tcell.KeyRight, tcell.KeyRight, tcell.KeyLeft)
+	if te.block != (columnBlock{0, 0, 3, 2}) {
+		t.Errorf("Expected the block from 0, 0 to 3, 2, got %v", te.block)
+	}
+	if line, col := te.cursor.GetLineCol(); line != 3 || col != 0 {
+		t.Errorf("Expected the cursor at 3, 0, got %d, %d", line, col)
+	}
+
+	te.HandleEvent(tcell.NewEventKey(tcell.KeyRight, 0, tcell.ModNone))
+	if te.IsBlockSelecting() {
+		t.Errorf("Expected other keys to end the block selection")
+	}
+}
+
+func TestBlockCopyCut(t *testing.T) {
+	te := newTestTextEdit("abcd\nef\nghij\n")
+	te.cursor = te.cursor.SetLineCol(0, 1)
+	selectBlock(te, tcell.KeyDown, tcell.KeyDown, tcell.KeyRight, tcell.KeyRight)
+
+	if bytes := string(te.GetSelectedBytes()); bytes != "bc\nf\nhi\n" {
+		t.Errorf("Expected to copy \"bc\\nf\\nhi\\n\", got %q", bytes)
+	}
+
+	te.Delete(false) // Cut
+	if contents := string(te.Buffer.Bytes()); contents != "ad\ne\ngj\n" {
+		t.Errorf("Expected \"ad\\ne\\ngj\\n\" after cutting, got %q", contents)
+	}
+	if te.IsBlockSelecting() {
+		t.Errorf("Expected the block selection to end")
+	}
+	if p, expected := getCursorPositions(te), [][2]int{{0, 1}, {1, 1}, {2, 1}}; !reflect.DeepEqual(p, expected) {
+		t.Errorf("Expected cursors at %v, got %v", expected, p)
+	}
+}
+
+func TestBlockType(t *testing.T) {
+	tests := []struct {
+		keys     []tcell.Key
+		expected string
+	}{
+		{[]tcell.Key{tcell.KeyDown, tcell.KeyDown}, "aXbcd\neXf\ngXhij\n"},                            // An empty block
+		{[]tcell.Key{tcell.KeyDown, tcell.KeyDown, tcell.KeyRight, tcell.KeyRight}, "aXd\neX\ngXj\n"}, // Typing replaces the block
+		{[]tcell.Key{tcell.KeyDown, tcell.KeyRight, tcell.KeyRight, tcell.KeyRight, tcell.KeyRight}, "aX\neX\nghij\n"},
+	}
+	for _, test := range tests {
+		te := newTestTextEdit("abcd\nef\nghij\n")
+		te.cursor = te.cursor.SetLineCol(0, 1)
+		selectBlock(te, test.keys...)
+		te.HandleEvent(tcell.NewEventKey(tcell.KeyRune, 'X', tcell.ModNone))
+		if contents := string(te.Buffer.Bytes()); contents != test.expected {
+			t.Errorf("Expected %q, got %q", test.expected, contents)
+		}
+	}
+}
+
+func TestInsertBlock(t *testing.T) {
+	tests := []struct {
+		contents  string
+		line, col int
+		block     string
+		expected  string
+	}{
+		{"abc\nx\n\n", 0, 2, "12\n34\n56\n", "ab12c\nx 34\n  56\n"}, // Short lines are padded
+		{"abc\nx", 0, 2, "12\n34\n56\n", "ab12c\nx 34\n  56"},       // Lines are added at the end
+		{"abc\r\ndef\r\n", 1, 0, "1\r\n2\r\n", "abc\r\n1def\r\n2"},  // With the delimiter of the file
+		{"\tab\nabcdef\n", 0, 1, "1\n2\n", "\t1ab\nabcd2ef\n"},      // At the display column of the cursor
+	}
+	for _, test := range tests {
+		te := newTestTextEdit(test.contents)
+		te.cursor = te.cursor.SetLineCol(test.line, test.col)
+		te.InsertBlock(test.block)
+		if contents := string(te.Buffer.Bytes()); contents != test.expected {
+			t.Errorf("Pasting %q into %q: expected %q, got %q", test.block, test.contents, test.expected, contents)
+		}
+	}
+
+	// Pasting over a block replaces it, beginning at its top left corner
+	te := newTestTextEdit("abcd\nef\nghij\n")
+	te.cursor = te.cursor.SetLineCol(0, 3)
+	selectBlock(te, tcell.KeyDown, tcell.KeyLeft, tcell.KeyLeft)
+	te.InsertBlock("12\n34\n")
+	if contents := string(te.Buffer.Bytes()); contents != "a12d\ne34\nghij\n" {
+		t.Errorf("Expected \"a12d\\ne34\\nghij\\n\", got %q", contents)
+	}
+}
@@ -0,0 +1,129 @@
+package ui
+
+import (
+	"unicode"
+
+	"github.com/fivemoreminix/qedit/pkg/buffer"
+)
+
+// autoPairQuotes are the quotes closed by AutoPair, besides brackets.
+var autoPairQuotes = map[rune]bool{'"': true, '\'': true, '`': true}
+
+// getAutoPair returns the rune that closes `r`, if `r` is an opening bracket or a
+// quote.
+func getAutoPair(r rune) (rune, bool) {
+	if autoPairQuotes[r] {
+		return r, true
+	}
+	closing, ok := buffer.BracketPairs[r]
+	return closing, ok
+}
+
+// isClosingBracket returns whether the rune closes a bracket.
+func isClosingBracket(r rune) bool {
+	for _, closing := range buffer.BracketPairs {
+		if r == closing {
+			return true
+		}
+	}
+	return false
+}
+
+// getBracketPair returns the positions of the brackets of the pair at the
+// cursor, for drawing them, or -1 lines if there is no pair.
+func (t *TextEdit) getBracketPair() (startLine, startCol, endLine, endCol int) {
+	pair, ok := t.cursor.BracketPair(t.Highlighter)
+	if !ok {
+		return -1, -1, -1, -1
+	}
+	startLine, startCol = pair.Start.GetLineCol()
+	endLine, endCol = pair.End.GetLineCol()
+	return startLine, startCol, endLine, endCol
+}
+
+// JumpToMatchingBracket moves the cursor to the bracket matching the bracket at
+// the cursor, or before it.
+func (t *TextEdit) JumpToMatchingBracket() {
+	pair, ok := t.cursor.BracketPair(t.Highlighter)
+	if !ok {
+		return
+	}
+	to := pair.Start
+	if t.cursor.Eq(pair.Start) || !t.cursor.Eq(pair.End) && t.cursor.Left().Eq(pair.Start) {
+		to = pair.End // The opening bracket is at or before the cursor
+	}
+	t.selectMode = false
+	t.SetCursor(to)
+	t.ScrollToCursor()
+}
+
+// getRuneAt returns the rune at line, col, or zero if there is none.
+func (t *TextEdit) getRuneAt(line, col int) rune {
+	if col < 0 {
+		return 0
+	}
+	return t.Buffer.RuneAtPos(t.Buffer.LineColToPos(line, col))
+}
+
+// autoPair types `contents` when it is a bracket or quote and AutoPair is on. An
+// opening bracket or quote is inserted with its closing one, or around the
+// selection, and typing the closing one when it is at the cursor moves over it.
+// Returns whether `contents` was typed.
+func (t *TextEdit) autoPair(contents string) bool {
+	runes := []rune(contents)
+	if !t.AutoPair || len(runes) != 1 {
+		return false
+	}
+	r := runes[0]
+	closing, opens := getAutoPair(r)
+
+	if t.selectMode {
+		if !opens {
+			return false // The selection is replaced
+		}
+		// Surround the selection; its anchors move with the inserted text
+		after := t.selection.End.Right()
+		afterLine, afterCol := after.GetLineCol()
+		if after.Eq(t.selection.End) { // At the end of the buffer
+			afterCol++
+		}
+		startLine, startCol := t.selection.Start.GetLineCol()
+		t.Buffer.Insert(afterLine, afterCol, []byte(string(closing)))
+		t.Buffer.Insert(startLine, startCol, []byte(string(r)))
+		return true
+	}
+
+	line, col := t.cursor.GetLineCol()
+	next := t.getRuneAt(line, col)
+	if next == r && (isClosingBracket(r) || autoPairQuotes[r]) {
+		t.SetCursor(t.cursor.Right()) // Type over the closing bracket or quote
+		t.ScrollToCursor()
+		return true
+	}
+	if !opens || next != 0 && !unicode.IsSpace(next) && !isClosingBracket(next) {
+		return false // Only close when the cursor is not before text
+	}
+	if prev := t.getRuneAt(line, col-1); autoPairQuotes[r] && (prev == '_' || unicode.IsLetter(prev) || unicode.IsDigit(prev)) {
+		return false // Like the apostrophe of "don't"
+	}
+
+	t.Buffer.Insert(line, col, []byte(string(r)+string(closing)))
+	t.cursor = t.cursor.Left() // Between the pair
+	t.ScrollToCursor()
+	t.updateCursorVisibility()
+	return true
+}
+
+// deletePair deletes an empty pair of brackets or quotes around the cursor, if
+// AutoPair is on. Returns whether it did.
+func (t *TextEdit) deletePair() bool {
+	line, col := t.cursor.GetLineCol()
+	if !t.AutoPair || col == 0 {
+		return false
+	}
+	if closing, ok := getAutoPair(t.getRuneAt(line, col-1)); !ok || t.getRuneAt(line, col) != closing {
+		return false
+	}
+	t.Buffer.Remove(line, col-1, line, col)
+	return true
+}
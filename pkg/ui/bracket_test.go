@@ -0,0 +1,25 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestAutoPairTypedOnly(t *testing.T) {
+	te := newTestTextEdit("")
+	te.HandleEvent(tcell.NewEventKey(tcell.KeyRune, '(', tcell.ModNone))
+	if contents := string(te.Buffer.Bytes()); contents != "()" {
+		t.Errorf("Expected typing a bracket to close it, got %q", contents)
+	}
+	te.HandleEvent(tcell.NewEventKey(tcell.KeyRune, ')', tcell.ModNone))
+	if contents := string(te.Buffer.Bytes()); contents != "()" {
+		t.Errorf("Expected typing the closing bracket to move over it, got %q", contents)
+	}
+
+	te.Insert("(") // Like a paste
+	te.Insert(")")
+	if contents := string(te.Buffer.Bytes()); contents != "()()" {
+		t.Errorf("Expected inserted brackets to be left alone, got %q", contents)
+	}
+}
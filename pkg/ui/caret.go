@@ -0,0 +1,264 @@
+package ui
+
+import (
+	"unicode/utf8"
+
+	"github.com/fivemoreminix/qedit/pkg/buffer"
+	"github.com/gdamore/tcell/v2"
+)
+
+// A caret is a cursor of a TextEdit, other than its primary cursor, with its own
+// selection. Its cursor and selection are registered with the Buffer, so they
+// move with edits like the primary cursor does.
+type caret struct {
+	cursor     buffer.Cursor
+	selection  buffer.Region
+	selectMode bool
+}
+
+// addCaret adds a cursor to the TextEdit, which is edited with the primary one.
+func (t *TextEdit) addCaret(c *caret) {
+	t.Buffer.RegisterCursor(&c.cursor)
+	t.Buffer.RegisterCursor(&c.selection.Start)
+	t.Buffer.RegisterCursor(&c.selection.End)
+	t.carets = append(t.carets, c)
+}
+
+// removeCaret removes the caret at index `i` of the carets.
+func (t *TextEdit) removeCaret(i int) {
+	c := t.carets[i]
+	t.Buffer.UnregisterCursor(&c.cursor)
+	t.Buffer.UnregisterCursor(&c.selection.Start)
+	t.Buffer.UnregisterCursor(&c.selection.End)
+	t.carets = append(t.carets[:i], t.carets[i+1:]...)
+}
+
+// swapCaret exchanges the primary cursor and selection with those of the caret.
+// Anchors are registered by address, so the positions swap, too.
+func (t *TextEdit) swapCaret(c *caret) {
+	t.cursor, c.cursor = c.cursor, t.cursor
+	t.selection, c.selection = c.selection, t.selection
+	t.selectMode, c.selectMode = c.selectMode, t.selectMode
+}
+
+// eachCaret calls f once for every cursor, with that cursor as the primary one,
+// so f can use the single cursor methods of the TextEdit. The primary cursor is
+// last, so the view only scrolls for it.
+func (t *TextEdit) eachCaret(f func()) {
+	scrollx, scrolly := t.scrollx, t.scrolly
+	for _, c := range t.carets {
+		t.swapCaret(c)
+		f()
+		t.swapCaret(c)
+	}
+	t.scrollx, t.scrolly = scrollx, scrolly
+	f()
+}
+
+// mergeCarets removes any caret at the same position as another cursor, or whose
+// selection overlaps the selection of another. The primary cursor is always kept.
+func (t *TextEdit) mergeCarets() {
+	kept := []*caret{{t.cursor, t.selection, t.selectMode}}
+	for i := 0; i < len(t.carets); {
+		c := t.carets[i]
+		collides := false
+		for _, k := range kept {
+			if c.cursor.Eq(k.cursor) || c.selectMode && k.selectMode && regionsOverlap(c.selection, k.selection) {
+				collides = true
+				break
+			}
+		}
+		if collides {
+			t.removeCaret(i)
+		} else {
+			kept = append(kept, c)
+			i++
+		}
+	}
+}
+
+// regionsOverlap returns whether two regions share any rune. Region bounds are inclusive.
+func regionsOverlap(a, b buffer.Region) bool {
+	return !a.End.Less(b.Start) && !b.End.Less(a.Start)
+}
+
+// regionContains returns whether the rune at line, col is in the Region.
+func regionContains(r buffer.Region, line, col int) bool {
+	startLine, startCol := r.Start.GetLineCol()
+	endLine, endCol := r.End.GetLineCol()
+	if line < startLine || line > endLine {
+		return false
+	}
+	return (line != startLine || col >= startCol) && (line != endLine || col <= endCol)
+}
+
+// getSelectionsOnLine returns the selections of every cursor that include `line`.
+func (t *TextEdit) getSelectionsOnLine(line int) []buffer.Region {
+	var selections []buffer.Region
+	add := func(selectMode bool, r buffer.Region) {
+		startLine, _ := r.Start.GetLineCol()
+		endLine, _ := r.End.GetLineCol()
+		if selectMode && line >= startLine && line <= endLine {
+			selections = append(selections, r)
+		}
+	}
+	add(t.selectMode, t.selection)
+	if r, ok := t.getBlockSelectionOnLine(line); ok {
+		selections = append(selections, r)
+	}
+	for _, c := range t.carets {
+		add(c.selectMode, c.selection)
+	}
+	return selections
+}
+
+// drawCaretsOnLine draws the cursors, other than the primary cursor, which are on
+// `row` of `line`, at the screen row `y`. The terminal only shows the primary cursor.
+func (t *TextEdit) drawCaretsOnLine(s tcell.Screen, y, line, row int) {
+	cursorStyle := t.theme.GetOrDefault("TextEditCursor")
+	columnWidth := t.getColumnWidth()
+	for _, c := range t.carets {
+		cursLine, cursCol := c.cursor.GetLineCol()
+		if cursLine != line {
+			continue
+		}
+		cursRow, left := t.getRowAt(line, cursCol)
+		if cursRow != row {
+			continue
+		}
+		x := t.x + columnWidth + t.GetDisplayCol(line, cursCol) - left
+		if x >= t.x+columnWidth && x < t.x+t.width {
+			r, combc, _, _ := s.GetContent(x, y)
+			s.SetContent(x, y, r, combc, cursorStyle)
+		}
+	}
+}
+
+// GetCursorCount returns the number of cursors, including the primary cursor.
+func (t *TextEdit) GetCursorCount() int {
+	return 1 + len(t.carets)
+}
+
+// RemoveExtraCursors removes every cursor except the primary cursor.
+func (t *TextEdit) RemoveExtraCursors() {
+	for len(t.carets) > 0 {
+		t.removeCaret(len(t.carets) - 1)
+	}
+}
+
+// AddCursorAbove adds a cursor on the line above the topmost cursor.
+func (t *TextEdit) AddCursorAbove() {
+	top := t.cursor
+	for _, c := range t.carets {
+		if c.cursor.Less(top) {
+			top = c.cursor
+		}
+	}
+	if line, _ := top.GetLineCol(); line > 0 {
+		t.addCursorAt(top.Up())
+	}
+}
+
+// AddCursorBelow adds a cursor on the line below the bottommost cursor.
+func (t *TextEdit) AddCursorBelow() {
+	bottom := t.cursor
+	for _, c := range t.carets {
+		if bottom.Less(c.cursor) {
+			bottom = c.cursor
+		}
+	}
+	if line, _ := bottom.GetLineCol(); line < t.Buffer.Lines()-1 {
+		t.addCursorAt(bottom.Down())
+	}
+}
+
+// addCursorAt adds a cursor without a selection, and scrolls to it.
+func (t *TextEdit) addCursorAt(cursor buffer.Cursor) {
+	c := &caret{cursor: cursor, selection: buffer.NewRegion(&t.Buffer)}
+	t.addCaret(c)
+	t.mergeCarets()
+	t.scrollToCaret(c)
+}
+
+// scrollToCaret scrolls the view to the cursor of the caret.
+func (t *TextEdit) scrollToCaret(c *caret) {
+	t.swapCaret(c)
+	t.ScrollToCursor()
+	t.swapCaret(c)
+}
+
+// AddNextOccurrence selects the word at the cursor, if nothing is selected.
+// Otherwise, it adds a cursor selecting the next occurrence of the selected text
+// after the last cursor added, wrapping around to the start of the buffer.
+func (t *TextEdit) AddNextOccurrence() {
+	if !t.selectMode {
+		word, ok := t.cursor.Word()
+		if !ok {
+			return
+		}
+		t.selection.Start, t.selection.End = word.Start, word.End
+		t.cursor = word.End
+		t.selectMode = true
+		t.updateCursorVisibility()
+		return
+	}
+
+	text := t.GetSelectedBytes()
+	last := t.selection
+	if len(t.carets) > 0 {
+		last = t.carets[len(t.carets)-1].selection
+	}
+	endLine, endCol := last.End.GetLineCol()
+	from := t.Buffer.LineColToPos(endLine, endCol)
+	from += utf8.RuneLen(t.Buffer.RuneAtPos(from))
+
+	pos := buffer.Index(t.Buffer, from, text)
+	if pos < 0 {
+		pos = buffer.Index(t.Buffer, 0, text) // Wrap around
+	}
+	if pos < 0 {
+		return
+	}
+
+	_, lastSize := utf8.DecodeLastRune(text)
+	c := &caret{selection: buffer.NewRegion(&t.Buffer), selectMode: true}
+	c.selection.Start = c.selection.Start.SetLineCol(t.Buffer.PosToLineCol(pos))
+	c.selection.End = c.selection.End.SetLineCol(t.Buffer.PosToLineCol(pos + len(text) - lastSize))
+	c.cursor = c.selection.End
+	t.addCaret(c)
+	t.mergeCarets()
+	t.scrollToCaret(c)
+}
+
+// SplitSelectionIntoLines replaces every selection with a cursor at the end of
+// each line it spans. On its last line, the cursor is placed after the selection.
+func (t *TextEdit) SplitSelectionIntoLines() {
+	type lineCol struct{ line, col int }
+	var positions []lineCol
+	t.eachCaret(func() {
+		line, col := t.cursor.GetLineCol()
+		if !t.selectMode {
+			positions = append(positions, lineCol{line, col})
+			return
+		}
+		startLine, _ := t.selection.Start.GetLineCol()
+		endLine, endCol := t.selection.End.GetLineCol()
+		for line := startLine; line < endLine; line++ {
+			positions = append(positions, lineCol{line, t.Buffer.RunesInLine(line)})
+		}
+		positions = append(positions, lineCol{endLine, endCol + 1}) // Clamped by SetLineCol
+	})
+
+	// The primary cursor was visited last, so its last line keeps it
+	t.RemoveExtraCursors()
+	t.selectMode = false
+	primary := positions[len(positions)-1]
+	t.cursor = t.cursor.SetLineCol(primary.line, primary.col)
+	for _, p := range positions[:len(positions)-1] {
+		c := &caret{cursor: buffer.NewCursor(&t.Buffer).SetLineCol(p.line, p.col), selection: buffer.NewRegion(&t.Buffer)}
+		t.addCaret(c)
+	}
+	t.mergeCarets()
+	t.ScrollToCursor()
+	t.updateCursorVisibility()
+}
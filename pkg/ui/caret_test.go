@@ -0,0 +1,146 @@
+package ui
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// getCursorPositions returns the line and column of every cursor, in order.
+func getCursorPositions(te *TextEdit) [][2]int {
+	var positions [][2]int
+	te.eachCaret(func() {
+		line, col := te.cursor.GetLineCol()
+		positions = append(positions, [2]int{line, col})
+	})
+	sort.Slice(positions, func(i, j int) bool {
+		return positions[i][0] < positions[j][0] || positions[i][0] == positions[j][0] && positions[i][1] < positions[j][1]
+	})
+	return positions
+}
+
+func TestCaretsInsertDelete(t *testing.T) {
+	te := newTestTextEdit("ab\ncd\nef\n")
+	te.cursor = te.cursor.SetLineCol(0, 1)
+	te.addCursorAt(te.cursor.SetLineCol(1, 1))
+	te.addCursorAt(te.cursor.SetLineCol(2, 1))
+	expect := func(contents string, positions [][2]int) {
+		t.Helper()
+		if str := string(te.Buffer.Bytes()); str != contents {
+			t.Errorf("Expected %q, got %q", contents, str)
+		}
+		if p := getCursorPositions(te); !reflect.DeepEqual(p, positions) {
+			t.Errorf("Expected cursors at %v, got %v", positions, p)
+		}
+	}
+
+	te.Insert("X")
+	expect("aXb\ncXd\neXf\n", [][2]int{{0, 2}, {1, 2}, {2, 2}})
+	te.Delete(false)
+	expect("ab\ncd\nef\n", [][2]int{{0, 1}, {1, 1}, {2, 1}})
+	te.Delete(true)
+	expect("a\nc\ne\n", [][2]int{{0, 1}, {1, 1}, {2, 1}})
+	te.Insert("\n")
+	expect("a\n\nc\n\ne\n\n", [][2]int{{1, 0}, {3, 0}, {5, 0}})
+}
+
+func TestMergeCarets(t *testing.T) {
+	te := newTestTextEdit("abc\n")
+	te.cursor = te.cursor.SetLineCol(0, 1)
+	te.addCursorAt(te.cursor.SetLineCol(0, 2))
+	te.addCursorAt(te.cursor.SetLineCol(0, 1)) // At the primary cursor
+	if count := te.GetCursorCount(); count != 2 {
+		t.Fatalf("Expected a cursor at the primary cursor to be merged, got %d cursors", count)
+	}
+
+	te.Delete(false) // Both cursors end up at the start of the line
+	if str := string(te.Buffer.Bytes()); str != "c\n" {
+		t.Errorf("Expected \"c\\n\", got %q", str)
+	}
+	if count := te.GetCursorCount(); count != 1 {
+		t.Errorf("Expected colliding cursors to be merged, got %d cursors", count)
+	}
+
+	te = newTestTextEdit("abcdef\n")
+	te.selectMode = true
+	te.selection.Start = te.selection.Start.SetLineCol(0, 0)
+	te.selection.End = te.selection.End.SetLineCol(0, 2)
+	te.cursor = te.cursor.SetLineCol(0, 3)
+	c := &caret{cursor: te.cursor.SetLineCol(0, 5), selection: te.selection, selectMode: true}
+	c.selection.Start = c.selection.Start.SetLineCol(0, 2) // Overlaps the primary selection
+	c.selection.End = c.selection.End.SetLineCol(0, 4)
+	te.addCaret(c)
+	te.mergeCarets()
+	if count := te.GetCursorCount(); count != 1 {
+		t.Errorf("Expected overlapping selections to be merged, got %d cursors", count)
+	}
+}
+
+func TestAddNextOccurrence(t *testing.T) {
+	te := newTestTextEdit("foo bar foo\nfoo\n")
+	te.cursor = te.cursor.SetLineCol(0, 9)
+	selections := func() [][2][2]int {
+		var s [][2][2]int
+		te.eachCaret(func() {
+			startLine, startCol := te.selection.Start.GetLineCol()
+			endLine, endCol := te.selection.End.GetLineCol()
+			s = append(s, [2][2]int{{startLine, startCol}, {endLine, endCol}})
+		})
+		return s
+	}
+
+	te.AddNextOccurrence() // Selects the word at the cursor
+	te.AddNextOccurrence()
+	te.AddNextOccurrence() // Wraps around to the start of the buffer
+	expected := [][2][2]int{{{1, 0}, {1, 2}}, {{0, 0}, {0, 2}}, {{0, 8}, {0, 10}}}
+	if s := selections(); !reflect.DeepEqual(s, expected) {
+		t.Errorf("Expected selections %v, got %v", expected, s)
+	}
+
+	te.AddNextOccurrence() // Every occurrence is selected, so the next one merges
+	if count := te.GetCursorCount(); count != 3 {
+		t.Errorf("Expected 3 cursors, got %d", count)
+	}
+}
+
+func TestSplitSelectionIntoLines(t *testing.T) {
+	te := newTestTextEdit("abc\ndef\nghi\n")
+	te.selectMode = true
+	te.selection.Start = te.selection.Start.SetLineCol(0, 1)
+	te.selection.End = te.selection.End.SetLineCol(2, 0)
+	te.cursor = te.cursor.SetLineCol(2, 1)
+
+	te.SplitSelectionIntoLines()
+	if te.selectMode {
+		t.Errorf("Expected no selection")
+	}
+	if p, expected := getCursorPositions(te), [][2]int{{0, 3}, {1, 3}, {2, 1}}; !reflect.DeepEqual(p, expected) {
+		t.Errorf("Expected cursors at %v, got %v", expected, p)
+	}
+	if line, col := te.cursor.GetLineCol(); line != 2 || col != 1 {
+		t.Errorf("Expected the primary cursor on the last line, at 2, 1, got %d, %d", line, col)
+	}
+}
+
+func TestAddCursorAboveBelow(t *testing.T) {
+	te := newTestTextEdit("ab\ncd\nef")
+	te.cursor = te.cursor.SetLineCol(0, 1)
+	te.AddCursorAbove() // Already on the first line
+	if count := te.GetCursorCount(); count != 1 {
+		t.Errorf("Expected no cursor above the first line, got %d cursors", count)
+	}
+
+	te.AddCursorBelow()
+	te.AddCursorBelow()
+	te.AddCursorBelow() // Already on the last line
+	if p, expected := getCursorPositions(te), [][2]int{{0, 1}, {1, 1}, {2, 1}}; !reflect.DeepEqual(p, expected) {
+		t.Errorf("Expected cursors at %v, got %v", expected, p)
+	}
+
+	te.RemoveExtraCursors()
+	te.cursor = te.cursor.SetLineCol(2, 0)
+	te.AddCursorAbove()
+	if p, expected := getCursorPositions(te), [][2]int{{1, 0}, {2, 0}}; !reflect.DeepEqual(p, expected) {
+		t.Errorf("Expected cursors at %v, got %v", expected, p)
+	}
+}
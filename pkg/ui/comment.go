@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// toggleComment comments or uncomments the lines from `start` to `end` with the
+// LineComment of the Language, or its BlockComment if it has no LineComment.
+func (t *TextEdit) toggleComment(start, end int) {
+	if t.Language == nil {
+		return
+	}
+	if token := t.Language.LineComment; token != "" {
+		t.toggleLineComment(start, end, token)
+	} else if open, close := t.Language.BlockComment[0], t.Language.BlockComment[1]; open != "" && close != "" {
+		t.toggleBlockComment(start, end, open, close)
+	}
+}
+
+// toggleLineComment removes the comment `token` from the start of each line from
+// `start` to `end`, if every line that is not blank begins with it. Otherwise,
+// every line that is not blank is commented, at the least indentation of them.
+func (t *TextEdit) toggleLineComment(start, end int, token string) {
+	commented := true
+	indent := -1
+	for line := start; line <= end; line++ {
+		text := t.getLineText(line)
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		space := getLeadingSpace(text)
+		if !strings.HasPrefix(text[len(space):], token) {
+			commented = false
+		}
+		if n := utf8.RuneCountInString(space); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	if indent < 0 { // Every line is blank
+		return
+	}
+
+	t.editLines(start, end, func(_ int, text string) []lineEdit {
+		if strings.TrimSpace(text) == "" {
+			return nil
+		}
+		if commented {
+			space := getLeadingSpace(text)
+			remove := utf8.RuneCountInString(token)
+			if strings.HasPrefix(text[len(space)+len(token):], " ") {
+				remove++
+			}
+			return []lineEdit{{col: utf8.RuneCountInString(space), remove: remove}}
+		}
+		return []lineEdit{{col: indent, insert: token + " "}}
+	})
+}
+
+// toggleBlockComment removes the comment tokens `open` and `close` from around
+// the lines from `start` to `end`, if the first begins with `open` and the last
+// ends with `close`. Otherwise, the lines are wrapped in a comment.
+func (t *TextEdit) toggleBlockComment(start, end int, open, close string) {
+	first, last := t.getLineText(start), strings.TrimRight(t.getLineText(end), " \t")
+	openCol := utf8.RuneCountInString(getLeadingSpace(first))
+	closeCol := utf8.RuneCountInString(last) - utf8.RuneCountInString(close)
+	if strings.TrimSpace(first) == "" && start == end {
+		return
+	}
+
+	var openEdit, closeEdit lineEdit
+	if strings.HasPrefix(first[len(getLeadingSpace(first)):], open) && strings.HasSuffix(last, close) &&
+		(start != end || closeCol >= openCol+utf8.RuneCountInString(open)) {
+		openEdit = lineEdit{col: openCol, remove: utf8.RuneCountInString(open)}
+		if strings.HasPrefix(first[len(getLeadingSpace(first))+len(open):], " ") {
+			openEdit.remove++
+		}
+		closeEdit = lineEdit{col: closeCol, remove: utf8.RuneCountInString(close)}
+		if strings.HasSuffix(last[:len(last)-len(close)], " ") && (start != end || closeCol > openEdit.col+openEdit.remove) {
+			closeEdit.col--
+			closeEdit.remove++
+		}
+	} else {
+		openEdit = lineEdit{col: openCol, insert: open + " "}
+		closeEdit = lineEdit{col: closeCol + utf8.RuneCountInString(close), insert: " " + close}
+	}
+
+	t.editLines(start, end, func(line int, _ string) []lineEdit {
+		var edits []lineEdit
+		if line == start {
+			edits = append(edits, openEdit)
+		}
+		if line == end {
+			edits = append(edits, closeEdit)
+		}
+		return edits
+	})
+}
+
+// ToggleComment comments the lines of each selection, or the line of each
+// cursor, or uncomments them if they are commented already.
+func (t *TextEdit) ToggleComment() {
+	t.eachLineRange(t.toggleComment)
+}
@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/fivemoreminix/qedit/pkg/buffer"
+)
+
+// newTestTextEdit returns a TextEdit with `contents`, without a screen.
+func newTestTextEdit(contents string) *TextEdit {
+	return NewTextEdit(nil, "", []byte(contents), &DefaultTheme)
+}
+
+func TestToggleLineComment(t *testing.T) {
+	tests := []struct {
+		contents   string
+		start, end int
+		expected   string
+	}{
+		{"\tx := 1\n\n\t\ty := 2\n", 0, 2, "\t// x := 1\n\n\t// \ty := 2\n"},
+		{"\t// x := 1\n\n\t\t//y := 2\n", 0, 2, "\tx := 1\n\n\t\ty := 2\n"},
+		{"// x\ny\n", 0, 1, "// // x\n// y\n"}, // Not every line is commented
+		{"\n\t\n", 0, 1, "\n\t\n"},             // Blank lines are left alone
+		{"a\nb\nc\n", 1, 1, "a\n// b\nc\n"},
+	}
+	for _, test := range tests {
+		te := newTestTextEdit(test.contents)
+		te.toggleLineComment(test.start, test.end, "//")
+		if contents := string(te.Buffer.Bytes()); contents != test.expected {
+			t.Errorf("Toggling %q: expected %q, got %q", test.contents, test.expected, contents)
+		}
+	}
+}
+
+func TestToggleBlockComment(t *testing.T) {
+	tests := []struct {
+		contents   string
+		start, end int
+		expected   string
+	}{
+		{"\tx := 1\n", 0, 0, "\t/* x := 1 */\n"},
+		{"\t/* x := 1 */\n", 0, 0, "\tx := 1\n"},
+		{"\ta\n\tb  \n", 0, 1, "\t/* a\n\tb */  \n"},
+		{"\t/*a\n\tb*/\n", 0, 1, "\ta\n\tb\n"},
+		{"/**/\n", 0, 0, "\n"},
+		{"/*/\n", 0, 0, "/* /*/ */\n"}, // The tokens overlap, so it is not a comment
+		{"\t\n", 0, 0, "\t\n"},
+	}
+	for _, test := range tests {
+		te := newTestTextEdit(test.contents)
+		te.toggleBlockComment(test.start, test.end, "/*", "*/")
+		if contents := string(te.Buffer.Bytes()); contents != test.expected {
+			t.Errorf("Toggling %q: expected %q, got %q", test.contents, test.expected, contents)
+		}
+	}
+}
+
+func TestToggleCommentCarets(t *testing.T) {
+	te := newTestTextEdit("a\nb\nc\n")
+	te.SetLanguage(buffer.Go)
+	te.cursor = te.cursor.SetLineCol(0, 0)
+	te.addCursorAt(te.cursor.SetLineCol(0, 1)) // On the same line
+	te.addCursorAt(te.cursor.SetLineCol(2, 0))
+
+	te.ToggleComment()
+	if contents, expected := string(te.Buffer.Bytes()), "// a\nb\n// c\n"; contents != expected {
+		t.Errorf("Expected %q, got %q", expected, contents)
+	}
+}
@@ -0,0 +1,103 @@
+package ui
+
+import (
+	"github.com/fivemoreminix/qedit/pkg/buffer"
+	"github.com/gdamore/tcell/v2"
+)
+
+// GetConflicts returns the merge conflicts in the TextEdit, in order. The whole
+// file is only read for them once, and then only the lines that are edited,
+// except in large files, where they are not looked for. Do not modify the slice.
+func (t *TextEdit) GetConflicts() []buffer.Conflict {
+	if t.LargeFile {
+		return nil
+	}
+	if t.conflictFinder == nil {
+		t.conflictFinder = buffer.NewConflictFinder(t.Buffer)
+		t.Buffer.RegisterObserver(t.conflictFinder)
+	}
+	t.conflicts = t.conflictFinder.GetConflicts()
+	return t.conflicts
+}
+
+// GetConflictAt returns the index of the conflict including `line`, or -1.
+func (t *TextEdit) GetConflictAt(line int) int {
+	for i, c := range t.GetConflicts() {
+		if c.Contains(line) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ResolveConflict replaces the conflict including `line` with the lines chosen
+// by `r`. Returns false if there is no conflict at `line`.
+func (t *TextEdit) ResolveConflict(line int, r buffer.Resolution) bool {
+	i := t.GetConflictAt(line)
+	if i < 0 {
+		return false
+	}
+
+	c := t.conflicts[i]
+	text := c.Resolve(t.Buffer, r)
+	t.ReplaceLines(c.Start, c.End+1, text)
+	t.SetCursor(t.cursor.SetLineCol(c.Start, 0))
+	t.ScrollToCursor()
+	return true
+}
+
+// NextConflict moves the cursor to the start of the next conflict after the
+// cursor, or the previous one if `forwards` is false. The search wraps around.
+func (t *TextEdit) NextConflict(forwards bool) {
+	conflicts := t.GetConflicts()
+	if len(conflicts) == 0 {
+		return
+	}
+
+	cursLine, _ := t.cursor.GetLineCol()
+	target := -1
+	if forwards {
+		target = conflicts[0].Start
+		for _, c := range conflicts {
+			if c.Start > cursLine {
+				target = c.Start
+				break
+			}
+		}
+	} else {
+		target = conflicts[len(conflicts)-1].Start
+		for i := len(conflicts) - 1; i >= 0; i-- {
+			if conflicts[i].Start < cursLine {
+				target = conflicts[i].Start
+				break
+			}
+		}
+	}
+
+	t.SetCursor(t.cursor.SetLineCol(target, 0))
+	t.ScrollToCursor()
+}
+
+// getConflictStyle returns the style of a line that is part of a conflict. If
+// `marker` is true, the line is a conflict marker and the style should be used
+// as a whole. Otherwise, only the background of the style should be used, to
+// keep syntax highlighting. If `ok` is false, the line is not in a conflict.
+func (t *TextEdit) getConflictStyle(line int) (style tcell.Style, marker bool, ok bool) {
+	for _, c := range t.conflicts { // Updated at the start of Draw
+		if !c.Contains(line) {
+			continue
+		}
+
+		switch {
+		case line == c.Start || line == c.Base || line == c.Separator || line == c.End:
+			return t.theme.GetOrDefault("ConflictMarker"), true, true
+		case line < c.Separator && (c.Base < 0 || line < c.Base):
+			return t.theme.GetOrDefault("ConflictOurs"), false, true
+		case line < c.Separator:
+			return t.theme.GetOrDefault("ConflictBase"), false, true
+		default:
+			return t.theme.GetOrDefault("ConflictTheirs"), false, true
+		}
+	}
+	return tcell.Style{}, false, false
+}
@@ -0,0 +1,346 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fivemoreminix/qedit/pkg/diff"
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// A diffRow is one row of a DiffView, shown on both sides at the same height.
+// A line index of -1 means that side shows a filler in place of a line, because
+// the other side has lines that were added or removed.
+type diffRow struct {
+	left, right int // Line indexes of each side, or -1
+
+	changed     bool     // Whether the row is part of a hunk
+	leftRanges  [][2]int // Rune ranges [start, end) changed within the left line
+	rightRanges [][2]int // Rune ranges [start, end) changed within the right line
+}
+
+// A DiffView compares two texts side by side. The texts are shown in two read-only
+// views in a horizontally split Panel, which scroll together. Lines are aligned so
+// equal lines are always beside each other, with filler rows where lines were
+// added or removed. Changes within modified lines are highlighted.
+type DiffView struct {
+	TabSize int // How many columns apart tab stops are
+
+	panel       *Panel
+	left, right *diffPane
+	rows        []diffRow
+	hunks       []int // Index of the first row of each hunk
+	hunkIdx     int   // The current hunk, for navigation
+
+	scrollx, scrolly int
+	screen           *tcell.Screen
+
+	baseComponent
+}
+
+// NewDiffView creates a DiffView comparing the `left` text (the old version) to
+// the `right` text (the new version). The titles are drawn above each side.
+func NewDiffView(screen *tcell.Screen, leftTitle string, left []byte, rightTitle string, right []byte, theme *Theme) *DiffView {
+	v := &DiffView{
+		TabSize: 4,
+		screen:  screen,
+
+		baseComponent: baseComponent{theme: theme},
+	}
+	v.left = &diffPane{Title: leftTitle, lines: diff.SplitLines(left), view: v, isLeft: true}
+	v.right = &diffPane{Title: rightTitle, lines: diff.SplitLines(right), view: v}
+	v.panel = &Panel{
+		Kind: PanelKindSplitHor,
+		Left: &Panel{Kind: PanelKindSingle, Left: v.left},
+	}
+	v.panel.Right = &Panel{Parent: v.panel, Kind: PanelKindSingle, Left: v.right}
+	v.panel.Left.(*Panel).Parent = v.panel
+
+	v.alignRows()
+	if len(v.hunks) > 0 {
+		v.scrolly = v.hunks[0] // Start at the first change
+	}
+	return v
+}
+
+// alignRows computes the rows shown on both sides from the differences between
+// the lines of the left and right panes.
+func (v *DiffView) alignRows() {
+	hunks := diff.Diff(v.left.lines, v.right.lines)
+
+	v.rows = v.rows[:0]
+	v.hunks = v.hunks[:0]
+	l, r := 0, 0
+	for _, h := range append(hunks, diff.Hunk{
+		OldStart: len(v.left.lines), OldEnd: len(v.left.lines),
+		NewStart: len(v.right.lines), NewEnd: len(v.right.lines),
+	}) {
+		for ; l < h.OldStart; l, r = l+1, r+1 { // Equal lines before the hunk
+			v.rows = append(v.rows, diffRow{left: l, right: r})
+		}
+		if h.IsInsertion() && h.IsDeletion() {
+			break // The sentinel hunk
+		}
+
+		v.hunks = append(v.hunks, len(v.rows))
+		for l < h.OldEnd || r < h.NewEnd {
+			row := diffRow{left: -1, right: -1, changed: true}
+			if l < h.OldEnd {
+				row.left = l
+				l++
+			}
+			if r < h.NewEnd {
+				row.right = r
+				r++
+			}
+			if row.left >= 0 && row.right >= 0 { // A modified line
+				row.leftRanges, row.rightRanges = diffLineRunes(v.left.lines[row.left], v.right.lines[row.right])
+			}
+			v.rows = append(v.rows, row)
+		}
+	}
+}
+
+// diffLineRunes returns the rune ranges that differ between two lines.
+func diffLineRunes(a, b string) (aRanges, bRanges [][2]int) {
+	for _, h := range diff.Diff([]rune(a), []rune(b)) {
+		if !h.IsInsertion() {
+			aRanges = append(aRanges, [2]int{h.OldStart, h.OldEnd})
+		}
+		if !h.IsDeletion() {
+			bRanges = append(bRanges, [2]int{h.NewStart, h.NewEnd})
+		}
+	}
+	return
+}
+
+// GetHunkCount returns the number of hunks of changes between the two sides.
+func (v *DiffView) GetHunkCount() int {
+	return len(v.hunks)
+}
+
+// GetHunkIdx returns the index of the current hunk, which was last navigated to.
+func (v *DiffView) GetHunkIdx() int {
+	return v.hunkIdx
+}
+
+// NextHunk scrolls to the next hunk, or the previous one if `forwards` is false.
+// Navigation wraps around at the first and last hunks.
+func (v *DiffView) NextHunk(forwards bool) {
+	if len(v.hunks) == 0 {
+		return
+	}
+
+	if row := v.hunks[v.hunkIdx]; row >= v.scrolly && row < v.scrolly+v.getViewHeight() {
+		// The current hunk is in view: step from it
+		if forwards {
+			v.hunkIdx = (v.hunkIdx + 1) % len(v.hunks)
+		} else {
+			v.hunkIdx = (v.hunkIdx - 1 + len(v.hunks)) % len(v.hunks)
+		}
+	} else if forwards { // The user scrolled away: step from what is in view
+		v.hunkIdx = 0
+		for i, row := range v.hunks {
+			if row > v.scrolly {
+				v.hunkIdx = i
+				break
+			}
+		}
+	} else {
+		v.hunkIdx = len(v.hunks) - 1
+		for i := len(v.hunks) - 1; i >= 0; i-- {
+			if v.hunks[i] < v.scrolly {
+				v.hunkIdx = i
+				break
+			}
+		}
+	}
+	v.scrollTo(v.hunks[v.hunkIdx])
+}
+
+// scrollTo scrolls vertically so the row is at the top of the view, if possible.
+func (v *DiffView) scrollTo(row int) {
+	v.scrolly = Clamp(row, 0, Max(0, len(v.rows)-v.getViewHeight()))
+}
+
+// getViewHeight returns the number of rows visible at once; one line of each
+// pane is used for its title.
+func (v *DiffView) getViewHeight() int {
+	return Max(1, v.height-1)
+}
+
+func (v *DiffView) Draw(s tcell.Screen) {
+	v.panel.Draw(s)
+	if v.focused {
+		(*v.screen).HideCursor()
+	}
+}
+
+func (v *DiffView) SetTheme(theme *Theme) {
+	v.theme = theme
+}
+
+func (v *DiffView) SetPos(x, y int) {
+	v.x, v.y = x, y
+	v.panel.SetPos(x, y)
+	v.panel.UpdateSplits()
+}
+
+func (v *DiffView) SetSize(width, height int) {
+	v.width, v.height = width, height
+	v.panel.SetSize(width, height)
+	v.panel.SplitAt = width / 2
+	v.panel.UpdateSplits()
+	v.scrollTo(v.scrolly)
+}
+
+// HandleEvent scrolls both sides of the DiffView with the arrow keys, page keys,
+// Home and End. The 'n' and 'p' keys go to the next and previous hunks.
+func (v *DiffView) HandleEvent(event tcell.Event) bool {
+	switch ev := event.(type) {
+	case *tcell.EventKey:
+		switch ev.Key() {
+		case tcell.KeyUp:
+			v.scrollTo(v.scrolly - 1)
+		case tcell.KeyDown:
+			v.scrollTo(v.scrolly + 1)
+		case tcell.KeyLeft:
+			v.scrollx = Max(0, v.scrollx-1)
+		case tcell.KeyRight:
+			v.scrollx++
+		case tcell.KeyPgUp:
+			v.scrollTo(v.scrolly - v.getViewHeight())
+		case tcell.KeyPgDn:
+			v.scrollTo(v.scrolly + v.getViewHeight())
+		case tcell.KeyHome:
+			v.scrollx = 0
+			v.scrollTo(0)
+		case tcell.KeyEnd:
+			v.scrollTo(len(v.rows))
+		case tcell.KeyRune:
+			switch ev.Rune() {
+			case 'n':
+				v.NextHunk(true)
+			case 'p':
+				v.NextHunk(false)
+			default:
+				return false
+			}
+		default:
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// A diffPane draws one side of a DiffView. All of its scrolling is shared with
+// the DiffView.
+type diffPane struct {
+	Title  string
+	lines  []string
+	view   *DiffView
+	isLeft bool
+
+	baseComponent
+}
+
+// getLine returns the line index and changed rune ranges the pane has in `row`.
+func (p *diffPane) getLine(row diffRow) (int, [][2]int) {
+	if p.isLeft {
+		return row.left, row.leftRanges
+	}
+	return row.right, row.rightRanges
+}
+
+func (p *diffPane) Draw(s tcell.Screen) {
+	v := p.view
+	theme := v.theme
+	normalStyle := theme.GetOrDefault("TextEdit")
+	columnStyle := theme.GetOrDefault("DiffColumn")
+	fillerStyle := theme.GetOrDefault("DiffFiller")
+	var changedStyle, changedTextStyle tcell.Style
+	if p.isLeft {
+		changedStyle, changedTextStyle = theme.GetOrDefault("DiffRemoved"), theme.GetOrDefault("DiffRemovedText")
+	} else {
+		changedStyle, changedTextStyle = theme.GetOrDefault("DiffAdded"), theme.GetOrDefault("DiffAddedText")
+	}
+
+	// Title
+	DrawRect(s, p.x, p.y, p.width, 1, ' ', theme.GetOrDefault("WindowHeader"))
+	DrawStr(s, p.x+1, p.y, runewidth.Truncate(p.Title, Max(0, p.width-2), "…"), theme.GetOrDefault("WindowHeader"))
+
+	columnWidth := Max(3, 1+len(strconv.Itoa(len(p.lines))))
+	for y := p.y + 1; y < p.y+p.height; y++ {
+		rowIdx := v.scrolly + y - p.y - 1
+		DrawRect(s, p.x, y, p.width, 1, ' ', normalStyle)
+
+		lineNumStr := ""
+		if rowIdx < len(v.rows) {
+			row := v.rows[rowIdx]
+			line, ranges := p.getLine(row)
+			if line < 0 { // Filler where the other side has lines
+				DrawRect(s, p.x+columnWidth, y, p.width-columnWidth, 1, '╱', fillerStyle)
+			} else {
+				lineNumStr = strconv.Itoa(line + 1)
+				style := normalStyle
+				if row.changed {
+					style = changedStyle
+					DrawRect(s, p.x+columnWidth, y, p.width-columnWidth, 1, ' ', style)
+				}
+				p.drawLine(s, p.x+columnWidth, y, p.lines[line], ranges, style, changedTextStyle)
+			}
+		}
+
+		columnStr := fmt.Sprintf("%s%s│", strings.Repeat(" ", columnWidth-len(lineNumStr)-1), lineNumStr)
+		DrawStr(s, p.x, y, columnStr, columnStyle)
+	}
+}
+
+// drawLine draws the visible part of `line` at x, y, up to the right side of the
+// pane. The grapheme clusters starting in `ranges` are drawn with `changedStyle`.
+// Lines are drawn like in a TextEdit, so tabs and wide characters line up.
+func (p *diffPane) drawLine(s tcell.Screen, x, y int, line string, ranges [][2]int, style, changedStyle tcell.Style) {
+	v := p.view
+	line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+	rangeIdx := 0
+	eachGraphemeIn(line, v.TabSize, func(cluster string, col, gx, runes, width int) bool {
+		screenX := x + gx - v.scrollx
+		if screenX >= p.x+p.width {
+			return true
+		}
+		if screenX+width <= x {
+			return false // Scrolled past
+		}
+
+		for rangeIdx < len(ranges) && col >= ranges[rangeIdx][1] {
+			rangeIdx++
+		}
+		sty := style
+		if rangeIdx < len(ranges) && col >= ranges[rangeIdx][0] {
+			sty = changedStyle
+		}
+
+		clusterRunes := []rune(cluster)
+		r, combc := clusterRunes[0], clusterRunes[1:]
+		if r == '\t' || screenX < x || screenX+width > p.x+p.width { // A tab, or a cluster partly in view
+			r, combc = ' ', nil
+		}
+		s.SetContent(screenX, y, r, combc, sty)
+		if r == ' ' { // Fill the rest of a tab
+			for i := 1; i < width && screenX+i < p.x+p.width; i++ {
+				if screenX+i >= x {
+					s.SetContent(screenX+i, y, ' ', nil, sty)
+				}
+			}
+		}
+		return false
+	})
+}
+
+// HandleEvent does nothing; events are handled by the DiffView for both panes.
+func (p *diffPane) HandleEvent(event tcell.Event) bool {
+	return false
+}
@@ -0,0 +1,128 @@
+package ui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestDiffViewAlignRows(t *testing.T) {
+	tests := []struct {
+		name        string
+		left, right string
+		rows        [][2]int // Left and right line of each row
+		hunks       []int
+	}{
+		{"insertion", "a\nb\n", "a\nx\ny\nb\n", [][2]int{{0, 0}, {-1, 1}, {-1, 2}, {1, 3}}, []int{1}},
+		{"deletion", "a\nx\nb\n", "a\nb\n", [][2]int{{0, 0}, {1, -1}, {2, 1}}, []int{1}},
+		{"replacement", "a\nb\nc\nd\n", "a\nB\nd\n", [][2]int{{0, 0}, {1, 1}, {2, -1}, {3, 2}}, []int{1}},
+		{"two hunks", "a\nb\nc\n", "x\nb\ny\n", [][2]int{{0, 0}, {1, 1}, {2, 2}}, []int{0, 2}},
+		{"equal", "a\n", "a\n", [][2]int{{0, 0}}, []int{}},
+	}
+	for _, test := range tests {
+		v := NewDiffView(nil, "left", []byte(test.left), "right", []byte(test.right), &DefaultTheme)
+		rows := make([][2]int, len(v.rows))
+		for i, row := range v.rows {
+			rows[i] = [2]int{row.left, row.right}
+			if equal := row.left >= 0 && row.right >= 0 && v.left.lines[row.left] == v.right.lines[row.right]; row.changed == equal {
+				t.Errorf("%s: expected row %d to be changed only if its lines differ", test.name, i)
+			}
+		}
+		if !reflect.DeepEqual(rows, test.rows) {
+			t.Errorf("%s: expected rows %v, got %v", test.name, test.rows, rows)
+		}
+		if hunks := append([]int{}, v.hunks...); !reflect.DeepEqual(hunks, test.hunks) {
+			t.Errorf("%s: expected hunks at rows %v, got %v", test.name, test.hunks, hunks)
+		}
+	}
+}
+
+func TestDiffLineRunes(t *testing.T) {
+	tests := []struct {
+		a, b             string
+		aRanges, bRanges [][2]int
+	}{
+		{"foo bar\n", "foo baz\n", [][2]int{{6, 7}}, [][2]int{{6, 7}}},
+		{"abc\n", "abXc\n", nil, [][2]int{{2, 3}}},
+		{"a世b\n", "ab\n", [][2]int{{1, 2}}, nil},
+	}
+	for _, test := range tests {
+		aRanges, bRanges := diffLineRunes(test.a, test.b)
+		if !reflect.DeepEqual(aRanges, test.aRanges) || !reflect.DeepEqual(bRanges, test.bRanges) {
+			t.Errorf("diffLineRunes(%q, %q): expected %v and %v, got %v and %v", test.a, test.b, test.aRanges, test.bRanges, aRanges, bRanges)
+		}
+	}
+}
+
+func TestDiffViewNextHunk(t *testing.T) {
+	left := strings.Repeat("line\n", 20)
+	right := "changed\n" + strings.Repeat("line\n", 9) + "changed\n" + strings.Repeat("line\n", 9) + "changed\n"
+	v := NewDiffView(nil, "left", []byte(left), "right", []byte(right), &DefaultTheme)
+	v.SetSize(40, 3) // Two rows in view
+	if len(v.hunks) != 3 {
+		t.Fatalf("Expected 3 hunks, got %v", v.hunks)
+	}
+
+	expect := func(hunkIdx int) {
+		t.Helper()
+		if v.hunkIdx != hunkIdx || v.scrolly != Min(v.hunks[hunkIdx], len(v.rows)-v.getViewHeight()) {
+			t.Errorf("Expected to be at hunk %d, got hunk %d scrolled to row %d", hunkIdx, v.hunkIdx, v.scrolly)
+		}
+	}
+	v.NextHunk(true)
+	expect(1)
+	v.NextHunk(true)
+	expect(2)
+	v.NextHunk(true) // Wraps around to the first
+	expect(0)
+	v.NextHunk(false) // Wraps around to the last
+	expect(2)
+
+	v.scrollTo(4) // Scrolled away from the current hunk, between the first two
+	v.NextHunk(true)
+	expect(1)
+	v.scrollTo(4)
+	v.NextHunk(false)
+	expect(0)
+}
+
+func TestDiffPaneDrawLine(t *testing.T) {
+	s := tcell.NewSimulationScreen("")
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Fini()
+	s.SetSize(20, 1)
+
+	line := "e\u0301\t世x\n" // A combining accent, a tab and a wide rune
+	v := NewDiffView(nil, "left", []byte(line), "right", []byte(line), &DefaultTheme)
+	v.SetSize(40, 2)
+	p := v.left
+	p.SetPos(0, 0)
+	p.SetSize(20, 2)
+	p.drawLine(s, 0, 0, line, nil, tcell.StyleDefault, tcell.StyleDefault)
+
+	cells := []struct {
+		x     int
+		r     rune
+		combc []rune
+	}{
+		{0, 'e', []rune{'\u0301'}},
+		{1, ' ', nil}, {2, ' ', nil}, {3, ' ', nil}, // The tab, to the tab stop at 4
+		{4, '世', nil},
+		{6, 'x', nil},
+	}
+	for _, c := range cells {
+		if r, combc, _, _ := s.GetContent(c.x, 0); r != c.r || len(combc) != len(c.combc) || len(combc) > 0 && combc[0] != c.combc[0] {
+			t.Errorf("Expected %q%q at %d, got %q%q", c.r, c.combc, c.x, r, combc)
+		}
+	}
+
+	te := newTestTextEdit(line)
+	te.TabSize = v.TabSize
+	if x := te.GetDisplayCol(0, 4); x != 6 { // The same column as in a TextEdit
+		t.Errorf("Expected x at display column 6 in a TextEdit, got %d", x)
+	}
+}
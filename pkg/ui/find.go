@@ -0,0 +1,48 @@
+package ui
+
+import (
+	"unicode/utf8"
+
+	"github.com/fivemoreminix/qedit/pkg/buffer"
+)
+
+// Find selects the next occurrence of `text` at or after the cursor, or after the
+// start of the selection, wrapping around to the start of the buffer. Returns
+// false if there is none. The buffer is searched without copying it, so large
+// files can be searched, too. An error is returned if a large file was truncated
+// by another program, so it could not be searched.
+func (t *TextEdit) Find(text []byte) (bool, error) {
+	if len(text) == 0 {
+		return false, nil
+	}
+
+	from := t.Buffer.LineColToPos(t.cursor.GetLineCol())
+	if t.selectMode { // Find the occurrence after the one selected
+		from = t.Buffer.LineColToPos(t.selection.Start.GetLineCol())
+		from += utf8.RuneLen(t.Buffer.RuneAtPos(from))
+	}
+
+	pos := buffer.Index(t.Buffer, from, text)
+	if pos < 0 && from > 0 {
+		pos = buffer.Index(t.Buffer, 0, text) // Wrap around
+	}
+	if err := t.Err(); err != nil {
+		return false, err
+	}
+	if pos < 0 {
+		return false, nil
+	}
+
+	_, lastSize := utf8.DecodeLastRune(text)
+	startLine, startCol := t.Buffer.PosToLineCol(pos)
+	endLine, endCol := t.Buffer.PosToLineCol(pos + len(text) - lastSize)
+
+	t.RemoveExtraCursors()
+	t.blockMode = false
+	t.selection.Start = t.selection.Start.SetLineCol(startLine, startCol)
+	t.selection.End = t.selection.End.SetLineCol(endLine, endCol)
+	t.selectMode = true
+	t.SetCursor(t.selection.End.Right()) // After the occurrence, like selecting it with Shift
+	t.ScrollToCursor()
+	return true, nil
+}
@@ -0,0 +1,24 @@
+package ui
+
+import "testing"
+
+func TestFind(t *testing.T) {
+	te := newTestTextEdit("one 世界\ntwo 世界\n")
+	expectSelection := func(text string, line, col int) {
+		t.Helper()
+		if found, err := te.Find([]byte(text)); !found || err != nil {
+			t.Fatalf("Expected to find %q, got %v, %v", text, found, err)
+		}
+		if l, c := te.selection.Start.GetLineCol(); l != line || c != col || string(te.GetSelectedBytes()) != text {
+			t.Errorf("Expected %q selected at %d, %d, got %q at %d, %d", text, line, col, te.GetSelectedBytes(), l, c)
+		}
+	}
+
+	expectSelection("世界", 0, 4)
+	expectSelection("世界", 1, 4) // After the selected occurrence
+	expectSelection("世界", 0, 4) // Wraps around
+	expectSelection("界\ntwo", 0, 5)
+	if found, _ := te.Find([]byte("three")); found {
+		t.Errorf("Expected not to find text that isn't in the buffer")
+	}
+}
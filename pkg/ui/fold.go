@@ -0,0 +1,227 @@
+package ui
+
+import (
+	"math"
+
+	"github.com/fivemoreminix/qedit/pkg/buffer"
+	"github.com/gdamore/tcell/v2"
+)
+
+// foldMarker is drawn after the first line of a folded range, in place of the
+// lines hidden by it.
+const foldMarker = "⋯"
+
+// getFoldRanges returns the ranges of lines which can be folded, from the Language
+// of the TextEdit. They are found again whenever the contents have changed, except
+// in large files, where they are not looked for.
+func (t *TextEdit) getFoldRanges() []buffer.FoldRange {
+	if t.LargeFile || t.Language == nil {
+		return nil
+	}
+	if t.foldRangesRevision != t.revision {
+		t.foldRanges = t.Language.GetFolds(t.Buffer, t.Highlighter, t.TabSize)
+		t.foldRangesRevision = t.revision
+	}
+	return t.foldRanges
+}
+
+// addFold folds the range of lines. Its first and last lines are anchored to the
+// Buffer, so the fold moves with edits like a cursor does.
+func (t *TextEdit) addFold(r buffer.FoldRange) {
+	fold := buffer.NewRegion(&t.Buffer)
+	fold.Start = fold.Start.SetLineCol(r.Start, 0)
+	fold.End = fold.End.SetLineCol(r.End, math.MaxInt32)
+	t.Buffer.RegisterCursor(&fold.Start)
+	t.Buffer.RegisterCursor(&fold.End)
+	t.folds = append(t.folds, &fold)
+	t.foldedRevision = -1
+}
+
+// removeFold unfolds the fold at index `i` of the folds.
+func (t *TextEdit) removeFold(i int) {
+	t.Buffer.UnregisterCursor(&t.folds[i].Start)
+	t.Buffer.UnregisterCursor(&t.folds[i].End)
+	t.folds = append(t.folds[:i], t.folds[i+1:]...)
+	t.foldedRevision = -1
+}
+
+// getFolds returns the ranges of lines which are folded. A fold which edits have
+// reduced to a single line is unfolded. They are found again only when the
+// contents or the folds have changed, since every line drawn and every step of a
+// cursor past folded lines looks at them.
+func (t *TextEdit) getFolds() []buffer.FoldRange {
+	if t.foldedRevision == t.revision {
+		return t.folded
+	}
+	folds := t.folded[:0]
+	for i := 0; i < len(t.folds); {
+		start, _ := t.folds[i].Start.GetLineCol()
+		end, _ := t.folds[i].End.GetLineCol()
+		if end <= start {
+			t.removeFold(i)
+			continue
+		}
+		folds = append(folds, buffer.FoldRange{Start: start, End: end})
+		i++
+	}
+	t.folded, t.foldedRevision = folds, t.revision
+	return folds
+}
+
+// isFolded returns whether `line` is the first line of a folded range.
+func (t *TextEdit) isFolded(line int) bool {
+	for _, f := range t.getFolds() {
+		if f.Start == line {
+			return true
+		}
+	}
+	return false
+}
+
+// getFoldHiding returns the outermost folded range which hides `line`, which is
+// any line of the range but its first. Returns false if the line is not hidden.
+func (t *TextEdit) getFoldHiding(line int) (buffer.FoldRange, bool) {
+	var outer buffer.FoldRange
+	var found bool
+	for _, f := range t.getFolds() {
+		if line > f.Start && line <= f.End && (!found || f.Start < outer.Start) {
+			outer, found = f, true
+		}
+	}
+	return outer, found
+}
+
+// nextVisibleLine returns the first line after `line` which is not hidden by a
+// fold. It is the number of lines of the buffer if there is none.
+func (t *TextEdit) nextVisibleLine(line int) int {
+	line++
+	for f, ok := t.getFoldHiding(line); ok; f, ok = t.getFoldHiding(line) {
+		line = f.End + 1
+	}
+	return line
+}
+
+// prevVisibleLine returns the last line before `line` which is not hidden by a
+// fold. It is -1 if there is none.
+func (t *TextEdit) prevVisibleLine(line int) int {
+	line--
+	for f, ok := t.getFoldHiding(line); ok; f, ok = t.getFoldHiding(line) {
+		line = f.Start
+	}
+	return line
+}
+
+// skipFolds returns the cursor `to`, which the cursor is moving to, moved past
+// the folded lines that hide it: to the line after them when moving down, and to
+// the first line of the fold when moving up. The cursor is not moved if there is
+// no line after them.
+func (t *TextEdit) skipFolds(to buffer.Cursor) buffer.Cursor {
+	line, col := to.GetLineCol()
+	f, ok := t.getFoldHiding(line)
+	if !ok {
+		return to
+	}
+	if cursLine, _ := t.cursor.GetLineCol(); line > cursLine {
+		if f.End+1 >= t.Buffer.Lines() {
+			return t.cursor
+		}
+		return to.SetLineCol(f.End+1, col)
+	}
+	if col >= t.Buffer.RunesInLine(line) { // Moved left from the start of a line
+		col = math.MaxInt32
+	}
+	return to.SetLineCol(f.Start, col)
+}
+
+// revealLine unfolds the folds which hide `line`.
+func (t *TextEdit) revealLine(line int) {
+	for i := 0; i < len(t.folds); {
+		start, _ := t.folds[i].Start.GetLineCol()
+		end, _ := t.folds[i].End.GetLineCol()
+		if line > start && line <= end {
+			t.removeFold(i)
+		} else {
+			i++
+		}
+	}
+}
+
+// moveCursorsOutOfFolds moves every cursor on a folded line to the end of the
+// first line of its fold, where it can be seen.
+func (t *TextEdit) moveCursorsOutOfFolds() {
+	t.blockMode = false
+	t.eachCaret(func() {
+		line, _ := t.cursor.GetLineCol()
+		if f, ok := t.getFoldHiding(line); ok {
+			t.selectMode = false
+			t.cursor = t.cursor.SetLineCol(f.Start, math.MaxInt32)
+		}
+	})
+	t.mergeCarets()
+	t.ScrollToCursor()
+	t.updateCursorVisibility()
+}
+
+// Fold folds the smallest range of lines, which can be folded, that includes the
+// line of the cursor and is not folded already. Folding again folds the range
+// around that one.
+func (t *TextEdit) Fold() {
+	line, _ := t.cursor.GetLineCol()
+	var inner buffer.FoldRange
+	var found bool
+	for _, r := range t.getFoldRanges() {
+		if r.Contains(line) && !t.isFolded(r.Start) && (!found || r.Start > inner.Start) {
+			inner, found = r, true
+		}
+	}
+	if found {
+		t.addFold(inner)
+		t.moveCursorsOutOfFolds()
+	}
+}
+
+// Unfold unfolds the folds beginning on the line of the cursor.
+func (t *TextEdit) Unfold() {
+	line, _ := t.cursor.GetLineCol()
+	for i := 0; i < len(t.folds); {
+		if start, _ := t.folds[i].Start.GetLineCol(); start == line {
+			t.removeFold(i)
+		} else {
+			i++
+		}
+	}
+}
+
+// FoldAll folds every range of lines which can be folded.
+func (t *TextEdit) FoldAll() {
+	for _, r := range t.getFoldRanges() {
+		if !t.isFolded(r.Start) {
+			t.addFold(r)
+		}
+	}
+	t.moveCursorsOutOfFolds()
+}
+
+// UnfoldAll unfolds every fold.
+func (t *TextEdit) UnfoldAll() {
+	for len(t.folds) > 0 {
+		t.removeFold(len(t.folds) - 1)
+	}
+}
+
+// drawFoldMarker draws the foldMarker after the end of `line` if it is folded, at
+// screen row `y`. The display column `left` is drawn at the left edge of the view.
+func (t *TextEdit) drawFoldMarker(s tcell.Screen, y, line, left int) {
+	if !t.isFolded(line) {
+		return
+	}
+	columnWidth := t.getColumnWidth()
+	x := t.x + columnWidth + t.GetDisplayCol(line, t.Buffer.RunesInLine(line)) - left + 1
+	style := t.theme.GetOrDefault("TextEditFold")
+	for _, r := range foldMarker {
+		if x >= t.x+columnWidth && x < t.x+t.width {
+			s.SetContent(x, y, r, nil, style)
+		}
+		x++
+	}
+}
@@ -0,0 +1,113 @@
+package ui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fivemoreminix/qedit/pkg/buffer"
+	"github.com/gdamore/tcell/v2"
+)
+
+// newFoldedTextEdit returns a TextEdit with `lines` lines, each its index, and
+// the ranges folded.
+func newFoldedTextEdit(lines int, folds ...buffer.FoldRange) *TextEdit {
+	var contents strings.Builder
+	for i := 0; i < lines; i++ {
+		contents.WriteString(string(rune('0'+i)) + "\n")
+	}
+	te := newTestTextEdit(contents.String())
+	for _, f := range folds {
+		te.addFold(f)
+	}
+	return te
+}
+
+func TestVisibleLines(t *testing.T) {
+	te := newFoldedTextEdit(10, buffer.FoldRange{Start: 1, End: 3}, buffer.FoldRange{Start: 2, End: 3}, buffer.FoldRange{Start: 5, End: 6})
+	tests := []struct {
+		line       int
+		next, prev int
+	}{
+		{0, 1, -1},
+		{1, 4, 0},
+		{4, 5, 1},
+		{5, 7, 4},
+		{7, 8, 5},
+		{9, 10, 8},
+	}
+	for _, test := range tests {
+		if next := te.nextVisibleLine(test.line); next != test.next {
+			t.Errorf("Expected line %d after %d, got %d", test.next, test.line, next)
+		}
+		if prev := te.prevVisibleLine(test.line); prev != test.prev {
+			t.Errorf("Expected line %d before %d, got %d", test.prev, test.line, prev)
+		}
+	}
+
+	if f, ok := te.getFoldHiding(3); !ok || f != (buffer.FoldRange{Start: 1, End: 3}) {
+		t.Errorf("Expected line 3 hidden by the outer fold, got %v, %v", f, ok)
+	}
+	if _, ok := te.getFoldHiding(1); ok {
+		t.Errorf("Expected the first line of a fold to be visible")
+	}
+}
+
+func TestSkipFolds(t *testing.T) {
+	tests := []struct {
+		fromLine, fromCol int
+		toLine, toCol     int
+		line, col         int // Expected line and column after skipping folds
+	}{
+		{1, 0, 2, 0, 4, 0}, // Down past the fold
+		{4, 0, 3, 0, 1, 0}, // Up to the first line of the fold
+		{4, 0, 3, 1, 1, 1}, // Left from the start of a line, to the end of the fold's first line
+		{8, 0, 9, 0, 8, 0}, // No line after the fold
+		{0, 0, 1, 0, 1, 0}, // Not hidden
+	}
+	for _, test := range tests {
+		te := newFoldedTextEdit(10, buffer.FoldRange{Start: 1, End: 3}, buffer.FoldRange{Start: 8, End: 10})
+		te.cursor = te.cursor.SetLineCol(test.fromLine, test.fromCol)
+		to := te.skipFolds(te.cursor.SetLineCol(test.toLine, test.toCol))
+		if line, col := to.GetLineCol(); line != test.line || col != test.col {
+			t.Errorf("Moving from %d, %d to %d, %d: expected %d, %d, got %d, %d",
+				test.fromLine, test.fromCol, test.toLine, test.toCol, test.line, test.col, line, col)
+		}
+	}
+
+	te := newFoldedTextEdit(10, buffer.FoldRange{Start: 1, End: 3})
+	te.cursor = te.cursor.SetLineCol(1, 0)
+	te.HandleEvent(tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone))
+	if line, _ := te.cursor.GetLineCol(); line != 4 {
+		t.Errorf("Expected the Down key to skip to line 4, got %d", line)
+	}
+}
+
+func TestRevealLine(t *testing.T) {
+	te := newFoldedTextEdit(10, buffer.FoldRange{Start: 1, End: 3}, buffer.FoldRange{Start: 2, End: 3}, buffer.FoldRange{Start: 5, End: 6})
+	te.revealLine(1) // The first line of a fold is not hidden
+	te.revealLine(3)
+	if folds, expected := te.getFolds(), []buffer.FoldRange{{Start: 5, End: 6}}; !reflect.DeepEqual(folds, expected) {
+		t.Errorf("Expected folds %v, got %v", expected, folds)
+	}
+}
+
+func TestFoldAnchors(t *testing.T) {
+	te := newFoldedTextEdit(10, buffer.FoldRange{Start: 2, End: 4})
+	if folds, expected := te.getFolds(), []buffer.FoldRange{{Start: 2, End: 4}}; !reflect.DeepEqual(folds, expected) {
+		t.Errorf("Expected folds %v, got %v", expected, folds)
+	}
+
+	te.Buffer.Insert(0, 0, []byte("x\n"))
+	if folds, expected := te.getFolds(), []buffer.FoldRange{{Start: 3, End: 5}}; !reflect.DeepEqual(folds, expected) {
+		t.Errorf("Expected folds %v after inserting a line, got %v", expected, folds)
+	}
+	if !te.isFolded(3) || te.isFolded(2) {
+		t.Errorf("Expected the fold to begin on line 3")
+	}
+
+	te.Buffer.Remove(3, 0, 4, 1) // The fold is left with a single line
+	if folds := te.getFolds(); len(folds) != 0 || len(te.folds) != 0 {
+		t.Errorf("Expected the fold to be unfolded, got %v", folds)
+	}
+}
@@ -0,0 +1,102 @@
+package ui
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+// eachGrapheme calls f with each grapheme cluster of `line`, not including the
+// line delimiter, until f returns true. A grapheme cluster is what a user sees as
+// one character. Its column is the index of its first rune, its x is the display
+// column it is drawn at, and its width is the number of cells it is drawn in.
+func (t *TextEdit) eachGrapheme(line int, f func(col, x, runes, width int) bool) {
+	text := strings.TrimSuffix(strings.TrimSuffix(string(t.Buffer.Line(line)), "\n"), "\r")
+	eachGraphemeIn(text, t.TabSize, func(_ string, col, x, runes, width int) bool {
+		return f(col, x, runes, width)
+	})
+}
+
+// eachGraphemeIn calls f with each grapheme cluster of `text`, like eachGrapheme,
+// until f returns true. Tab stops are every `tabSize` columns. Views that draw
+// text without a Buffer use it to draw lines the same way a TextEdit does.
+func eachGraphemeIn(text string, tabSize int, f func(cluster string, col, x, runes, width int) bool) {
+	var col, x int
+	state := -1
+	for len(text) > 0 {
+		var cluster string
+		var width int
+		cluster, text, width, state = uniseg.FirstGraphemeClusterInString(text, state)
+		width = clusterWidth(cluster, width, x, tabSize)
+		runes := utf8.RuneCountInString(cluster)
+		if f(cluster, col, x, runes, width) {
+			return
+		}
+		col += runes
+		x += width
+	}
+}
+
+// getClusterWidth returns the number of cells the grapheme cluster is drawn in at
+// the display column `x`, given the width uniseg measured for it. A tab extends
+// to the next tab stop, which are every TabSize columns.
+func (t *TextEdit) getClusterWidth(cluster string, width, x int) int {
+	return clusterWidth(cluster, width, x, t.TabSize)
+}
+
+// clusterWidth is getClusterWidth with tab stops every `tabSize` columns.
+func clusterWidth(cluster string, width, x, tabSize int) int {
+	if cluster == "\t" {
+		tabSize = Max(1, tabSize)
+		return tabSize - x%tabSize
+	}
+	return width
+}
+
+// GetDisplayCol returns the display column the rune at line, col is drawn at,
+// which is the number of cells the line is drawn in before it. Tabs extend to
+// the next tab stop, and wide characters take two cells.
+func (t *TextEdit) GetDisplayCol(line, col int) int {
+	var displayCol int
+	t.eachGrapheme(line, func(gcol, x, runes, width int) bool {
+		if gcol >= col {
+			return true
+		}
+		displayCol = x + width
+		return false
+	})
+	return displayCol
+}
+
+// GetColAtDisplayCol returns the column of the grapheme cluster of `line` drawn
+// in the cell at display column `x`, or the length of the line if it ends before
+// `x`. A cell within a tab or a wide character maps to the start of it.
+func (t *TextEdit) GetColAtDisplayCol(line, x int) int {
+	var col int
+	t.eachGrapheme(line, func(gcol, gx, runes, width int) bool {
+		if gx+width > x {
+			col = gcol
+			return true
+		}
+		col = gcol + runes
+		return false
+	})
+	return col
+}
+
+// getColAfterDisplayCol returns the column of the first grapheme cluster of `line`
+// drawn at or after the display column `x`, or the length of the line if it ends
+// before `x`.
+func (t *TextEdit) getColAfterDisplayCol(line, x int) int {
+	var col int
+	t.eachGrapheme(line, func(gcol, gx, runes, width int) bool {
+		if gx >= x {
+			col = gcol
+			return true
+		}
+		col = gcol + runes
+		return false
+	})
+	return col
+}
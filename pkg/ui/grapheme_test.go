@@ -0,0 +1,36 @@
+package ui
+
+import "testing"
+
+func TestDisplayCol(t *testing.T) {
+	tests := []struct {
+		line       string
+		col        int // Column of a grapheme cluster
+		displayCol int // Expected GetDisplayCol(col)
+		x          int // A display column
+		colAt      int // Expected GetColAtDisplayCol(x)
+		colAfter   int // Expected getColAfterDisplayCol(x)
+	}{
+		{"ab\tc\n", 2, 2, 2, 2, 2},   // A tab after text extends to the next tab stop
+		{"ab\tc\n", 3, 4, 3, 2, 3},   // A cell inside a tab maps to its start
+		{"ab\tc\n", 4, 5, 9, 4, 4},   // Past the end of the line
+		{"\t\tx\n", 2, 8, 5, 1, 2},   // Tabs at tab stops take the whole tab size
+		{"abcd\tx\n", 5, 8, 7, 4, 5}, // Text ending on a tab stop
+		{"世界x\n", 1, 2, 3, 1, 2},     // Wide characters take two cells
+		{"世界x\n", 2, 4, 4, 2, 2},
+		{"e\u0301\tx\n", 2, 1, 2, 2, 3}, // A combining accent is drawn in the cell of its letter
+	}
+	for _, test := range tests {
+		te := newTestTextEdit(test.line)
+		te.TabSize = 4
+		if displayCol := te.GetDisplayCol(0, test.col); displayCol != test.displayCol {
+			t.Errorf("%q: expected column %d at display column %d, got %d", test.line, test.col, test.displayCol, displayCol)
+		}
+		if col := te.GetColAtDisplayCol(0, test.x); col != test.colAt {
+			t.Errorf("%q: expected display column %d in column %d, got %d", test.line, test.x, test.colAt, col)
+		}
+		if col := te.getColAfterDisplayCol(0, test.x); col != test.colAfter {
+			t.Errorf("%q: expected column %d at or after display column %d, got %d", test.line, test.colAfter, test.x, col)
+		}
+	}
+}
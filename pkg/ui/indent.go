@@ -0,0 +1,353 @@
+package ui
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fivemoreminix/qedit/pkg/buffer"
+)
+
+// defaultTabSize is the TabSize of a file whose Language has none, when it can't
+// be told from the file.
+const defaultTabSize = 4
+
+// detectIndentation sets UseHardTabs and TabSize by how `contents` is indented, or
+// by the defaults of the Language if it can't be told.
+func (t *TextEdit) detectIndentation(contents []byte) {
+	t.UseHardTabs, t.TabSize = true, defaultTabSize
+	if t.Language != nil {
+		t.UseHardTabs = !t.Language.UseSpaces
+		if t.Language.TabSize > 0 {
+			t.TabSize = t.Language.TabSize
+		}
+	}
+	if hardTabs, tabSize, ok := buffer.DetectIndentation(contents); ok {
+		t.UseHardTabs = hardTabs
+		if tabSize > 0 { // The width of a tab can't be told from tabs
+			t.TabSize = tabSize
+		}
+	}
+}
+
+// ConvertIndentation replaces the indentation of every line with tabs, if
+// `hardTabs` is true, or spaces, keeping its width, and sets UseHardTabs. When
+// converting to tabs, the spaces left over that are narrower than a tab are kept.
+// Large files are not converted.
+func (t *TextEdit) ConvertIndentation(hardTabs bool) {
+	if t.LargeFile {
+		return
+	}
+	t.UseHardTabs = hardTabs
+	tabSize := Max(1, t.TabSize)
+	t.editLines(0, t.Buffer.Lines()-1, func(_ int, text string) []lineEdit {
+		indent := getLeadingSpace(text)
+		width := 0
+		for _, r := range indent {
+			if r == '\t' {
+				width += tabSize - width%tabSize
+			} else {
+				width++
+			}
+		}
+		var converted string
+		if hardTabs {
+			converted = strings.Repeat("\t", width/tabSize) + strings.Repeat(" ", width%tabSize)
+		} else {
+			converted = strings.Repeat(" ", width)
+		}
+		if converted == indent {
+			return nil
+		}
+		return []lineEdit{{remove: len(indent), insert: converted}}
+	})
+}
+
+// getIndentUnit returns one level of indentation: a tab, or TabSize spaces if
+// the file does not use hard tabs.
+func (t *TextEdit) getIndentUnit() string {
+	if t.UseHardTabs {
+		return "\t"
+	}
+	return strings.Repeat(" ", t.TabSize)
+}
+
+// getLineText returns the text of `line`, without its line delimiter.
+func (t *TextEdit) getLineText(line int) string {
+	return strings.TrimSuffix(strings.TrimSuffix(string(t.Buffer.Line(line)), "\n"), "\r")
+}
+
+// getLeadingSpace returns the spaces and tabs at the start of `text`.
+func getLeadingSpace(text string) string {
+	return text[:len(text)-len(strings.TrimLeft(text, " \t"))]
+}
+
+// setLineIndent replaces the spaces and tabs at the start of `line` with `indent`.
+// Cursors on the line keep their place in its text.
+func (t *TextEdit) setLineIndent(line int, indent string) {
+	if n := utf8.RuneCountInString(getLeadingSpace(t.getLineText(line))); n > 0 {
+		t.Buffer.Remove(line, 0, line, n-1)
+	}
+	if indent != "" {
+		t.Buffer.Insert(line, 0, []byte(indent))
+	}
+}
+
+// dedentLine indents `line` one level less than it is. If the line begins with a
+// closing bracket, it is instead indented like the line of its opening bracket.
+func (t *TextEdit) dedentLine(line int) {
+	indent := getLeadingSpace(t.getLineText(line))
+	col := utf8.RuneCountInString(indent)
+	if isClosingBracket(t.getRuneAt(line, col)) {
+		cursor := buffer.NewCursor(&t.Buffer).SetLineCol(line, col)
+		if pair, ok := cursor.BracketPair(t.Highlighter); ok {
+			if openLine, _ := pair.Start.GetLineCol(); openLine < line {
+				t.setLineIndent(line, getLeadingSpace(t.getLineText(openLine)))
+				return
+			}
+		}
+	}
+
+	unit := t.getIndentUnit()
+	if strings.HasSuffix(indent, unit) {
+		indent = strings.TrimSuffix(indent, unit)
+	} else if strings.HasSuffix(indent, "\t") {
+		indent = strings.TrimSuffix(indent, "\t") // A tab in a file indented with spaces
+	} else {
+		indent = strings.TrimRight(indent[:len(indent)-Min(len(indent), t.TabSize)], " ")
+	}
+	t.setLineIndent(line, indent)
+}
+
+// dedentsLine returns whether `line` is indented one level less than the line
+// before it, according to the Language.
+func (t *TextEdit) dedentsLine(line int) bool {
+	return t.AutoIndent && t.Language != nil && t.Language.DedentsLine(t.getLineText(line))
+}
+
+// insertNewline begins a new line at the cursor, if `contents` is a single line
+// delimiter and AutoIndent is on, and returns true. The new line is indented like
+// the line of the cursor, or one level more if the Language indents after the
+// text before the cursor. When the text after the cursor is then dedented, like
+// a closing bracket after an opening one, it is moved to a line of its own.
+func (t *TextEdit) insertNewline(contents string) bool {
+	if !t.AutoIndent || contents != "\n" && contents != "\r\n" {
+		return false
+	}
+
+	line, col := t.cursor.GetLineCol()
+	text := []rune(t.getLineText(line))
+	col = Min(col, len(text))
+	before, after := string(text[:col]), string(text[col:])
+	indent := getLeadingSpace(before)
+	next := indent
+	indents := t.Language != nil && t.Language.IndentsAfter(before)
+	if indents {
+		next += t.getIndentUnit()
+	}
+
+	if before == indent && after == "" && col > 0 { // Leave no spaces on a blank line
+		t.Buffer.Remove(line, 0, line, col-1)
+		col = 0
+	}
+
+	// The spaces after the cursor are replaced by the indentation of the new line
+	if n := utf8.RuneCountInString(getLeadingSpace(after)); n > 0 {
+		t.Buffer.Remove(line, col, line, col+n-1)
+		after = strings.TrimLeft(after, " \t")
+	}
+
+	if indents && after != "" && t.Language.DedentsLine(indent+after) {
+		t.Buffer.Insert(line, col, []byte("\n"+next+"\n"+indent))
+		t.cursor = t.cursor.SetLineCol(line+1, utf8.RuneCountInString(next))
+		return true
+	}
+
+	t.Buffer.Insert(line, col, []byte("\n"+next))
+	if after != "" && t.dedentsLine(line+1) {
+		t.dedentLine(line + 1)
+	}
+	return true
+}
+
+// A lineEdit replaces `remove` runes of a line at column `col` with `insert`.
+type lineEdit struct {
+	col, remove int
+	insert      string
+}
+
+// shift returns where the column `col` of the line is after the lineEdit. A
+// column within the removed runes is moved to the start of them.
+func (e lineEdit) shift(col int) int {
+	if col >= e.col+e.remove {
+		return col - e.remove + utf8.RuneCountInString(e.insert)
+	} else if col > e.col {
+		return e.col
+	}
+	return col
+}
+
+// getSelectedLines returns the first and last lines of the selection, or the
+// line of the cursor if nothing is selected.
+func (t *TextEdit) getSelectedLines() (start, end int) {
+	if !t.selectMode {
+		line, _ := t.cursor.GetLineCol()
+		return line, line
+	}
+	start, _ = t.selection.Start.GetLineCol()
+	end, _ = t.selection.End.GetLineCol()
+	return start, end
+}
+
+// editLines applies the lineEdits returned by `edit` for each line from `start`
+// to `end`, inclusive, as a single replacement of the lines from the first to
+// the last one changed. The lineEdits of a line are in order of column, and do
+// not overlap. Cursors, selections and other anchors keep their place in the
+// text of their lines.
+func (t *TextEdit) editLines(start, end int, edit func(line int, text string) []lineEdit) {
+	edits := make([][]lineEdit, end-start+1)
+	first, last := -1, -1 // The lines changed by the edits
+	for line := start; line <= end; line++ {
+		edits[line-start] = edit(line, t.getLineText(line))
+		for _, e := range edits[line-start] {
+			if e.remove > 0 || e.insert != "" {
+				if first < 0 {
+					first = line
+				}
+				last = line
+				break
+			}
+		}
+	}
+	if first < 0 {
+		return
+	}
+	start, end, edits = first, last, edits[first-start:last-start+1] // Keep the unchanged lines out of the replacement
+
+	var text strings.Builder
+	for line := start; line <= end; line++ {
+		lineText := t.getLineText(line)
+		runes := []rune(lineText)
+		for i := len(edits[line-start]) - 1; i >= 0; i-- { // Right to left keeps the columns of the others
+			e := edits[line-start][i]
+			runes = append(runes[:e.col], append([]rune(e.insert), runes[e.col+e.remove:]...)...)
+		}
+		text.WriteString(string(runes))
+		text.WriteString(string(t.Buffer.Line(line))[len(lineText):]) // The line delimiter
+	}
+
+	// Replacing the lines would move everything anchored to them to their start
+	anchors := t.getAnchors()
+	positions := make([][2]int, len(anchors))
+	for i, a := range anchors {
+		positions[i][0], positions[i][1] = a.GetLineCol()
+	}
+	t.ReplaceLines(start, end+1, []byte(text.String()))
+	for i, a := range anchors {
+		line, col := positions[i][0], positions[i][1]
+		if line >= start && line <= end {
+			lineEdits := edits[line-start]
+			for j := len(lineEdits) - 1; j >= 0; j-- {
+				col = lineEdits[j].shift(col)
+			}
+		}
+		*a = a.SetLineCol(line, col)
+	}
+	t.ScrollToCursor()
+	t.updateCursorVisibility()
+}
+
+// getAnchors returns every cursor anchored to the Buffer by the TextEdit: those of
+// the cursors and their selections, the folds, and the signs.
+func (t *TextEdit) getAnchors() []*buffer.Cursor {
+	anchors := []*buffer.Cursor{&t.cursor, &t.selection.Start, &t.selection.End}
+	for _, c := range t.carets {
+		anchors = append(anchors, &c.cursor, &c.selection.Start, &c.selection.End)
+	}
+	for _, f := range t.folds {
+		anchors = append(anchors, &f.Start, &f.End)
+	}
+	for _, group := range t.signs {
+		for _, sign := range group {
+			anchors = append(anchors, &sign.cursor)
+		}
+	}
+	return anchors
+}
+
+// getLineRanges returns the lines of the selection, or the line of the cursor,
+// of every cursor, as ranges of lines in order. Ranges which overlap are merged,
+// so each line is in at most one range.
+func (t *TextEdit) getLineRanges() [][2]int {
+	var ranges [][2]int
+	t.eachCaret(func() {
+		start, end := t.getSelectedLines()
+		ranges = append(ranges, [2]int{start, end})
+	})
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+	merged := ranges[:1]
+	for _, r := range ranges[1:] {
+		if last := &merged[len(merged)-1]; r[0] <= last[1] {
+			last[1] = Max(last[1], r[1])
+		} else {
+			merged = append(merged, r)
+		}
+	}
+	return merged
+}
+
+// selectsLines returns whether any cursor has a selection of more than one line.
+func (t *TextEdit) selectsLines() bool {
+	selects := false
+	t.eachCaret(func() {
+		start, end := t.getSelectedLines()
+		selects = selects || t.selectMode && start != end
+	})
+	return selects
+}
+
+// indentLines indents each line from `start` to `end` by one more level. Blank
+// lines are not indented.
+func (t *TextEdit) indentLines(start, end int) {
+	unit := t.getIndentUnit()
+	t.editLines(start, end, func(_ int, text string) []lineEdit {
+		if strings.TrimSpace(text) == "" {
+			return nil
+		}
+		return []lineEdit{{insert: unit}}
+	})
+}
+
+// outdentLines indents each line from `start` to `end` by one level less,
+// removing a tab or up to TabSize spaces from its start.
+func (t *TextEdit) outdentLines(start, end int) {
+	t.editLines(start, end, func(_ int, text string) []lineEdit {
+		if strings.HasPrefix(text, "\t") {
+			return []lineEdit{{remove: 1}}
+		}
+		spaces := len(text) - len(strings.TrimLeft(text, " "))
+		return []lineEdit{{remove: Min(spaces, t.TabSize)}}
+	})
+}
+
+// eachLineRange calls f once for each range of lines of getLineRanges, so lines
+// selected by several cursors are only edited once.
+func (t *TextEdit) eachLineRange(f func(start, end int)) {
+	t.blockMode = false
+	for _, r := range t.getLineRanges() {
+		f(r[0], r[1])
+	}
+	t.mergeCarets()
+}
+
+// IndentLines indents the lines of each selection, or the line of each cursor,
+// by one more level.
+func (t *TextEdit) IndentLines() {
+	t.eachLineRange(t.indentLines)
+}
+
+// OutdentLines indents the lines of each selection, or the line of each cursor,
+// by one level less.
+func (t *TextEdit) OutdentLines() {
+	t.eachLineRange(t.outdentLines)
+}
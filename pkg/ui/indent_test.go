@@ -0,0 +1,88 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/fivemoreminix/qedit/pkg/buffer"
+)
+
+func TestEditLinesAnchors(t *testing.T) {
+	te := newTestTextEdit("abc\ndefg\nhi\n")
+	te.cursor = te.cursor.SetLineCol(0, 1) // At an insertion
+	te.selectMode = true
+	te.selection.Start = te.selection.Start.SetLineCol(0, 2)
+	te.selection.End = te.selection.End.SetLineCol(1, 3)
+	te.addCursorAt(te.cursor.SetLineCol(1, 1)) // Within a removal
+	c := te.carets[0]
+	sign := &Sign{Glyph: '*'}
+	te.AddSign("test", 2, sign)
+
+	te.editLines(0, 1, func(line int, _ string) []lineEdit {
+		if line == 0 {
+			return []lineEdit{{col: 1, insert: "XY"}}
+		}
+		return []lineEdit{{col: 0, remove: 2}, {col: 3, insert: "Z"}}
+	})
+
+	if contents, expected := string(te.Buffer.Bytes()), "aXYbc\nfZg\nhi\n"; contents != expected {
+		t.Fatalf("Expected %q, got %q", expected, contents)
+	}
+	anchors := []struct {
+		name      string
+		cursor    buffer.Cursor
+		line, col int
+	}{
+		{"cursor", te.cursor, 0, 3},
+		{"selection start", te.selection.Start, 0, 4},
+		{"selection end", te.selection.End, 1, 2},
+		{"caret", c.cursor, 1, 0},
+		{"sign", sign.cursor, 2, 0},
+	}
+	for _, a := range anchors {
+		if line, col := a.cursor.GetLineCol(); line != a.line || col != a.col {
+			t.Errorf("Expected the %s at %d, %d, got %d, %d", a.name, a.line, a.col, line, col)
+		}
+	}
+}
+
+func TestIndentLinesCarets(t *testing.T) {
+	te := newTestTextEdit("a\nb\nc\n")
+	te.UseHardTabs = true
+	te.selectMode = true
+	te.selection.Start = te.selection.Start.SetLineCol(0, 0)
+	te.selection.End = te.selection.End.SetLineCol(1, 0)
+	te.cursor = te.cursor.SetLineCol(1, 0)
+	te.addCursorAt(te.cursor.SetLineCol(1, 1)) // Within the selection
+
+	te.IndentLines()
+	if contents, expected := string(te.Buffer.Bytes()), "\ta\n\tb\nc\n"; contents != expected {
+		t.Errorf("Expected %q, got %q", expected, contents)
+	}
+	te.OutdentLines()
+	if contents, expected := string(te.Buffer.Bytes()), "a\nb\nc\n"; contents != expected {
+		t.Errorf("Expected %q, got %q", expected, contents)
+	}
+}
+
+// changeLines records the lines of each Change to a Buffer.
+type changeLines [][2]int
+
+func (c *changeLines) OnChange(change buffer.Change) {
+	*c = append(*c, [2]int{change.StartLine, change.EndLine})
+}
+
+func TestEditLinesReplacesChangedLines(t *testing.T) {
+	te := newTestTextEdit("a\nb  \nc\nd \ne\n")
+	changes := &changeLines{}
+	te.Buffer.RegisterObserver(changes)
+
+	te.TrimTrailingWhitespace()
+	if contents, expected := string(te.Buffer.Bytes()), "a\nb\nc\nd\ne\n"; contents != expected {
+		t.Fatalf("Expected %q, got %q", expected, contents)
+	}
+	for _, c := range *changes {
+		if c[0] < 1 || c[1] > 4 {
+			t.Errorf("Expected only lines 1 to 3 to be replaced, got a change of lines %d to %d", c[0], c[1])
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package ui
+
+import (
+	"math"
+
+	"github.com/fivemoreminix/qedit/pkg/buffer"
+	"github.com/gdamore/tcell/v2"
+)
+
+// handleMotionKey handles the keys that move the cursor by words, subwords, and
+// paragraphs, and to the ends of lines and of the buffer. With Shift, they extend
+// the selection. It also handles the keys that delete words. Returns whether the
+// key was handled.
+//
+//	Ctrl+Left, Ctrl+Right          previous word start, next word end
+//	Alt+Ctrl+Left, Alt+Ctrl+Right  previous subword start, next subword end
+//	Ctrl+Up, Ctrl+Down             previous paragraph, next paragraph
+//	Home, End                      first non-blank (or first) column, last column
+//	Ctrl+Home, Ctrl+End            start of buffer, end of buffer
+//	Ctrl+Backspace, Ctrl+Delete    delete previous word, delete next word
+func (t *TextEdit) handleMotionKey(ev *tcell.EventKey) bool {
+	ctrl := ev.Modifiers()&tcell.ModCtrl != 0
+	alt := ev.Modifiers()&tcell.ModAlt != 0
+
+	var to buffer.Cursor
+	switch ev.Key() {
+	case tcell.KeyLeft, tcell.KeyRight:
+		if !ctrl {
+			return false
+		}
+		if ev.Key() == tcell.KeyLeft {
+			if alt {
+				to = t.cursor.PrevSubwordStart()
+			} else {
+				to = t.cursor.PrevWordStart()
+			}
+		} else if alt {
+			to = t.cursor.NextSubwordEnd()
+		} else {
+			to = t.cursor.NextWordEnd()
+		}
+	case tcell.KeyUp, tcell.KeyDown:
+		if !ctrl || alt {
+			return false
+		}
+		if ev.Key() == tcell.KeyUp {
+			to = t.cursor.ParagraphUp()
+		} else {
+			to = t.cursor.ParagraphDown()
+		}
+	case tcell.KeyHome:
+		if ctrl {
+			to = t.cursor.BufferStart()
+		} else if to = t.cursor.FirstNonBlank(); to.Eq(t.cursor) {
+			cursLine, _ := t.cursor.GetLineCol()
+			to = t.cursor.SetLineCol(cursLine, 0) // Already at the first non-blank
+		}
+	case tcell.KeyEnd:
+		if ctrl {
+			to = t.cursor.BufferEnd()
+		} else {
+			cursLine, _ := t.cursor.GetLineCol()
+			to = t.cursor.SetLineCol(cursLine, math.MaxInt32) // Max column
+		}
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if !ctrl {
+			return false
+		}
+		t.deleteTo(t.cursor.PrevWordStart())
+		return true
+	case tcell.KeyDelete:
+		if !ctrl {
+			return false
+		}
+		t.deleteTo(t.cursor.NextWordEnd())
+		return true
+	default:
+		return false
+	}
+
+	if ev.Modifiers()&tcell.ModShift != 0 {
+		t.selectTo(to)
+	} else {
+		t.selectMode = false
+		t.SetCursor(to)
+	}
+	t.ScrollToCursor()
+	return true
+}
+
+// selectTo moves the cursor to `to`, and selects the text between it and where
+// the selection began: the end of the selection opposite the cursor, or the
+// cursor, if nothing is selected. The selection excludes the rune at `to` when it
+// extends to the right, like the cursor is between runes.
+func (t *TextEdit) selectTo(to buffer.Cursor) {
+	anchor := t.cursor
+	if t.selectMode && t.cursor.Eq(t.selection.Start) {
+		anchor = t.selection.End.Right() // The selection extends to the left
+	} else if t.selectMode {
+		anchor = t.selection.Start
+	}
+
+	start, end := anchor, to
+	if to.Less(anchor) {
+		start, end = to, anchor
+	}
+	t.SetCursor(to)
+	if t.selectMode = start.Less(end); t.selectMode {
+		t.selection.Start, t.selection.End = start, end.Left()
+	}
+}
+
+// deleteTo deletes the text between the cursor and `to`, or the selection, if
+// there is one.
+func (t *TextEdit) deleteTo(to buffer.Cursor) {
+	if t.selectMode {
+		t.delete(true)
+		return
+	}
+
+	start, end := t.cursor, to
+	if to.Less(t.cursor) {
+		start, end = to, t.cursor
+	}
+	if start.Less(end) {
+		startLine, startCol := start.GetLineCol()
+		endLine, endCol := end.Left().GetLineCol()
+		t.Buffer.Remove(startLine, startCol, endLine, endCol) // The cursor is anchored to start
+	}
+	t.ScrollToCursor()
+	t.updateCursorVisibility()
+}
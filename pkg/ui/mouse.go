@@ -0,0 +1,86 @@
+package ui
+
+import (
+	"github.com/fivemoreminix/qedit/pkg/buffer"
+	"github.com/gdamore/tcell/v2"
+)
+
+// wheelLines is how many lines the view scrolls for each step of the mouse wheel.
+const wheelLines = 3
+
+// handleMouse places the cursor where the primary button is pressed, and selects
+// the text it is dragged over. With Shift, the press extends the selection. The
+// mouse wheel scrolls the view. Returns whether the event was handled.
+func (t *TextEdit) handleMouse(ev *tcell.EventMouse) bool {
+	if t.handleBlockMouse(ev) {
+		return true
+	}
+
+	buttons := ev.Buttons()
+	switch {
+	case buttons&tcell.WheelUp != 0:
+		t.scrollLines(-wheelLines)
+		return true
+	case buttons&tcell.WheelDown != 0:
+		t.scrollLines(wheelLines)
+		return true
+	case buttons&tcell.Button1 == 0:
+		t.selecting = false
+		return false
+	}
+
+	mx, my := ev.Position()
+	if !t.selecting && (mx < t.x || mx >= t.x+t.width || my < t.y || my >= t.y+t.height) {
+		return false
+	}
+	to := t.getCursorAtView(mx-t.x-t.getColumnWidth(), my-t.y)
+	if t.selecting || ev.Modifiers()&tcell.ModShift != 0 {
+		t.selectTo(to)
+	} else {
+		t.RemoveExtraCursors()
+		t.blockMode = false
+		t.selectMode = false
+		t.SetCursor(to)
+	}
+	t.selecting = true
+	t.ScrollToCursor()
+	return true
+}
+
+// getCursorAtView returns a cursor at the rune drawn at the column `x` and row `y`
+// of the view, after the line numbers. A point past the end of a row is at the
+// end of it, and a point past the end of the buffer is at its end.
+func (t *TextEdit) getCursorAtView(x, y int) buffer.Cursor {
+	line, left := t.getLineAtViewRow(Max(0, Min(y, t.height-1)))
+	if line >= t.Buffer.Lines() {
+		return t.cursor.BufferEnd()
+	}
+	col := t.GetColAtDisplayCol(line, Max(0, x)+left)
+	if t.SoftWrap { // Keep to the row clicked, instead of the rows after it
+		rows := t.getWrapRows(line)
+		for i := 0; i < len(rows)-1; i++ {
+			if rows[i].x == left {
+				col = Min(col, rows[i+1].col-1)
+				break
+			}
+		}
+	}
+	return t.cursor.SetLineCol(line, col)
+}
+
+// scrollLines scrolls the view by `n` visible lines, down if `n` is positive,
+// without moving the cursor.
+func (t *TextEdit) scrollLines(n int) {
+	for ; n > 0; n-- {
+		if next := t.nextVisibleLine(t.scrolly); next < t.Buffer.Lines() {
+			t.scrolly = next
+		}
+	}
+	for ; n < 0; n++ {
+		if prev := t.prevVisibleLine(t.scrolly); prev >= 0 {
+			t.scrolly = prev
+		}
+	}
+	t.scrollRow = 0
+	t.updateCursorVisibility()
+}
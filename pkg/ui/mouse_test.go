@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestMouseSelect(t *testing.T) {
+	te := newTestTextEdit("abc\n\t世界 x\nlast\n")
+	te.LineNumbers = false
+	te.TabSize = 4
+	te.SetSize(20, 10)
+	mouse := func(x, y int, buttons tcell.ButtonMask) {
+		te.HandleEvent(tcell.NewEventMouse(x, y, buttons, tcell.ModNone))
+	}
+	expectCursor := func(line, col int) {
+		t.Helper()
+		if l, c := te.cursor.GetLineCol(); l != line || c != col {
+			t.Errorf("Expected the cursor at %d, %d, got %d, %d", line, col, l, c)
+		}
+	}
+
+	mouse(7, 1, tcell.Button1) // On the right half of 界, after the tab and 世
+	expectCursor(1, 2)
+	mouse(0, 1, tcell.ButtonNone)
+	if te.selectMode {
+		t.Errorf("Expected nothing selected after a click")
+	}
+
+	mouse(1, 0, tcell.Button1)
+	mouse(15, 1, tcell.Button1) // Past the end of the line
+	mouse(15, 1, tcell.ButtonNone)
+	expectCursor(1, 5)
+	if !te.selectMode || string(te.GetSelectedBytes()) != "bc\n\t世界 x" {
+		t.Errorf("Expected a selection to the end of line 1, got %q", te.GetSelectedBytes())
+	}
+
+	mouse(3, 9, tcell.Button1) // Past the end of the buffer
+	expectCursor(3, 0)
+}
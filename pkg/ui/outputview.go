@@ -0,0 +1,129 @@
+package ui
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+type outputLine struct {
+	text        string
+	highlighted bool
+}
+
+// An OutputView shows lines of text as they are produced, like the output of a
+// command. One line is selected at a time, and choosing it with Enter calls the
+// LineChosenCallback. While the last line is selected, the view follows new
+// lines as they are appended.
+type OutputView struct {
+	LineChosenCallback func(idx int) // Called when a line is chosen with Enter. Optional.
+
+	lines    []outputLine
+	selected int
+	scrolly  int
+	screen   *tcell.Screen
+
+	baseComponent
+}
+
+func NewOutputView(screen *tcell.Screen, theme *Theme) *OutputView {
+	return &OutputView{
+		screen:        screen,
+		baseComponent: baseComponent{theme: theme},
+	}
+}
+
+// AppendLine adds a line to the end of the output. A highlighted line is drawn
+// in a distinct style, to show that it can be chosen.
+func (v *OutputView) AppendLine(text string, highlighted bool) {
+	following := v.selected >= len(v.lines)-1
+	v.lines = append(v.lines, outputLine{text, highlighted})
+	if following {
+		v.SetSelected(len(v.lines) - 1)
+	}
+}
+
+// Clear removes all lines.
+func (v *OutputView) Clear() {
+	v.lines = v.lines[:0]
+	v.selected, v.scrolly = 0, 0
+}
+
+// GetLineCount returns the number of lines of output.
+func (v *OutputView) GetLineCount() int {
+	return len(v.lines)
+}
+
+// GetSelected returns the index of the selected line.
+func (v *OutputView) GetSelected() int {
+	return v.selected
+}
+
+// SetSelected selects the line at `idx`, clamped to the available lines, and
+// scrolls to show it.
+func (v *OutputView) SetSelected(idx int) {
+	v.selected = Clamp(idx, 0, Max(0, len(v.lines)-1))
+	if v.selected < v.scrolly {
+		v.scrolly = v.selected
+	} else if v.selected >= v.scrolly+v.height {
+		v.scrolly = v.selected - v.height + 1
+	}
+}
+
+func (v *OutputView) Draw(s tcell.Screen) {
+	normalStyle := v.theme.GetOrDefault("TextEdit")
+	highlightedStyle := v.theme.GetOrDefault("OutputHighlighted")
+	selectedStyle := v.theme.GetOrDefault("TextEditSelected")
+
+	DrawRect(s, v.x, v.y, v.width, v.height, ' ', normalStyle)
+	for y := 0; y < v.height && v.scrolly+y < len(v.lines); y++ {
+		idx := v.scrolly + y
+		style := normalStyle
+		if v.lines[idx].highlighted {
+			style = highlightedStyle
+		}
+		if idx == v.selected && v.focused {
+			style = selectedStyle
+			DrawRect(s, v.x, v.y+y, v.width, 1, ' ', style)
+		}
+		DrawStr(s, v.x, v.y+y, runewidth.Truncate(v.lines[idx].text, v.width, "…"), style)
+	}
+
+	if v.focused {
+		(*v.screen).HideCursor()
+	}
+}
+
+func (v *OutputView) SetSize(width, height int) {
+	v.width, v.height = width, height
+	v.SetSelected(v.selected)
+}
+
+// HandleEvent moves the selection with the arrow keys, page keys, Home and End,
+// and chooses the selected line with Enter.
+func (v *OutputView) HandleEvent(event tcell.Event) bool {
+	switch ev := event.(type) {
+	case *tcell.EventKey:
+		switch ev.Key() {
+		case tcell.KeyUp:
+			v.SetSelected(v.selected - 1)
+		case tcell.KeyDown:
+			v.SetSelected(v.selected + 1)
+		case tcell.KeyPgUp:
+			v.SetSelected(v.selected - v.height)
+		case tcell.KeyPgDn:
+			v.SetSelected(v.selected + v.height)
+		case tcell.KeyHome:
+			v.SetSelected(0)
+		case tcell.KeyEnd:
+			v.SetSelected(len(v.lines) - 1)
+		case tcell.KeyEnter:
+			if v.LineChosenCallback != nil && v.selected < len(v.lines) {
+				v.LineChosenCallback(v.selected)
+			}
+		default:
+			return false
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package ui
+
+import (
+	"sort"
+
+	"github.com/fivemoreminix/qedit/pkg/buffer"
+	"github.com/gdamore/tcell/v2"
+)
+
+// A Sign is a marker drawn in the sign column of a TextEdit, to the left of the
+// line numbers. Signs are used for things like diagnostics, version control
+// markers, and bookmarks. A Sign is anchored to the Buffer of the TextEdit it
+// was added to, so it moves with the line it was placed on as edits occur.
+type Sign struct {
+	Glyph    rune
+	Style    tcell.Style
+	Tooltip  string // Text describing the Sign, for the status bar or similar
+	Priority int    // When multiple Signs are on a line, the highest Priority is drawn
+
+	cursor buffer.Cursor // Registered with the Buffer while the Sign is added
+}
+
+// GetLine returns the line the Sign is currently on.
+func (s *Sign) GetLine() int {
+	line, _ := s.cursor.GetLineCol()
+	return line
+}
+
+// A SignProvider gives a TextEdit Signs computed on demand, instead of Signs
+// anchored to lines. It suits providers with many Signs over ranges of lines, like
+// the changes since a version of the file, which would be costly to anchor.
+type SignProvider interface {
+	// GetSigns returns the Sign on each line from startLine to endLine,
+	// inclusively, that has one.
+	GetSigns(startLine, endLine int) map[int]*Sign
+}
+
+// SetSignProvider sets the SignProvider of the group named `group`, whose Signs
+// are shown alongside the Signs added to the TextEdit. A nil SignProvider removes
+// the group's provider.
+func (t *TextEdit) SetSignProvider(group string, provider SignProvider) {
+	if provider == nil {
+		delete(t.signProviders, group)
+		return
+	}
+	if t.signProviders == nil {
+		t.signProviders = make(map[string]SignProvider)
+	}
+	t.signProviders[group] = provider
+}
+
+// AddSign places the Sign at the beginning of `line` in the group named `group`.
+// Groups let each provider of Signs manage its own, for example, with ClearSigns.
+// The Sign must not already be added to a TextEdit.
+func (t *TextEdit) AddSign(group string, line int, sign *Sign) {
+	if t.signs == nil {
+		t.signs = make(map[string][]*Sign)
+	}
+	sign.cursor = buffer.NewCursor(&t.Buffer).SetLineCol(line, 0)
+	t.Buffer.RegisterCursor(&sign.cursor)
+	t.signs[group] = append(t.signs[group], sign)
+}
+
+// RemoveSign removes the Sign from the group. Returns true if it was found.
+func (t *TextEdit) RemoveSign(group string, sign *Sign) bool {
+	signs := t.signs[group]
+	for i := range signs {
+		if signs[i] == sign {
+			t.Buffer.UnregisterCursor(&sign.cursor)
+			t.signs[group] = append(signs[:i], signs[i+1:]...)
+			if len(t.signs[group]) == 0 {
+				delete(t.signs, group)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// ClearSigns removes every Sign in the group.
+func (t *TextEdit) ClearSigns(group string) {
+	for _, sign := range t.signs[group] {
+		t.Buffer.UnregisterCursor(&sign.cursor)
+	}
+	delete(t.signs, group)
+}
+
+// GetSignGroup returns the Signs in the group, in the order they were added.
+// Do not modify the returned slice.
+func (t *TextEdit) GetSignGroup(group string) []*Sign {
+	return t.signs[group]
+}
+
+// GetSigns returns all Signs on `line`, from highest to lowest Priority.
+func (t *TextEdit) GetSigns(line int) []*Sign {
+	var signs []*Sign
+	for _, group := range t.signs {
+		for _, sign := range group {
+			if sign.GetLine() == line {
+				signs = append(signs, sign)
+			}
+		}
+	}
+	for _, provider := range t.signProviders {
+		if sign, ok := provider.GetSigns(line, line)[line]; ok {
+			signs = append(signs, sign)
+		}
+	}
+	sort.SliceStable(signs, func(i, j int) bool { return signs[i].Priority > signs[j].Priority })
+	return signs
+}
+
+// getSignColumnWidth returns the width of the sign column, which is only present
+// when the TextEdit has any Signs or SignProviders.
+func (t *TextEdit) getSignColumnWidth() int {
+	if len(t.signs) > 0 || len(t.signProviders) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// getVisibleSigns returns a map of each line between startLine and endLine,
+// inclusively, to the Sign with the highest Priority on that line.
+func (t *TextEdit) getVisibleSigns(startLine, endLine int) map[int]*Sign {
+	visible := make(map[int]*Sign)
+	for _, group := range t.signs {
+		for _, sign := range group {
+			line := sign.GetLine()
+			if line < startLine || line > endLine {
+				continue
+			}
+			if other, ok := visible[line]; !ok || sign.Priority > other.Priority {
+				visible[line] = sign
+			}
+		}
+	}
+	for _, provider := range t.signProviders {
+		for line, sign := range provider.GetSigns(startLine, endLine) {
+			if other, ok := visible[line]; !ok || sign.Priority > other.Priority {
+				visible[line] = sign
+			}
+		}
+	}
+	return visible
+}
@@ -4,14 +4,16 @@ import (
 	"bytes"
 	"fmt"
 	"math"
-	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"unicode"
 	"unicode/utf8"
 
 	"github.com/fivemoreminix/qedit/pkg/buffer"
+	"github.com/fivemoreminix/qedit/pkg/diff"
 	"github.com/gdamore/tcell/v2"
-	"github.com/mattn/go-runewidth"
+	"github.com/rivo/uniseg"
 )
 
 // TextEdit is a field for line-based editing. It features syntax highlighting
@@ -19,21 +21,52 @@ import (
 // content being edited.
 type TextEdit struct {
 	Buffer      buffer.Buffer
-	Highlighter *buffer.Highlighter
+	Highlighter buffer.Highlighter
+	Language    *buffer.Language
 	LineNumbers bool   // Whether to render line numbers (and therefore the column)
 	Dirty       bool   // Whether the buffer has been edited
 	UseHardTabs bool   // When true, tabs are '\t'
 	TabSize     int    // How many spaces to indent by
 	IsCRLF      bool   // Whether the file's line endings are CRLF (\r\n) or LF (\n)
+	LargeFile   bool   // Whether features that read the whole file are disabled
+	AutoPair    bool   // Whether brackets and quotes are closed as they are typed
+	AutoIndent  bool   // Whether new lines are indented by the rules of the Language
 	FilePath    string // Will be empty if the file has not been saved yet
+	SoftWrap    bool   // Whether long lines are wrapped into rows, instead of scrolled
+	WrapColumn  int    // The display column to wrap at, or 0 to wrap at the view width
 
+	revision         int           // Incremented on every change to the contents
 	screen           *tcell.Screen // We keep our own reference to the screen for cursor purposes.
 	cursor           buffer.Cursor
 	scrollx, scrolly int // X and Y offset of view, known as scroll
+	scrollRow        int // Rows of line scrolly above the view, with soft wrap
 	theme            *Theme
+	colorscheme      *buffer.Colorscheme
 
 	selection  buffer.Region // Selection: selectMode determines if it should be used
 	selectMode bool          // Whether the user is actively selecting text
+	carets     []*caret      // Cursors other than the primary cursor
+
+	blockMode bool        // Whether the selection is a rectangular block
+	block     columnBlock // The block selection, when blockMode is true
+	dragging  bool        // Whether the mouse is dragging a block selection
+	selecting bool        // Whether the mouse is dragging a selection
+
+	signs         map[string][]*Sign      // Signs in the sign column, by group
+	signProviders map[string]SignProvider // Compute more Signs, by group
+
+	conflicts      []buffer.Conflict      // Merge conflicts, as of the last GetConflicts
+	conflictFinder *buffer.ConflictFinder // Finds the conflicts as the Buffer is edited
+
+	folds              []*buffer.Region   // Folded ranges of lines, anchored to the Buffer
+	folded             []buffer.FoldRange // Lines of the folds, as of foldedRevision
+	foldedRevision     int                // -1 when folds were added or removed since
+	foldRanges         []buffer.FoldRange // Ranges of lines which can be folded, as of foldRangesRevision
+	foldRangesRevision int
+
+	mappedFile   *buffer.MappedFile // Contents of a large file, or nil
+	stopIndexing func()             // Stops counting the lines of a large file
+	indexed      atomic.Int64       // Bytes of a large file whose lines have been counted
 
 	baseComponent
 }
@@ -45,8 +78,8 @@ func NewTextEdit(screen *tcell.Screen, filePath string, contents []byte, theme *
 		Buffer:      nil, // Set in SetContents
 		Highlighter: nil, // Set in SetContents
 		LineNumbers: true,
-		UseHardTabs: true,
-		TabSize:     4,
+		AutoPair:    true,
+		AutoIndent:  true,
 		FilePath:    filePath,
 
 		screen:        screen,
@@ -56,9 +89,90 @@ func NewTextEdit(screen *tcell.Screen, filePath string, contents []byte, theme *
 	return te
 }
 
+// NewLargeTextEdit opens a large file that has been mapped into memory, without
+// reading all of it. Its lines are counted in the background, and features that
+// would read the whole file, like highlighting and line numbers, are disabled.
+// The TextEdit must be closed with Close, to unmap the file.
+func NewLargeTextEdit(screen *tcell.Screen, filePath string, file *buffer.MappedFile, theme *Theme) *TextEdit {
+	te := &TextEdit{
+		Language:   buffer.PlainText,
+		AutoPair:   true,
+		AutoIndent: true,
+		FilePath:   filePath,
+		LargeFile:  true,
+
+		screen:        screen,
+		mappedFile:    file,
+		baseComponent: baseComponent{theme: theme},
+	}
+	pieceTable := buffer.NewPieceTable(file.Data)
+	te.setBuffer(pieceTable)
+	file.Guard(func() { // Detected from the start of the file, without reading the whole file
+		start := file.Data[:Min(len(file.Data), 64*1024)]
+		te.detectLineDelimiter(start)
+		te.detectIndentation(start)
+	})
+	te.stopIndexing = pieceTable.IndexLines(func(done, total int) {
+		// Redraw at every percent of progress
+		if prev := te.indexed.Swap(int64(done)); done == total || done*100/total != int(prev)*100/total {
+			if screen != nil && *screen != nil {
+				ev := &EventLoadProgress{}
+				ev.SetEventNow()
+				(*screen).PostEvent(ev) // Dropped if the queue is full; Close waits for us
+			}
+		}
+	})
+	return te
+}
+
+// EventLoadProgress is posted to the screen as the lines of a large file are
+// counted, so the progress can be drawn.
+type EventLoadProgress struct {
+	tcell.EventTime
+}
+
+// GetLoadProgress returns how many bytes of a large file have been read to count
+// its lines, and the size of the file. Until they are equal, the number of lines
+// in the Buffer is incomplete.
+func (t *TextEdit) GetLoadProgress() (done, total int) {
+	if t.mappedFile == nil {
+		return 0, 0
+	}
+	return int(t.indexed.Load()), len(t.mappedFile.Data)
+}
+
+// Err returns buffer.ErrFileTruncated if a large file was truncated by another
+// program while it is open, so its contents could not all be read.
+func (t *TextEdit) Err() error {
+	if pieceTable, ok := t.Buffer.(*buffer.PieceTable); ok {
+		return pieceTable.Err()
+	}
+	return nil
+}
+
+// Close stops any work the TextEdit does in the background, like highlighting,
+// and unmaps the file of a large file. The TextEdit must not be used afterwards.
+func (t *TextEdit) Close() {
+	t.closeHighlighter()
+	if t.stopIndexing != nil {
+		t.stopIndexing()
+	}
+	if t.mappedFile != nil {
+		t.mappedFile.Close()
+	}
+}
+
 // SetContents applies the string to the internal buffer of the TextEdit component.
-// The string is determined to be either CRLF or LF based on line-endings.
+// The string is determined to be either CRLF or LF based on line-endings, and
+// indented with tabs or spaces based on its indentation.
 func (t *TextEdit) SetContents(contents []byte) {
+	t.detectLineDelimiter(contents)
+	t.setBuffer(buffer.NewRopeBuffer(contents))
+	t.detectIndentation(contents)
+}
+
+// detectLineDelimiter sets IsCRLF by the first line delimiter in `contents`.
+func (t *TextEdit) detectLineDelimiter(contents []byte) {
 	var i int
 loop:
 	for i < len(contents) {
@@ -74,35 +188,26 @@ loop:
 		_, size := utf8.DecodeRune(contents[i:])
 		i += size
 	}
+}
 
-	t.Buffer = buffer.NewRopeBuffer(contents)
+// setBuffer replaces the Buffer of the TextEdit, and everything anchored to it.
+func (t *TextEdit) setBuffer(buf buffer.Buffer) {
+	t.Buffer = buf
+	t.Buffer.RegisterObserver(&textEditObserver{t})
+	t.revision++
+	t.signs = nil // Signs were anchored to the previous Buffer
 	t.cursor = buffer.NewCursor(&t.Buffer)
 	t.Buffer.RegisterCursor(&t.cursor)
 	t.selection = buffer.NewRegion(&t.Buffer)
-
-	// TODO: replace with automatic determination of language via filetype
-	lang := &buffer.Language{
-		Name:      "Go",
-		Filetypes: []string{".go"},
-		Rules: map[*buffer.RegexpRegion]buffer.Syntax{
-			{Start: regexp.MustCompile(`\/\/.*`)}: buffer.Comment,
-			{Start: regexp.MustCompile(`".*?"`)}:  buffer.String,
-			{
-				Start: regexp.MustCompile(`\b(var|const|if|else|range|for|switch|fallthrough|case|default|break|continue|go|func|return|defer|import|type|package)\b`),
-			}: buffer.Keyword,
-			{
-				Start: regexp.MustCompile(`\b(u?int(8|16|32|64)?|rune|byte|string|bool|struct)\b`),
-			}: buffer.Type,
-			{
-				Start: regexp.MustCompile(`\b([1-9][0-9]*|0[0-7]*|0[Xx][0-9A-Fa-f]+|0[Bb][01]+)\b`),
-			}: buffer.Number,
-			{
-				Start: regexp.MustCompile(`\b(len|cap|panic|make|copy|append)\b`),
-			}: buffer.Builtin,
-			{
-				Start: regexp.MustCompile(`\b(nil|true|false)\b`),
-			}: buffer.Special,
-		},
+	t.Buffer.RegisterCursor(&t.selection.Start)
+	t.Buffer.RegisterCursor(&t.selection.End)
+	t.carets = nil // Cursors were anchored to the previous Buffer
+	t.folds = nil
+	t.blockMode = false
+	t.conflictFinder = nil // Looks at the previous Buffer
+
+	if t.Language == nil {
+		t.Language = buffer.LanguageByFilename(t.FilePath)
 	}
 
 	colorscheme := &buffer.Colorscheme{
@@ -115,9 +220,79 @@ loop:
 		buffer.Number:  tcell.Style{}.Foreground(tcell.ColorFuchsia).Background(tcell.ColorBlack),
 		buffer.Builtin: tcell.Style{}.Foreground(tcell.ColorBlue).Background(tcell.ColorBlack),
 		buffer.Special: tcell.Style{}.Foreground(tcell.ColorFuchsia).Background(tcell.ColorBlack),
+
+		buffer.DocComment: tcell.Style{}.Foreground(tcell.ColorTeal).Background(tcell.ColorBlack),
 	}
 
-	t.Highlighter = buffer.NewHighlighter(t.Buffer, lang, colorscheme)
+	t.colorscheme = colorscheme
+	t.Highlighter = nil // Belonged to the previous Buffer
+	t.resetHighlighter()
+}
+
+// A textEditObserver keeps the state of a TextEdit up to date with the Changes to
+// its Buffer.
+type textEditObserver struct {
+	t *TextEdit
+}
+
+func (o *textEditObserver) OnChange(change buffer.Change) {
+	o.t.Dirty = true
+	o.t.revision++
+}
+
+// EventHighlight is posted to the screen when lines of a TextEdit have been
+// highlighted in the background. Apply must be called in the main goroutine upon
+// receiving it.
+type EventHighlight struct {
+	tcell.EventTime
+	highlighter *buffer.AsyncHighlighter
+	result      *buffer.HighlightResult
+}
+
+// Apply uses the highlighting in the event, unless it is outdated.
+func (ev *EventHighlight) Apply() {
+	ev.highlighter.ApplyResult(ev.result)
+}
+
+// resetHighlighter replaces the Highlighter with a new one for the Language of
+// the TextEdit. With a screen, highlighting is done in the background, and posted
+// as EventHighlights.
+func (t *TextEdit) resetHighlighter() {
+	t.closeHighlighter()
+
+	if t.LargeFile {
+		t.Highlighter = buffer.NewPlainHighlighter(t.colorscheme)
+	} else if t.screen == nil || *t.screen == nil {
+		t.Highlighter = buffer.NewHighlighter(t.Buffer, t.Language, t.colorscheme)
+	} else {
+		var highlighter *buffer.AsyncHighlighter
+		highlighter = buffer.NewAsyncHighlighter(t.Buffer, t.Language, t.colorscheme, func(result *buffer.HighlightResult) bool {
+			ev := &EventHighlight{highlighter: highlighter, result: result}
+			ev.SetEventNow()
+			return (*t.screen).PostEvent(ev) == nil // Dropped if the queue is full, and redone later
+		})
+		t.Highlighter = highlighter
+	}
+	t.Buffer.RegisterObserver(t.Highlighter)
+}
+
+// closeHighlighter stops the Highlighter from being notified of changes, and
+// stops highlighting in the background.
+func (t *TextEdit) closeHighlighter() {
+	if t.Highlighter == nil {
+		return
+	}
+	t.Buffer.UnregisterObserver(t.Highlighter)
+	if async, ok := t.Highlighter.(*buffer.AsyncHighlighter); ok {
+		async.Close()
+	}
+}
+
+// SetLanguage changes the Language of the TextEdit, which is used for syntax
+// highlighting and formatting.
+func (t *TextEdit) SetLanguage(lang *buffer.Language) {
+	t.Language = lang
+	t.resetHighlighter()
 }
 
 // GetLineDelimiter returns "\r\n" for a CRLF buffer, or "\n" for an LF buffer.
@@ -140,15 +315,123 @@ func (t *TextEdit) ChangeLineDelimiters(crlf bool) {
 	panic("Cannot ChangeLineDelimiters")
 }
 
-// Delete with `forwards` false will backspace, destroying the character before the cursor,
-// while Delete with `forwards` true will delete the character after (or on) the cursor.
+// GetRevision returns a number that changes every time the contents of the
+// TextEdit are changed. It can be compared with a previous revision to know
+// if the contents need to be looked at again.
+func (t *TextEdit) GetRevision() int {
+	return t.revision
+}
+
+// ReplaceLines replaces the lines [startLine, endLine) with `text`. If endLine
+// equals startLine, `text` is inserted before startLine. The `text` should end
+// with a line delimiter, unless it is placed at the end of the buffer.
+func (t *TextEdit) ReplaceLines(startLine, endLine int, text []byte) {
+	if lines := t.Buffer.Lines(); endLine >= lines { // If replacing to the end of the buffer...
+		endLine = lines
+		if startLine < endLine {
+			t.Buffer.Remove(startLine, 0, lines-1, math.MaxInt32)
+		}
+	} else if startLine < endLine {
+		lastLine := endLine - 1
+		t.Buffer.Remove(startLine, 0, lastLine, t.Buffer.RunesInLineWithDelim(lastLine)-1) // Includes delimiter
+	}
+
+	if len(text) > 0 {
+		t.Buffer.Insert(startLine, 0, text)
+	}
+
+	t.ScrollToCursor()
+	t.updateCursorVisibility()
+}
+
+// ReplaceContents changes the contents of the TextEdit to `contents` with as
+// few edits as possible, by only replacing the lines that differ. Unlike with
+// SetContents, the cursor and anything else anchored to the Buffer stays with
+// the lines that are unchanged.
+func (t *TextEdit) ReplaceContents(contents []byte) {
+	oldLines, newLines := diff.SplitLines(t.Buffer.Bytes()), diff.SplitLines(contents)
+	hunks := diff.Diff(oldLines, newLines)
+	if len(hunks) == 0 {
+		return
+	}
+
+	// A cursor within replaced lines would be moved to the end of the replacement,
+	// so keep it on the line that only differs in whitespace, if there is one, or
+	// on the same line within the hunk.
+	cursorLine, cursorCol := t.cursor.GetLineCol()
+	newCursorLine := -1
+	for _, h := range hunks {
+		if cursorLine >= h.OldStart && cursorLine < h.OldEnd {
+			newCursorLine = h.NewStart + Min(cursorLine-h.OldStart, Max(0, h.NewEnd-h.NewStart-1))
+			if cursorLine < len(oldLines) {
+				stripped := strings.Join(strings.Fields(oldLines[cursorLine]), "")
+				for i := h.NewStart; i < h.NewEnd; i++ {
+					if strings.Join(strings.Fields(newLines[i]), "") == stripped {
+						newCursorLine = i
+						break
+					}
+				}
+			}
+		}
+	}
+
+	for i := len(hunks) - 1; i >= 0; i-- { // Bottom-up, so line numbers stay valid
+		h := hunks[i]
+		t.ReplaceLines(h.OldStart, h.OldEnd, []byte(strings.Join(newLines[h.NewStart:h.NewEnd], "")))
+	}
+
+	if newCursorLine >= 0 {
+		t.SetCursor(t.cursor.SetLineCol(newCursorLine, cursorCol))
+		t.ScrollToCursor()
+	}
+}
+
+// Format formats the contents with the formatter of the Language, if it has one.
+// The formatted contents are applied with ReplaceContents. If formatting fails,
+// the contents are left untouched and the error is returned.
+func (t *TextEdit) Format() error {
+	if !t.Language.CanFormat() {
+		return nil
+	}
+
+	src := t.Buffer.Bytes()
+	if t.IsCRLF { // Formatters expect LF line endings
+		src = bytes.ReplaceAll(src, []byte{'\r', '\n'}, []byte{'\n'})
+	}
+	formatted, err := t.Language.Format(src)
+	if err != nil {
+		return err
+	}
+	if t.IsCRLF {
+		formatted = bytes.ReplaceAll(formatted, []byte{'\n'}, []byte{'\r', '\n'})
+	}
+
+	t.ReplaceContents(formatted)
+	return nil
+}
+
+// Delete with `forwards` false will backspace, destroying the character before each cursor,
+// while Delete with `forwards` true will delete the character after (or on) each cursor.
 // In insert mode, forwards is always true.
 func (t *TextEdit) Delete(forwards bool) {
-	t.Dirty = true
+	if t.blockMode && t.blockToCursors() {
+		t.mergeCarets()
+		t.updateCursorVisibility()
+		return // Deleted the contents of the block
+	}
+	t.eachCaret(func() { t.delete(forwards) })
+	t.mergeCarets()
+}
 
-	var deletedLine bool // Whether any whole line has been deleted (changing the # of lines)
+// delete is Delete for only the primary cursor.
+func (t *TextEdit) delete(forwards bool) {
 	cursLine, cursCol := t.cursor.GetLineCol()
-	startingLine := cursLine
+
+	if !t.selectMode && !forwards && t.deletePair() { // Deleted both of an empty pair
+		t.ScrollToCursor()
+		t.updateCursorVisibility()
+		return
+	}
 
 	if t.selectMode { // If text is selected, delete the whole selection
 		t.selectMode = false // Disable selection and prevent infinite loop
@@ -159,71 +442,80 @@ func (t *TextEdit) Delete(forwards bool) {
 		// Delete the region
 		t.Buffer.Remove(startLine, startCol, endLine, endCol)
 		t.cursor.SetLineCol(startLine, startCol) // Set cursor to start of region
-
-		deletedLine = startLine != endLine
 	} else { // Not deleting selection
 		if forwards { // Delete the character after the cursor
 			// If the cursor is not at the end of the last line...
 			if cursLine < t.Buffer.Lines()-1 || cursCol < t.Buffer.RunesInLine(cursLine) {
-				bytes := t.Buffer.Slice(cursLine, cursCol, cursLine, cursCol) // Get the character at cursor
-				deletedLine = bytes[0] == '\n'
-
-				t.Buffer.Remove(cursLine, cursCol, cursLine, cursCol) // Remove character at cursor
+				endCol := cursCol // Remove the whole grapheme cluster at the cursor
+				if nextLine, nextCol := t.cursor.Right().GetLineCol(); nextLine == cursLine {
+					endCol = Max(cursCol, nextCol-1)
+				} else if t.getRuneAt(cursLine, cursCol) == '\r' {
+					endCol++ // Both runes of a CRLF
+				}
+				t.Buffer.Remove(cursLine, cursCol, cursLine, endCol)
 			}
 		} else { // Delete the character before the cursor
 			// If the cursor is not at the first column of the first line...
 			if cursLine > 0 || cursCol > 0 {
+				endLine, endCol := cursLine, cursCol-1
 				t.cursor = t.cursor.Left() // Back up to that character
 				cursLine, cursCol = t.cursor.GetLineCol()
+				if endLine != cursLine {
+					endCol = cursCol // The line delimiter
+					if t.getRuneAt(cursLine, cursCol) == '\r' {
+						endCol++ // Both runes of a CRLF
+					}
+				}
 
-				bytes := t.Buffer.Slice(cursLine, cursCol, cursLine, cursCol) // Get the char at cursor
-				deletedLine = bytes[0] == '\n'
-
-				t.Buffer.Remove(cursLine, cursCol, cursLine, cursCol) // Remove character at cursor
+				t.Buffer.Remove(cursLine, cursCol, cursLine, endCol) // Remove the grapheme cluster before the cursor
 			}
 		}
 	}
 
 	t.ScrollToCursor()
 	t.updateCursorVisibility()
-
-	if deletedLine {
-		t.Highlighter.InvalidateLines(startingLine, t.Buffer.Lines()-1)
-	} else {
-		t.Highlighter.InvalidateLines(startingLine, startingLine)
-	}
 }
 
-// Writes `contents` at the cursor position. Line delimiters and tab character supported.
+// Writes `contents` at each cursor position. Line delimiters and tab character supported.
 // Any other control characters will be printed. Overwrites any active selection.
 func (t *TextEdit) Insert(contents string) {
-	t.Dirty = true
+	if t.blockMode {
+		t.blockToCursors() // Overwrite the block on each of its lines
+	}
+	t.eachCaret(func() { t.insert(contents) })
+	t.mergeCarets()
+}
 
+// insert is Insert for only the primary cursor.
+func (t *TextEdit) insert(contents string) {
 	if t.selectMode { // If there is a selection...
 		// Go to and delete the selection
-		t.Delete(true) // The parameter doesn't matter with selection
+		t.delete(true) // The parameter doesn't matter with selection
 	}
 
-	var lineInserted bool // True if contents contains a '\n'
-	cursLine, cursCol := t.cursor.GetLineCol()
-	startingLine := cursLine
+	if t.insertNewline(contents) {
+		t.ScrollToCursor()
+		t.updateCursorVisibility()
+		return
+	}
 
 	runes := []rune(contents)
+	typedLine, _ := t.cursor.GetLineCol()
+	typed := len(runes) == 1 && unicode.IsPrint(runes[0]) && !t.dedentsLine(typedLine) // Whether typing may dedent the line
 	for i := 0; i < len(runes); i++ {
 		ch := runes[i]
+		cursLine, cursCol := t.cursor.GetLineCol() // The anchored cursor moves after each insert
 		switch ch {
 		case '\r':
 			// If the character after is a \n, then it is a CRLF
 			if i+1 < len(runes) && runes[i+1] == '\n' {
 				i++ // Consume '\n' after
 				t.Buffer.Insert(cursLine, cursCol, []byte{'\n'})
-				lineInserted = true
 			}
 		case '\n':
 			t.Buffer.Insert(cursLine, cursCol, []byte{'\n'})
-			lineInserted = true
 		case '\b':
-			t.Delete(false) // Delete the character before the cursor
+			t.delete(false) // Delete the character before the cursor
 		case '\t':
 			if !t.UseHardTabs { // If this file does not use hard tabs...
 				// Insert spaces
@@ -238,26 +530,12 @@ func (t *TextEdit) Insert(contents string) {
 		}
 	}
 
-	t.ScrollToCursor()
-	t.updateCursorVisibility()
-
-	if lineInserted {
-		t.Highlighter.InvalidateLines(startingLine, t.Buffer.Lines()-1)
-	} else {
-		t.Highlighter.InvalidateLines(startingLine, startingLine)
+	if typed && t.dedentsLine(typedLine) {
+		t.dedentLine(typedLine)
 	}
-}
 
-// getTabCountInLineAtCol returns tabs in the given line, before the column position,
-// if hard tabs are enabled. If hard tabs are not enabled, the function returns zero.
-// Multiply returned tab count by TabSize to get the offset produced by tabs.
-// Col must be a valid column position in the given line. Maybe call clampLineCol before
-// this function.
-func (t *TextEdit) getTabCountInLineAtCol(line, col int) int {
-	if t.UseHardTabs {
-		return t.Buffer.Count(line, 0, line, col, []byte{'\t'})
-	}
-	return 0
+	t.ScrollToCursor()
+	t.updateCursorVisibility()
 }
 
 // updateCursorVisibility sets the position of the terminal's cursor with the
@@ -267,8 +545,9 @@ func (t *TextEdit) updateCursorVisibility() {
 	if t.focused && !t.selectMode {
 		columnWidth := t.getColumnWidth()
 		line, col := t.cursor.GetLineCol()
-		tabOffset := t.getTabCountInLineAtCol(line, col) * (t.TabSize - 1)
-		(*t.screen).ShowCursor(t.x+columnWidth+col+tabOffset-t.scrollx, t.y+line-t.scrolly)
+		row, left := t.getRowAt(line, col)
+		x := t.GetDisplayCol(line, col) // Display width of the line before the cursor
+		(*t.screen).ShowCursor(t.x+columnWidth+x-left, t.y+t.getViewRow(line, row))
 	}
 }
 
@@ -276,11 +555,23 @@ func (t *TextEdit) updateCursorVisibility() {
 func (t *TextEdit) ScrollToCursor() {
 	line, col := t.cursor.GetLineCol()
 
-	// Handle hard tabs
-	tabOffset := t.getTabCountInLineAtCol(line, col) * (t.TabSize - 1) // Offset for the current line from hard tabs
+	// The column the cursor is drawn at, after hard tabs and wide characters
+	x := t.GetDisplayCol(line, col)
 
-	// Scroll the screen when going to lines out of view
-	if line >= t.scrolly+t.height-1 { // If the new line is below view...
+	t.revealLine(line)
+
+	if t.SoftWrap || len(t.folds) > 0 { // Scroll by rows, skipping folded lines
+		row, _ := t.getRowAt(line, col)
+		if y := t.getViewRow(line, row); y < 0 {
+			t.scrolly, t.scrollRow = line, row
+		} else if y >= t.height {
+			t.scrollToRow(line, row, t.height-1)
+		}
+		if t.SoftWrap { // Never scroll horizontally
+			t.scrollx = 0
+			return
+		}
+	} else if line >= t.scrolly+t.height-1 { // If the new line is below view...
 		t.scrolly = line - t.height + 1 // Scroll just enough to view that line
 	} else if line < t.scrolly { // If the new line is above view
 		t.scrolly = line
@@ -289,10 +580,10 @@ func (t *TextEdit) ScrollToCursor() {
 	columnWidth := t.getColumnWidth()
 
 	// Scroll the screen horizontally when going to columns out of view
-	if col+tabOffset >= t.scrollx+(t.width-columnWidth-1) { // If the new column is right of view
-		t.scrollx = (col + tabOffset) - (t.width - columnWidth) + 1 // Scroll just enough to view that column
-	} else if col+tabOffset < t.scrollx { // If the new column is left of view
-		t.scrollx = col + tabOffset // Scroll left enough to view that column
+	if x >= t.scrollx+(t.width-columnWidth-1) { // If the new column is right of view
+		t.scrollx = x - (t.width - columnWidth) + 1 // Scroll just enough to view that column
+	} else if x < t.scrollx { // If the new column is left of view
+		t.scrollx = x // Scroll left enough to view that column
 	}
 }
 
@@ -305,8 +596,14 @@ func (t *TextEdit) SetCursor(newCursor buffer.Cursor) {
 	t.updateCursorVisibility()
 }
 
-// getColumnWidth returns the width of the line numbers column if it is present.
+// getColumnWidth returns the combined width of the sign column and the line numbers
+// column, if they are present.
 func (t *TextEdit) getColumnWidth() int {
+	return t.getSignColumnWidth() + t.getLineNumbersWidth()
+}
+
+// getLineNumbersWidth returns the width of the line numbers column if it is present.
+func (t *TextEdit) getLineNumbersWidth() int {
 	var columnWidth int
 	if t.LineNumbers {
 		// Set columnWidth to max count of line number digits
@@ -320,6 +617,9 @@ func (t *TextEdit) getColumnWidth() int {
 // be a copy of the buffer, so do not write to it.
 func (t *TextEdit) GetSelectedBytes() []byte {
 	// TODO: there's a bug with copying text
+	if t.blockMode {
+		return t.getBlockBytes()
+	}
 	if t.selectMode {
 		startLine, startCol := t.selection.Start.GetLineCol()
 		endLine, endCol := t.selection.End.GetLineCol()
@@ -331,119 +631,113 @@ func (t *TextEdit) GetSelectedBytes() []byte {
 // Draw renders the TextEdit component.
 func (t *TextEdit) Draw(s tcell.Screen) {
 	columnWidth := t.getColumnWidth()
+	signWidth := t.getSignColumnWidth()
 	bufferLines := t.Buffer.Lines()
 
 	selectedStyle := t.theme.GetOrDefault("TextEditSelected")
-	columnStyle := t.Highlighter.Colorscheme.GetStyle(buffer.Column)
+	bracketStyle := t.theme.GetOrDefault("TextEditBracket")
+	columnStyle := t.Highlighter.GetStyle(buffer.Match{Syntax: buffer.Column})
 
-	t.Highlighter.UpdateInvalidatedLines(t.scrolly, t.scrolly+(t.height-1))
-
-	var tabBytes []byte
-	if t.UseHardTabs {
-		// Only call Repeat once for each draw in hard tab files
-		tabBytes = bytes.Repeat([]byte{' '}, t.TabSize)
+	if f, ok := t.getFoldHiding(t.scrolly); ok { // The view may have been scrolled into a fold
+		t.scrolly, t.scrollRow = f.Start, 0
 	}
+	lastLine, _ := t.getLineAtViewRow(t.height - 1) // The last line in view
+
+	t.Highlighter.UpdateInvalidatedLines(t.scrolly, lastLine)
+
+	visibleSigns := t.getVisibleSigns(t.scrolly, lastLine)
+	bracketStartLine, bracketStartCol, bracketEndLine, bracketEndCol := t.getBracketPair()
+	t.GetConflicts() // Update conflicts for getConflictStyle
 
-	defaultStyle := t.Highlighter.Colorscheme.GetStyle(buffer.Default)
+	defaultStyle := t.Highlighter.GetStyle(buffer.Match{Syntax: buffer.Default})
 	currentStyle := defaultStyle
 
-	for lineY := t.y; lineY < t.y+t.height; lineY++ { // For each line we can draw...
-		line := lineY + t.scrolly - t.y // The line number being drawn (starts at zero)
+	line, rows := t.scrolly, t.getWrapRows(t.scrolly) // The line being drawn (starts at zero), and its rows
+	t.scrollRow = Min(t.scrollRow, len(rows)-1)       // The line may have been shortened
+	row := t.scrollRow                                // The row of the line being drawn
+
+	for lineY := t.y; lineY < t.y+t.height; lineY, row = lineY+1, row+1 { // For each row we can draw...
+		if row >= len(rows) {
+			line = t.nextVisibleLine(line)
+			rows, row = t.getWrapRows(line), 0
+		}
 
 		lineNumStr := "" // Line number as a string
 
 		if line < bufferLines { // Only index buffer if we are within it...
 			lineNumStr = strconv.Itoa(line + 1) // Only set for lines within the buffer (not view)
+			if row > 0 {
+				lineNumStr = wrapIndicator
+			}
 
-			var origLineBytes []byte = t.Buffer.Line(line)
-			var lineBytes []byte = origLineBytes // Line to be drawn
-
-			if t.UseHardTabs {
-				lineBytes = bytes.ReplaceAll(lineBytes, []byte{'\t'}, tabBytes)
+			left, rowEnd := t.scrollx, math.MaxInt // Display column at the left of the view, and column the row ends at
+			if t.SoftWrap {
+				left = rows[row].x
+				if row+1 < len(rows) {
+					rowEnd = rows[row+1].col
+				}
 			}
 
+			lineBytes := t.Buffer.Line(line) // Line to be drawn
+
 			lineHighlightData := t.Highlighter.GetLineMatches(line)
 			var lineHighlightDataIdx int
 
-			var byteIdx int          // Byte index of lineStr
-			var runeIdx int          // Index into lineStr (as runes) we draw the next character at
-			col := t.x + columnWidth // X offset we draw the next rune at (some runes can be 2 cols wide)
+			conflictStyle, conflictMarker, inConflict := t.getConflictStyle(line)
+			_, conflictBg, _ := conflictStyle.Decompose()
 
-			for runeIdx < t.scrollx && byteIdx < len(lineBytes) {
-				_, size := utf8.DecodeRune(lineBytes[byteIdx:]) // Respect UTF-8
-				byteIdx += size
-				runeIdx++
-			}
+			var byteIdx int // Byte index of lineBytes
+			var runeIdx int // Column of the rune we draw next
+			var x int       // Display column of the rune we draw next
 
-			tabOffsetAtRuneIdx := func(idx int) int {
-				var count int
-				for _, r := range string(origLineBytes) {
-					if r == '\t' {
-						count++
-					}
-				}
-				return count * (t.TabSize - 1)
+			for x < left && byteIdx < len(lineBytes) { // Skip the clusters scrolled past, or on previous rows
+				cluster, _, width, _ := uniseg.FirstGraphemeCluster(lineBytes[byteIdx:], -1)
+				byteIdx += len(cluster)
+				runeIdx += utf8.RuneCount(cluster)
+				x += t.getClusterWidth(string(cluster), width, x)
 			}
-
-			// origRuneIdx converts a rune index from lineBytes to a runeIndex from origLineBytes
-			// not affected by the hard tabs becoming 4 or 8 spaces.
-			origRuneIdx := func(idx int) int { // returns the idx that is not mutated by hard tabs
-				var ridx int // new rune idx
-				for _, r := range string(origLineBytes) {
-					if idx <= 0 {
-						break
-					}
-
-					if r == '\t' {
-						idx -= t.TabSize
-					} else {
-						idx--
-					}
-					if idx >= 0 { // causes ridx = 0, when idx = 3
-						ridx++
-					}
-				}
-				return ridx
+			for lineHighlightDataIdx < len(lineHighlightData) && runeIdx > lineHighlightData[lineHighlightDataIdx].EndCol {
+				lineHighlightDataIdx++ // Passed while skipping
+			}
+			col := t.x + columnWidth + x - left // X offset we draw the next rune at (some runes can be 2 cols wide)
+			for c := t.x + columnWidth; c < col && c < t.x+t.width; c++ {
+				s.SetContent(c, lineY, ' ', nil, defaultStyle) // The rest of a cluster partly scrolled past
 			}
 
+			selections := t.getSelectionsOnLine(line)
+			hasBracket := line == bracketStartLine || line == bracketEndLine
+
 			for col < t.x+t.width { // For each column in view...
 				var r rune = ' '  // Rune to draw this iteration
-				var size int = 1  // Size of the rune (in bytes)
+				var combc []rune  // Combining runes drawn in the same cell as r
+				var size int = 1  // Size of the grapheme cluster (in bytes)
+				var runes int = 1 // Number of runes in the grapheme cluster
+				var width int = 1 // Number of cells the grapheme cluster is drawn in
 				var selected bool // Whether this rune should be styled as selected
 
-				tabOffsetAtRuneIdx := tabOffsetAtRuneIdx(runeIdx)
-
-				if byteIdx < len(lineBytes) { // If we are drawing part of the line contents...
-					r, size = utf8.DecodeRune(lineBytes[byteIdx:])
+				if byteIdx < len(lineBytes) && runeIdx < rowEnd { // If we are drawing part of the line contents...
+					// Draw a whole grapheme cluster, like a letter with accents, in one cell
+					var cluster []byte
+					cluster, _, width, _ = uniseg.FirstGraphemeCluster(lineBytes[byteIdx:], -1)
+					width = t.getClusterWidth(string(cluster), width, x)
+					clusterRunes := []rune(string(cluster))
+					r, size, runes = clusterRunes[0], len(cluster), len(clusterRunes)
+					if len(clusterRunes) > 1 {
+						combc = clusterRunes[1:]
+					}
 
-					if r == '\n' {
+					if r == '\n' || r == '\r' { // Also a CRLF
+						r, combc, width = ' ', nil, 1
+					} else if r == '\t' { // Drawn as spaces up to the next tab stop
 						r = ' '
 					}
 
-					startLine, startCol := t.selection.Start.GetLineCol()
-					endLine, endCol := t.selection.End.GetLineCol()
-
 					// Determine whether we select the current rune. Also only select runes within
 					// the line bytes range.
-					if t.selectMode && line >= startLine && line <= endLine { // If we're part of a selection...
-						_origRuneIdx := origRuneIdx(runeIdx)
-						if line == startLine { // If selection starts at this line...
-							if _origRuneIdx >= startCol { // And we're at or past the start col...
-								// If the start line is also the end line...
-								if line == endLine {
-									if _origRuneIdx <= endCol { // And we're before the end of that...
-										selected = true
-									}
-								} else { // Definitely highlight
-									selected = true
-								}
-							}
-						} else if line == endLine { // If selection ends at this line...
-							if _origRuneIdx <= endCol { // And we're at or before the end col...
-								selected = true
-							}
-						} else { // We're between the start and the end lines, definitely highlight.
+					for _, selection := range selections {
+						if regionContains(selection, line, runeIdx) {
 							selected = true
+							break
 						}
 					}
 				}
@@ -457,30 +751,66 @@ func (t *TextEdit) Draw(s tcell.Screen) {
 
 					if lineHighlightDataIdx < len(lineHighlightData) { // Works for single-line highlights
 						data := lineHighlightData[lineHighlightDataIdx]
-						if runeIdx-tabOffsetAtRuneIdx >= data.Col {
-							if runeIdx-tabOffsetAtRuneIdx > data.EndCol { // Passed that highlight data
+						if runeIdx >= data.Col {
+							if runeIdx > data.EndCol { // Passed that highlight data
 								currentStyle = defaultStyle
 								lineHighlightDataIdx++ // Go to next one
 							} else { // Start coloring as this syntax style
-								currentStyle = t.Highlighter.Colorscheme.GetStyle(data.Syntax)
+								currentStyle = t.Highlighter.GetStyle(data)
 							}
 						}
 					}
+
+					if conflictMarker {
+						currentStyle = conflictStyle
+					} else if inConflict {
+						currentStyle = currentStyle.Background(conflictBg)
+					}
+
+					if hasBracket && byteIdx < len(lineBytes) && runeIdx < rowEnd {
+						if line == bracketStartLine && runeIdx == bracketStartCol ||
+							line == bracketEndLine && runeIdx == bracketEndCol {
+							currentStyle = bracketStyle
+						}
+					}
 				}
 
 				// Draw the rune
-				s.SetContent(col, lineY, r, nil, currentStyle)
+				s.SetContent(col, lineY, r, combc, currentStyle)
+				if r == ' ' { // Fill the rest of a tab
+					for i := 1; i < width && col+i < t.x+t.width; i++ {
+						s.SetContent(col+i, lineY, ' ', nil, currentStyle)
+					}
+				}
 
-				col += runewidth.RuneWidth(r)
+				col += width
+				x += width
 
 				byteIdx += size
-				runeIdx++
+				runeIdx += runes
+			}
+
+			if row == len(rows)-1 {
+				t.drawFoldMarker(s, lineY, line, left)
+			}
+			t.drawCaretsOnLine(s, lineY, line, row)
+			t.drawBlockCursors(s, lineY, line, row)
+		}
+
+		if signWidth > 0 { // Draw the sign column
+			if sign, ok := visibleSigns[line]; ok && row == 0 {
+				s.SetContent(t.x, lineY, sign.Glyph, nil, sign.Style)
+			} else {
+				s.SetContent(t.x, lineY, ' ', nil, columnStyle)
 			}
 		}
 
-		columnStr := fmt.Sprintf("%s%s│", strings.Repeat(" ", columnWidth-len(lineNumStr)-1), lineNumStr) // Right align line number
+		lineNumbersWidth := columnWidth - signWidth
+		if lineNumbersWidth > 0 {
+			columnStr := fmt.Sprintf("%s%s│", strings.Repeat(" ", lineNumbersWidth-utf8.RuneCountInString(lineNumStr)-1), lineNumStr) // Right align line number
 
-		DrawStr(s, t.x, lineY, columnStr, columnStyle) // Draw column
+			DrawStr(s, t.x+signWidth, lineY, columnStr, columnStyle) // Draw column
+		}
 	}
 
 	t.updateCursorVisibility()
@@ -502,146 +832,159 @@ func (t *TextEdit) SetFocused(v bool) {
 func (t *TextEdit) HandleEvent(event tcell.Event) bool {
 	switch ev := event.(type) {
 	case *tcell.EventKey:
-		switch ev.Key() {
-		// Cursor movement
-		case tcell.KeyUp:
-			if ev.Modifiers()&tcell.ModShift != 0 {
-				if !t.selectMode {
-					var endCursor buffer.Cursor
-					if cursLine, _ := t.cursor.GetLineCol(); cursLine != 0 {
-						endCursor = t.cursor.Left()
-					} else {
-						endCursor = t.cursor
-					}
-					t.selection.End = endCursor
-					t.SetCursor(t.cursor.Up())
-					t.selection.Start = t.cursor
-					t.selectMode = true
-					t.ScrollToCursor()
-					break // Select only a single character at start
-				}
+		if t.handleBlockKey(ev) {
+			return true
+		}
+		switch {
+		case ev.Key() == tcell.KeyTab && t.selectsLines():
+			t.IndentLines() // Indent the lines of the selections instead of replacing them
+			return true
+		case ev.Key() == tcell.KeyBacktab:
+			t.OutdentLines()
+			return true
+		}
+		var handled bool
+		t.eachCaret(func() { handled = t.handleKey(ev) })
+		t.mergeCarets()
+		return handled
+	case *tcell.EventMouse:
+		return t.handleMouse(ev)
+	}
+	return false
+}
+
+// handleKey handles a key event at only the primary cursor.
+func (t *TextEdit) handleKey(ev *tcell.EventKey) bool {
+	if t.handleMotionKey(ev) {
+		return true
+	}
 
-				if t.selection.Start.Eq(t.cursor) {
-					t.SetCursor(t.cursor.Up())
-					t.selection.Start = t.cursor
+	switch ev.Key() {
+	// Cursor movement
+	case tcell.KeyUp:
+		if ev.Modifiers()&tcell.ModShift != 0 {
+			if !t.selectMode {
+				var endCursor buffer.Cursor
+				if cursLine, _ := t.cursor.GetLineCol(); cursLine != 0 {
+					endCursor = t.cursor.Left()
 				} else {
-					t.SetCursor(t.cursor.Up())
-					t.selection.End = t.cursor
+					endCursor = t.cursor
 				}
+				t.selection.End = endCursor
+				t.SetCursor(t.skipFolds(t.getCursorRowMove(-1)))
+				t.selection.Start = t.cursor
+				t.selectMode = true
+				t.ScrollToCursor()
+				break // Select only a single character at start
+			}
+
+			if t.selection.Start.Eq(t.cursor) {
+				t.SetCursor(t.skipFolds(t.getCursorRowMove(-1)))
+				t.selection.Start = t.cursor
 			} else {
-				t.selectMode = false
-				t.SetCursor(t.cursor.Up())
+				t.SetCursor(t.skipFolds(t.getCursorRowMove(-1)))
+				t.selection.End = t.cursor
+			}
+		} else {
+			t.selectMode = false
+			t.SetCursor(t.skipFolds(t.getCursorRowMove(-1)))
+		}
+		t.ScrollToCursor()
+	case tcell.KeyDown:
+		if ev.Modifiers()&tcell.ModShift != 0 {
+			if !t.selectMode {
+				t.selection.Start = t.cursor
+				t.SetCursor(t.skipFolds(t.getCursorRowMove(1)))
+				t.selection.End = t.cursor
+				t.selectMode = true
+				t.ScrollToCursor()
+				break
 			}
-			t.ScrollToCursor()
-		case tcell.KeyDown:
-			if ev.Modifiers()&tcell.ModShift != 0 {
-				if !t.selectMode {
-					t.selection.Start = t.cursor
-					t.SetCursor(t.cursor.Down())
-					t.selection.End = t.cursor
-					t.selectMode = true
-					t.ScrollToCursor()
-					break
-				}
 
-				if t.selection.End.Eq(t.cursor) {
-					t.SetCursor(t.cursor.Down())
-					t.selection.End = t.cursor
-				} else {
-					t.SetCursor(t.cursor.Down())
-					t.selection.Start = t.cursor
-				}
+			if t.selection.End.Eq(t.cursor) {
+				t.SetCursor(t.skipFolds(t.getCursorRowMove(1)))
+				t.selection.End = t.cursor
 			} else {
-				t.selectMode = false
-				t.SetCursor(t.cursor.Down())
+				t.SetCursor(t.skipFolds(t.getCursorRowMove(1)))
+				t.selection.Start = t.cursor
+			}
+		} else {
+			t.selectMode = false
+			t.SetCursor(t.skipFolds(t.getCursorRowMove(1)))
+		}
+		t.ScrollToCursor()
+	case tcell.KeyLeft:
+		if ev.Modifiers()&tcell.ModShift != 0 {
+			if !t.selectMode {
+				t.SetCursor(t.skipFolds(t.cursor.Left()))
+				t.selection.Start, t.selection.End = t.cursor, t.cursor
+				t.selectMode = true
+				t.ScrollToCursor()
+				break // Select only a single character at start
 			}
-			t.ScrollToCursor()
-		case tcell.KeyLeft:
-			if ev.Modifiers()&tcell.ModShift != 0 {
-				if !t.selectMode {
-					t.SetCursor(t.cursor.Left())
-					t.selection.Start, t.selection.End = t.cursor, t.cursor
-					t.selectMode = true
-					t.ScrollToCursor()
-					break // Select only a single character at start
-				}
 
-				if t.selection.Start.Eq(t.cursor) {
-					t.SetCursor(t.cursor.Left())
-					t.selection.Start = t.cursor
-				} else {
-					t.SetCursor(t.cursor.Left())
-					t.selection.End = t.cursor
-				}
+			if t.selection.Start.Eq(t.cursor) {
+				t.SetCursor(t.skipFolds(t.cursor.Left()))
+				t.selection.Start = t.cursor
 			} else {
-				t.selectMode = false
-				t.SetCursor(t.cursor.Left())
+				t.SetCursor(t.skipFolds(t.cursor.Left()))
+				t.selection.End = t.cursor
+			}
+		} else {
+			t.selectMode = false
+			t.SetCursor(t.skipFolds(t.cursor.Left()))
+		}
+		t.ScrollToCursor()
+	case tcell.KeyRight:
+		if ev.Modifiers()&tcell.ModShift != 0 {
+			if !t.selectMode {
+				t.selection.Start, t.selection.End = t.cursor, t.cursor
+				t.selectMode = true
+				break
 			}
-			t.ScrollToCursor()
-		case tcell.KeyRight:
-			if ev.Modifiers()&tcell.ModShift != 0 {
-				if !t.selectMode {
-					t.selection.Start, t.selection.End = t.cursor, t.cursor
-					t.selectMode = true
-					break
-				}
 
-				if t.selection.End.Eq(t.cursor) {
-					t.SetCursor(t.cursor.Right())
-					t.selection.End = t.cursor
-				} else {
-					t.SetCursor(t.cursor.Right())
-					t.selection.Start = t.cursor
-				}
+			if t.selection.End.Eq(t.cursor) {
+				t.SetCursor(t.skipFolds(t.cursor.Right()))
+				t.selection.End = t.cursor
 			} else {
-				t.selectMode = false
-				if ev.Modifiers()&tcell.ModCtrl != 0 {
-					t.SetCursor(t.cursor.NextWordBoundaryEnd())
-				} else {
-					t.SetCursor(t.cursor.Right())
-				}
+				t.SetCursor(t.skipFolds(t.cursor.Right()))
+				t.selection.Start = t.cursor
 			}
-			t.ScrollToCursor()
-		case tcell.KeyHome:
-			cursLine, _ := t.cursor.GetLineCol()
-			// TODO: go to first (non-whitespace) character on current line, if we are not already there
-			// otherwise actually go to first (0) character of the line
-			t.SetCursor(t.cursor.SetLineCol(cursLine, 0))
-			t.ScrollToCursor()
-		case tcell.KeyEnd:
-			cursLine, _ := t.cursor.GetLineCol()
-			t.SetCursor(t.cursor.SetLineCol(cursLine, math.MaxInt32)) // Max column
-			t.ScrollToCursor()
-		case tcell.KeyPgUp:
-			_, cursCol := t.cursor.GetLineCol()
-			t.SetCursor(t.cursor.SetLineCol(t.scrolly-t.height, cursCol)) // Go a page up
-			t.ScrollToCursor()
-		case tcell.KeyPgDn:
-			_, cursCol := t.cursor.GetLineCol()
-			t.SetCursor(t.cursor.SetLineCol(t.scrolly+t.height*2-1, cursCol)) // Go a page down
-			t.ScrollToCursor()
-
-		// Deleting
-		case tcell.KeyBackspace:
-			fallthrough
-		case tcell.KeyBackspace2:
-			t.Delete(false)
-		case tcell.KeyDelete:
-			t.Delete(true)
-
-		// Other control
-		case tcell.KeyTab:
-			t.Insert("\t") // (can translate to four spaces)
-		case tcell.KeyEnter:
-			t.Insert("\n")
-
-		// Inserting
-		case tcell.KeyRune:
-			t.Insert(string(ev.Rune())) // Insert rune
-		default:
-			return false
+		} else {
+			t.selectMode = false
+			t.SetCursor(t.skipFolds(t.cursor.Right()))
 		}
-		return true
+		t.ScrollToCursor()
+	case tcell.KeyPgUp:
+		_, cursCol := t.cursor.GetLineCol()
+		t.SetCursor(t.skipFolds(t.cursor.SetLineCol(t.scrolly-t.height, cursCol))) // Go a page up
+		t.ScrollToCursor()
+	case tcell.KeyPgDn:
+		_, cursCol := t.cursor.GetLineCol()
+		t.SetCursor(t.skipFolds(t.cursor.SetLineCol(t.scrolly+t.height*2-1, cursCol))) // Go a page down
+		t.ScrollToCursor()
+
+	// Deleting
+	case tcell.KeyBackspace:
+		fallthrough
+	case tcell.KeyBackspace2:
+		t.delete(false)
+	case tcell.KeyDelete:
+		t.delete(true)
+
+	// Other control
+	case tcell.KeyTab:
+		t.insert("\t") // (can translate to four spaces)
+	case tcell.KeyEnter:
+		t.insert("\n")
+
+	// Inserting
+	case tcell.KeyRune:
+		if !t.autoPair(string(ev.Rune())) { // Only typed brackets are paired, not pasted ones
+			t.insert(string(ev.Rune())) // Insert rune
+		}
+	default:
+		return false
 	}
-	return false
+	return true
 }
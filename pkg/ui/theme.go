@@ -29,6 +29,16 @@ func (theme *Theme) GetOrDefault(key string) tcell.Style {
 
 // DefaultTheme uses only the first 16 colors present in most colored terminals.
 var DefaultTheme = Theme{
+	"ConflictBase":        tcell.Style{}.Foreground(tcell.ColorSilver).Background(tcell.ColorDarkSlateGray),
+	"ConflictMarker":      tcell.Style{}.Foreground(tcell.ColorBlack).Background(tcell.ColorOlive),
+	"ConflictOurs":        tcell.Style{}.Foreground(tcell.ColorSilver).Background(tcell.ColorDarkGreen),
+	"ConflictTheirs":      tcell.Style{}.Foreground(tcell.ColorSilver).Background(tcell.ColorNavy),
+	"DiffAdded":           tcell.Style{}.Foreground(tcell.ColorSilver).Background(tcell.ColorDarkGreen),
+	"DiffAddedText":       tcell.Style{}.Foreground(tcell.ColorWhite).Background(tcell.ColorGreen),
+	"DiffColumn":          tcell.Style{}.Foreground(tcell.ColorDarkGray).Background(tcell.ColorBlack),
+	"DiffFiller":          tcell.Style{}.Foreground(tcell.ColorDarkGray).Background(tcell.ColorBlack),
+	"DiffRemoved":         tcell.Style{}.Foreground(tcell.ColorSilver).Background(tcell.ColorMaroon),
+	"DiffRemovedText":     tcell.Style{}.Foreground(tcell.ColorWhite).Background(tcell.ColorRed),
 	"Normal":              tcell.Style{}.Foreground(tcell.ColorSilver).Background(tcell.ColorBlack),
 	"Button":              tcell.Style{}.Foreground(tcell.ColorBlack).Background(tcell.ColorSilver),
 	"InputField":          tcell.Style{}.Foreground(tcell.ColorSilver).Background(tcell.ColorBlack),
@@ -36,9 +46,13 @@ var DefaultTheme = Theme{
 	"MenuBarFocused":      tcell.Style{}.Foreground(tcell.ColorBlack).Background(tcell.ColorLightGray),
 	"Menu":                tcell.Style{}.Foreground(tcell.ColorBlack).Background(tcell.ColorSilver),
 	"MenuSelected":        tcell.Style{}.Foreground(tcell.ColorSilver).Background(tcell.ColorBlack),
+	"OutputHighlighted":   tcell.Style{}.Foreground(tcell.ColorYellow).Background(tcell.ColorBlack),
 	"TabContainer":        tcell.Style{}.Foreground(tcell.ColorGray).Background(tcell.ColorBlack),
 	"TabContainerFocused": tcell.Style{}.Foreground(tcell.ColorSilver).Background(tcell.ColorBlack),
 	"TextEdit":            tcell.Style{}.Foreground(tcell.ColorSilver).Background(tcell.ColorBlack),
+	"TextEditBracket":     tcell.Style{}.Foreground(tcell.ColorWhite).Background(tcell.ColorTeal),
+	"TextEditCursor":      tcell.Style{}.Foreground(tcell.ColorBlack).Background(tcell.ColorGray),
+	"TextEditFold":        tcell.Style{}.Foreground(tcell.ColorWhite).Background(tcell.ColorDarkGray),
 	"TextEditSelected":    tcell.Style{}.Foreground(tcell.ColorBlack).Background(tcell.ColorSilver),
 	"Window":              tcell.Style{}.Foreground(tcell.ColorBlack).Background(tcell.ColorDarkGray),
 	"WindowHeader":        tcell.Style{}.Foreground(tcell.ColorBlack).Background(tcell.ColorSilver),
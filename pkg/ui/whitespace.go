@@ -0,0 +1,42 @@
+package ui
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// TrimTrailingWhitespace removes the spaces and tabs at the end of every line.
+// Large files are not trimmed.
+func (t *TextEdit) TrimTrailingWhitespace() {
+	if t.LargeFile {
+		return
+	}
+	t.editLines(0, t.Buffer.Lines()-1, func(_ int, text string) []lineEdit {
+		trimmed := strings.TrimRight(text, " \t")
+		if len(trimmed) == len(text) {
+			return nil
+		}
+		col := utf8.RuneCountInString(trimmed)
+		return []lineEdit{{col: col, remove: utf8.RuneCountInString(text) - col}}
+	})
+}
+
+// SetFinalNewline ensures the file ends with a line delimiter, if `final` is
+// true, or that it doesn't, by removing the empty lines at its end. An empty file
+// is left empty. Large files are not changed.
+func (t *TextEdit) SetFinalNewline(final bool) {
+	if t.LargeFile || t.Buffer.Len() == 0 {
+		return
+	}
+	lastLine := t.Buffer.Lines() - 1
+	if final {
+		if lastLine == 0 || t.Buffer.RunesInLineWithDelim(lastLine) > 0 {
+			t.Buffer.Insert(lastLine, t.Buffer.RunesInLine(lastLine), []byte(t.GetLineDelimiter()))
+		}
+		return
+	}
+	for ; lastLine > 0 && t.Buffer.RunesInLineWithDelim(lastLine) == 0; lastLine-- {
+		prev := lastLine - 1
+		t.Buffer.Remove(prev, t.Buffer.RunesInLine(prev), prev, t.Buffer.RunesInLineWithDelim(prev)-1)
+	}
+}
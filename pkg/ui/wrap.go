@@ -0,0 +1,158 @@
+package ui
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/fivemoreminix/qedit/pkg/buffer"
+)
+
+// wrapIndicator is drawn in the line numbers column on each row of a line after
+// its first, when soft wrap is on.
+const wrapIndicator = "↪"
+
+// A wrapRow is where one row of a line begins, when soft wrap is on: the column
+// of its first rune, and the display column that rune is drawn at.
+type wrapRow struct {
+	col, x int
+}
+
+// getWrapWidth returns how many cells of a line are drawn in each row when soft
+// wrap is on: the WrapColumn, if there is one, or the width of the view.
+func (t *TextEdit) getWrapWidth() int {
+	width := t.width - t.getColumnWidth() - 1 // The cursor needs a cell after the last rune
+	if t.WrapColumn > 0 {
+		width = Min(width, t.WrapColumn)
+	}
+	return Max(1, width)
+}
+
+// getWrapRows returns the rows `line` is drawn in. Without soft wrap, there is
+// only one. A row is ended after the last space or tab that fits in it, and
+// otherwise at the last grapheme cluster that fits in it, for a long word.
+func (t *TextEdit) getWrapRows(line int) []wrapRow {
+	rows := []wrapRow{{0, 0}}
+	if !t.SoftWrap || line >= t.Buffer.Lines() {
+		return rows
+	}
+	lineRunes := []rune(strings.TrimSuffix(strings.TrimSuffix(string(t.Buffer.Line(line)), "\n"), "\r"))
+	wrapWidth := t.getWrapWidth()
+	brk := wrapRow{-1, -1} // Where the row can be ended, after a space
+	t.eachGrapheme(line, func(col, x, runes, width int) bool {
+		for row := rows[len(rows)-1]; x+width-row.x > wrapWidth && col > row.col; row = rows[len(rows)-1] {
+			if brk.col > row.col {
+				rows = append(rows, brk)
+			} else {
+				rows = append(rows, wrapRow{col, x})
+			}
+		}
+		if unicode.IsSpace(lineRunes[col]) {
+			brk = wrapRow{col + runes, x + width}
+		}
+		return false
+	})
+	return rows
+}
+
+// getRowAt returns which row of `line` the rune at `col` is drawn on, and the
+// display column drawn at the left edge of the view on that row. Without soft
+// wrap, every line is one row, scrolled horizontally by scrollx.
+func (t *TextEdit) getRowAt(line, col int) (row, left int) {
+	if !t.SoftWrap {
+		return 0, t.scrollx
+	}
+	rows := t.getWrapRows(line)
+	for row < len(rows)-1 && rows[row+1].col <= col {
+		row++
+	}
+	return row, rows[row].x
+}
+
+// getViewRow returns the row of the view, from its top, that the row of `line`
+// is drawn at. The result is negative if it is above the view. It is at least
+// the height of the view if it is below, but it is not counted further. Lines
+// hidden by folds take no rows.
+func (t *TextEdit) getViewRow(line, row int) int {
+	if !t.SoftWrap && len(t.folds) == 0 {
+		return line - t.scrolly
+	}
+	if line < t.scrolly || line == t.scrolly && row < t.scrollRow {
+		return -1
+	}
+	y := row - t.scrollRow
+	for l := t.scrolly; l < line && y < t.height; l = t.nextVisibleLine(l) {
+		y += len(t.getWrapRows(l))
+	}
+	return y
+}
+
+// getLineAtViewRow returns the line drawn at the row `y` of the view, and the
+// display column drawn at the left edge of the view on that row. The line may
+// be past the end of the buffer.
+func (t *TextEdit) getLineAtViewRow(y int) (line, left int) {
+	if !t.SoftWrap && len(t.folds) == 0 {
+		return t.scrolly + y, t.scrollx
+	}
+	line, row := t.scrolly, t.scrollRow+y
+	for rows := t.getWrapRows(line); row >= len(rows); rows = t.getWrapRows(line) {
+		row -= len(rows)
+		line = t.nextVisibleLine(line)
+		if line >= t.Buffer.Lines() {
+			line += row
+			break
+		}
+	}
+	if !t.SoftWrap || line >= t.Buffer.Lines() {
+		return line, t.scrollx
+	}
+	return line, t.getWrapRows(line)[row].x
+}
+
+// scrollToRow scrolls the view, by rows, so the row of `line` is at
+// the row `y` of the view, or as near to it as the start of the buffer allows.
+func (t *TextEdit) scrollToRow(line, row, y int) {
+	for ; y > 0; y-- {
+		if row > 0 {
+			row--
+		} else if prev := t.prevVisibleLine(line); prev >= 0 {
+			line = prev
+			row = len(t.getWrapRows(line)) - 1
+		} else {
+			break
+		}
+	}
+	t.scrolly, t.scrollRow = line, row
+}
+
+// getCursorRowMove returns the cursor moved one row up, if `dir` is negative, or
+// one row down. With soft wrap, the cursor keeps its display column within the
+// row. Otherwise, or at the first or last line, it moves like Cursor.Up or Down.
+func (t *TextEdit) getCursorRowMove(dir int) buffer.Cursor {
+	line, col := t.cursor.GetLineCol()
+	rows := t.getWrapRows(line)
+	row, left := t.getRowAt(line, col)
+	prev, next := t.prevVisibleLine(line), t.nextVisibleLine(line)
+	if !t.SoftWrap || row+dir < 0 && prev < 0 || row+dir >= len(rows) && next >= t.Buffer.Lines() {
+		if dir < 0 {
+			return t.cursor.Up()
+		}
+		return t.cursor.Down()
+	}
+
+	x := t.GetDisplayCol(line, col) - left
+	if row += dir; row < 0 {
+		line = prev
+		rows = t.getWrapRows(line)
+		row = len(rows) - 1
+	} else if row >= len(rows) {
+		line = next
+		rows = t.getWrapRows(line)
+		row = 0
+	}
+
+	col = t.GetColAtDisplayCol(line, rows[row].x+x)
+	if row+1 < len(rows) && col >= rows[row+1].col { // Past the end of the row
+		col = t.GetColAtDisplayCol(line, rows[row+1].x-1)
+	}
+	return t.cursor.SetLineCol(line, col)
+}
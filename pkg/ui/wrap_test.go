@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetWrapRows(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected []wrapRow
+	}{
+		{"short line", "hello you", []wrapRow{{0, 0}}},
+		{"word break", "hello world again", []wrapRow{{0, 0}, {6, 6}, {12, 12}}},
+		{"overlong word", "abcdefghijklmnopqrstuvwxyz", []wrapRow{{0, 0}, {10, 10}, {20, 20}}},
+		{"overlong word after a space", "ab abcdefghijklmnop", []wrapRow{{0, 0}, {3, 3}, {13, 13}}},
+		{"wide runes", "世界世界世界", []wrapRow{{0, 0}, {5, 10}}},
+		{"wide rune at the edge", "abcdefghi世", []wrapRow{{0, 0}, {9, 9}}},
+		{"tabs", "\tab\tcdef", []wrapRow{{0, 0}, {4, 8}}},
+	}
+	for _, test := range tests {
+		te := newTestTextEdit(test.line + "\n")
+		te.LineNumbers = false
+		te.TabSize = 4
+		te.SoftWrap = true
+		te.WrapColumn = 10
+		te.SetSize(40, 10)
+		if rows := te.getWrapRows(0); !reflect.DeepEqual(rows, test.expected) {
+			t.Errorf("Wrapping %s %q: expected %v, got %v", test.name, test.line, test.expected, rows)
+		}
+	}
+
+	te := newTestTextEdit("hello world again\n")
+	te.LineNumbers = false
+	te.SetSize(40, 10)
+	te.WrapColumn = 10
+	if rows := te.getWrapRows(0); len(rows) != 1 {
+		t.Errorf("Expected one row without soft wrap, got %v", rows)
+	}
+}